@@ -0,0 +1,247 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// aggregator accumulates per-server, per-domain and per-provider statistics
+// as results arrive, so summaries and exporters read finished accumulators
+// instead of each rebuilding maps from the result slice. It is safe for
+// concurrent use.
+type aggregator struct {
+	mu         sync.Mutex
+	servers    map[string]*ServerStats // by name and address
+	domains    map[string]*DomainStats
+	providers  map[string]*ProviderStats
+	categories map[string]map[string]*CategoryStats     // by server key and category
+	types      map[string]map[string]*TypeStats         // by server key and record type
+	load       map[string]map[int]*loadStats            // by server key and concurrency bucket
+	outcomes   map[string][]queryOutcome                // by server key, in arrival order
+	pairs      map[string]map[pairQueryKey][]pairAnswer // by provider and attempt
+	order      []string                                 // server keys in arrival order, for stable ties
+
+	// endpoints are each provider's configured addresses, primary first;
+	// without them provider addresses keep their arrival order
+	endpoints map[string][]string
+}
+
+// ProviderStats aggregates a provider's addresses (primary and secondary)
+// together, as the website test ranks them
+type ProviderStats struct {
+	Name           string
+	Addrs          []string
+	TotalQueries   int
+	SuccessQueries int
+	AvgRTT         time.Duration
+
+	rtts     []time.Duration
+	rttTotal time.Duration
+}
+
+// liveStats accumulates the results of the running benchmark
+var liveStats = newAggregator()
+
+func newAggregator() *aggregator {
+	return &aggregator{
+		servers:    make(map[string]*ServerStats),
+		domains:    make(map[string]*DomainStats),
+		providers:  make(map[string]*ProviderStats),
+		categories: make(map[string]map[string]*CategoryStats),
+		types:      make(map[string]map[string]*TypeStats),
+		load:       make(map[string]map[int]*loadStats),
+		outcomes:   make(map[string][]queryOutcome),
+		pairs:      make(map[string]map[pairQueryKey][]pairAnswer),
+	}
+}
+
+// aggregate accumulates a finished set of results, such as a saved file
+func aggregate(results []*BenchmarkResult) *aggregator {
+	agg := newAggregator()
+	for _, result := range results {
+		agg.add(result)
+	}
+	return agg
+}
+
+// setEndpoints makes providerStats list each provider's addresses in the
+// configured order, so the primary comes first whichever answered first
+func (a *aggregator) setEndpoints(servers []*DNSServer, transport string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.endpoints = make(map[string][]string, len(servers))
+	for _, server := range servers {
+		a.endpoints[server.Name] = server.endpoints(transport)
+	}
+}
+
+// recordResult appends a result of the running benchmark to results and
+// liveStats
+func recordResult(result *BenchmarkResult) {
+	mu.Lock()
+	results = append(results, result)
+	mu.Unlock()
+	liveStats.add(result)
+}
+
+// add updates the accumulators with one result
+func (a *aggregator) add(result *BenchmarkResult) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := result.ServerName + " - " + result.ServerAddr
+	stats, ok := a.servers[key]
+	if !ok {
+		stats = &ServerStats{
+			ServerName:  result.ServerName,
+			ServerAddr:  result.ServerAddr,
+			Transport:   result.Transport,
+			RcodeCounts: make(map[string]int),
+		}
+		a.servers[key] = stats
+		a.order = append(a.order, key)
+	}
+	stats.TotalQueries++
+	stats.addSizes(result)
+	stats.addApdex(result)
+	switch {
+	case result.ConnectFailed:
+		stats.ConnectFailures++
+	case result.Status == "TIMEOUT":
+		stats.Timeouts++
+	case result.Status == "NO_RECORDS":
+		stats.NoRecords++
+	case result.Status == "FAILED" && result.Rcode != "":
+		stats.RcodeCounts[result.Rcode]++
+	}
+
+	a.addCategory(key, result)
+	a.addType(key, result)
+	a.addLoad(key, result)
+	a.addOutcome(key, result)
+	a.addPairQuery(result)
+
+	domain, ok := a.domains[result.Domain]
+	if !ok {
+		domain = &DomainStats{Domain: result.Domain}
+		a.domains[result.Domain] = domain
+	}
+	domain.TotalQueries++
+
+	provider, ok := a.providers[result.ServerName]
+	if !ok {
+		provider = &ProviderStats{Name: result.ServerName}
+		a.providers[result.ServerName] = provider
+	}
+	provider.TotalQueries++
+	if !slices.Contains(provider.Addrs, result.ServerAddr) {
+		provider.Addrs = append(provider.Addrs, result.ServerAddr)
+	}
+
+	if result.Status != "SUCCESS" {
+		return
+	}
+	if stats.SuccessQueries == 0 || result.RTT < stats.MinRTT {
+		stats.MinRTT = result.RTT
+	}
+	stats.MaxRTT = max(stats.MaxRTT, result.RTT)
+	stats.SuccessQueries++
+	stats.rttTotal += result.RTT
+	stats.rtts = append(stats.rtts, result.RTT)
+
+	domain.SuccessQueries++
+	domain.rttTotal += result.RTT
+
+	provider.SuccessQueries++
+	provider.rttTotal += result.RTT
+	provider.rtts = append(provider.rtts, result.RTT)
+}
+
+// serverStats returns a snapshot of the per-address statistics, sorted by
+// average RTT
+func (a *aggregator) serverStats() []*ServerStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	statsList := make([]*ServerStats, 0, len(a.order))
+	for _, key := range a.order {
+		stats := *a.servers[key]
+		stats.rtts = slices.Clone(stats.rtts)
+		stats.RcodeCounts = make(map[string]int, len(stats.RcodeCounts))
+		for rcode, n := range a.servers[key].RcodeCounts {
+			stats.RcodeCounts[rcode] = n
+		}
+		if stats.SuccessQueries > 0 {
+			stats.AvgRTT = stats.rttTotal / time.Duration(stats.SuccessQueries)
+			stats.CI95 = confidence95(stats.rtts)
+		}
+		statsList = append(statsList, &stats)
+	}
+	sort.SliceStable(statsList, func(i, j int) bool {
+		return statsList[i].AvgRTT < statsList[j].AvgRTT
+	})
+	return statsList
+}
+
+// domainStats returns a snapshot of the per-domain statistics, sorted by
+// average RTT
+func (a *aggregator) domainStats() []DomainStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	domainList := make([]DomainStats, 0, len(a.domains))
+	for _, stats := range a.domains {
+		domain := *stats
+		if domain.SuccessQueries > 0 {
+			domain.AvgRTT = domain.rttTotal / time.Duration(domain.SuccessQueries)
+		}
+		domainList = append(domainList, domain)
+	}
+	sort.Slice(domainList, func(i, j int) bool {
+		if domainList[i].AvgRTT != domainList[j].AvgRTT {
+			return domainList[i].AvgRTT < domainList[j].AvgRTT
+		}
+		return domainList[i].Domain < domainList[j].Domain
+	})
+	return domainList
+}
+
+// providerStats returns a snapshot of the per-provider statistics, ordered
+// by name, with the primary address first when the endpoints are known
+func (a *aggregator) providerStats() []*ProviderStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	providers := make([]*ProviderStats, 0, len(a.providers))
+	for _, stats := range a.providers {
+		provider := *stats
+		provider.Addrs = slices.Clone(stats.Addrs)
+		if configured, ok := a.endpoints[provider.Name]; ok {
+			// Addresses that are not configured (e.g. from a checkpoint) go last
+			rank := func(addr string) int {
+				if i := slices.Index(configured, addr); i >= 0 {
+					return i
+				}
+				return len(configured)
+			}
+			slices.SortStableFunc(provider.Addrs, func(x, y string) int { return rank(x) - rank(y) })
+		}
+		provider.rtts = slices.Clone(stats.rtts)
+		if provider.SuccessQueries > 0 {
+			provider.AvgRTT = provider.rttTotal / time.Duration(provider.SuccessQueries)
+		}
+		providers = append(providers, &provider)
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Name < providers[j].Name })
+	return providers
+}
+
+// SuccessRate returns the percentage of successful queries
+func (p *ProviderStats) SuccessRate() float64 {
+	if p.TotalQueries == 0 {
+		return 0
+	}
+	return float64(p.SuccessQueries) / float64(p.TotalQueries) * 100
+}
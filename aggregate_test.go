@@ -0,0 +1,205 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func testResult(server, addr, domain, status string, rtt time.Duration) *BenchmarkResult {
+	return &BenchmarkResult{ServerName: server, ServerAddr: addr, Domain: domain, Status: status, RTT: rtt}
+}
+
+func TestAggregatorAdd(t *testing.T) {
+	agg := newAggregator()
+	agg.add(testResult("Google", "8.8.8.8:53", "example.com", "SUCCESS", 10*time.Millisecond))
+	agg.add(testResult("Google", "8.8.8.8:53", "example.com", "SUCCESS", 30*time.Millisecond))
+	agg.add(testResult("Google", "8.8.8.8:53", "example.org", "TIMEOUT", 0))
+	agg.add(&BenchmarkResult{ServerName: "Google", ServerAddr: "8.8.8.8:53", Domain: "example.org", Status: "FAILED", Rcode: "SERVFAIL"})
+	agg.add(&BenchmarkResult{ServerName: "Google", ServerAddr: "8.8.8.8:53", Domain: "example.org", Status: "TIMEOUT", ConnectFailed: true})
+
+	stats := agg.servers["Google - 8.8.8.8:53"]
+	if stats == nil {
+		t.Fatal("no stats for Google - 8.8.8.8:53")
+	}
+	if stats.TotalQueries != 5 || stats.SuccessQueries != 2 {
+		t.Errorf("queries = %d/%d, want 2/5", stats.SuccessQueries, stats.TotalQueries)
+	}
+	if stats.MinRTT != 10*time.Millisecond || stats.MaxRTT != 30*time.Millisecond {
+		t.Errorf("min/max = %v/%v, want 10ms/30ms", stats.MinRTT, stats.MaxRTT)
+	}
+	// A connection failure is not also counted as a timeout
+	if stats.Timeouts != 1 || stats.ConnectFailures != 1 || stats.RcodeCounts["SERVFAIL"] != 1 {
+		t.Errorf("timeouts %d, connect failures %d, SERVFAIL %d, want 1 each", stats.Timeouts, stats.ConnectFailures, stats.RcodeCounts["SERVFAIL"])
+	}
+	if got := agg.domains["example.org"]; got.TotalQueries != 3 || got.SuccessQueries != 0 {
+		t.Errorf("example.org queries = %d/%d, want 0/3", got.SuccessQueries, got.TotalQueries)
+	}
+	if got := agg.providers["Google"]; got.TotalQueries != 5 || got.SuccessQueries != 2 {
+		t.Errorf("provider queries = %d/%d, want 2/5", got.SuccessQueries, got.TotalQueries)
+	}
+}
+
+func TestAggregatorServerStats(t *testing.T) {
+	agg := aggregate([]*BenchmarkResult{
+		testResult("Slow", "192.0.2.1:53", "example.com", "SUCCESS", 40*time.Millisecond),
+		testResult("Fast", "192.0.2.2:53", "example.com", "SUCCESS", 10*time.Millisecond),
+		testResult("Fast", "192.0.2.2:53", "example.com", "SUCCESS", 20*time.Millisecond),
+	})
+	stats := agg.serverStats()
+	if len(stats) != 2 {
+		t.Fatalf("got %d servers, want 2", len(stats))
+	}
+	if stats[0].ServerName != "Fast" || stats[0].AvgRTT != 15*time.Millisecond {
+		t.Errorf("first = %s avg %v, want Fast avg 15ms", stats[0].ServerName, stats[0].AvgRTT)
+	}
+	if stats[1].ServerName != "Slow" || stats[1].AvgRTT != 40*time.Millisecond {
+		t.Errorf("second = %s avg %v, want Slow avg 40ms", stats[1].ServerName, stats[1].AvgRTT)
+	}
+
+	// Snapshots do not change with later results
+	stats[0].RcodeCounts["REFUSED"] = 1
+	agg.add(testResult("Fast", "192.0.2.2:53", "example.com", "SUCCESS", 90*time.Millisecond))
+	if stats[0].SuccessQueries != 2 || agg.servers["Fast - 192.0.2.2:53"].RcodeCounts["REFUSED"] != 0 {
+		t.Error("serverStats snapshot shares state with the aggregator")
+	}
+}
+
+func TestAggregatorDomainStats(t *testing.T) {
+	agg := aggregate([]*BenchmarkResult{
+		testResult("Google", "8.8.8.8:53", "b.example", "SUCCESS", 20*time.Millisecond),
+		testResult("Google", "8.8.8.8:53", "a.example", "SUCCESS", 20*time.Millisecond),
+		testResult("Google", "8.8.8.8:53", "c.example", "SUCCESS", 5*time.Millisecond),
+		testResult("Google", "8.8.8.8:53", "c.example", "TIMEOUT", 0),
+	})
+	domains := agg.domainStats()
+	var names []string
+	for _, domain := range domains {
+		names = append(names, domain.Domain)
+	}
+	// Fastest first; equal averages by name
+	if want := []string{"c.example", "a.example", "b.example"}; !slices.Equal(names, want) {
+		t.Errorf("order = %v, want %v", names, want)
+	}
+	if domains[0].TotalQueries != 2 || domains[0].SuccessQueries != 1 || domains[0].AvgRTT != 5*time.Millisecond {
+		t.Errorf("c.example = %d/%d avg %v, want 1/2 avg 5ms", domains[0].SuccessQueries, domains[0].TotalQueries, domains[0].AvgRTT)
+	}
+}
+
+func TestAggregatorProviderStats(t *testing.T) {
+	results := []*BenchmarkResult{
+		testResult("Quad9", "9.9.9.9:53", "example.com", "SUCCESS", 30*time.Millisecond),
+		// The secondary answers first
+		testResult("Google", "8.8.4.4:53", "example.com", "SUCCESS", 10*time.Millisecond),
+		testResult("Google", "8.8.8.8:53", "example.com", "SUCCESS", 30*time.Millisecond),
+		testResult("Google", "8.8.8.8:53", "example.com", "TIMEOUT", 0),
+	}
+
+	providers := aggregate(results).providerStats()
+	if len(providers) != 2 || providers[0].Name != "Google" || providers[1].Name != "Quad9" {
+		t.Fatalf("providers not ordered by name: %v", providers)
+	}
+	google := providers[0]
+	if google.TotalQueries != 3 || google.SuccessQueries != 2 || google.AvgRTT != 20*time.Millisecond {
+		t.Errorf("Google = %d/%d avg %v, want 2/3 avg 20ms", google.SuccessQueries, google.TotalQueries, google.AvgRTT)
+	}
+	// Without configured endpoints, addresses keep their arrival order
+	if want := []string{"8.8.4.4:53", "8.8.8.8:53"}; !slices.Equal(google.Addrs, want) {
+		t.Errorf("addrs = %v, want %v", google.Addrs, want)
+	}
+
+	agg := newAggregator()
+	agg.setEndpoints([]*DNSServer{{Name: "Google", Primary: "8.8.8.8:53", Secondary: "8.8.4.4:53"}}, TransportUDP)
+	for _, result := range results {
+		agg.add(result)
+	}
+	for _, provider := range agg.providerStats() {
+		if provider.Name != "Google" {
+			continue
+		}
+		if want := []string{"8.8.8.8:53", "8.8.4.4:53"}; !slices.Equal(provider.Addrs, want) {
+			t.Errorf("addrs = %v, want the primary first %v", provider.Addrs, want)
+		}
+	}
+}
+
+func TestAggregatorTypeStats(t *testing.T) {
+	results := []*BenchmarkResult{
+		testResult("Google", "8.8.8.8:53", "example.com", "SUCCESS", 10*time.Millisecond),
+		testResult("Google", "8.8.8.8:53", "example.com", "NO_RECORDS", 30*time.Millisecond),
+		testResult("Google", "8.8.8.8:53", "example.com", "TIMEOUT", 0),
+	}
+	results[1].QType, results[2].QType = "HTTPS", "AAAA"
+
+	byServer, qtypes := aggregate(results).typeStats()
+	if want := []string{"A", "AAAA", "HTTPS"}; !slices.Equal(qtypes, want) {
+		t.Errorf("types = %v, want %v", qtypes, want)
+	}
+	types := byServer["Google - 8.8.8.8:53"]
+	// NOERROR without records still counts as answered
+	if https := types["HTTPS"]; https.Answered != 1 || https.AvgRTT != 30*time.Millisecond {
+		t.Errorf("HTTPS = %d answered avg %v, want 1 avg 30ms", https.Answered, https.AvgRTT)
+	}
+	if aaaa := types["AAAA"]; aaaa.Answered != 0 || aaaa.TotalQueries != 1 {
+		t.Errorf("AAAA = %d/%d answered, want 0/1", aaaa.Answered, aaaa.TotalQueries)
+	}
+}
+
+func TestAggregatorLoadStats(t *testing.T) {
+	agg := newAggregator()
+	for _, inFlight := range []int{1, 2, 3, 0} {
+		result := testResult("Google", "8.8.8.8:53", "example.com", "SUCCESS", time.Duration(inFlight)*10*time.Millisecond)
+		result.InFlight = inFlight
+		agg.add(result)
+	}
+	load := agg.loadStats()["Google (8.8.8.8:53)"]
+	// 2 and 3 in flight share a bucket; results without a count are left out
+	if len(load) != 2 || load[0].count != 1 || load[1].count != 2 || load[1].total != 50*time.Millisecond {
+		t.Errorf("buckets = %v, want 1 query at 1 and 2 queries (50ms) at 2-3", load)
+	}
+}
+
+func TestAggregatorFailureStreaks(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	agg := newAggregator()
+	// Sent in this order, arriving out of order
+	for i, status := range []string{"SUCCESS", "TIMEOUT", "TIMEOUT", "SUCCESS", "TIMEOUT"} {
+		result := testResult("Google", "8.8.8.8:53", "example.com", status, time.Millisecond)
+		result.Timestamp = start.Add(time.Duration(4-i) * time.Second)
+		agg.add(result)
+	}
+	agg.add(testResult("Quad9", "9.9.9.9:53", "example.com", "SUCCESS", time.Millisecond))
+
+	streaks := agg.failureStreaks()
+	if len(streaks) != 1 {
+		t.Fatalf("got %d addresses with failures, want 1", len(streaks))
+	}
+	s := streaks[0]
+	if s.Failures != 3 || s.Queries != 5 || s.Longest != 2 || !s.Start.Equal(start.Add(2*time.Second)) {
+		t.Errorf("streak = %d/%d failures, longest %d from %v, want 3/5, longest 2 from %v", s.Failures, s.Queries, s.Longest, s.Start, start.Add(2*time.Second))
+	}
+}
+
+func TestAggregatorPairStats(t *testing.T) {
+	results := []*BenchmarkResult{
+		testResult("Google", "8.8.8.8:53", "example.com", "SUCCESS", 30*time.Millisecond),
+		testResult("Google", "8.8.4.4:53", "example.com", "SUCCESS", 10*time.Millisecond),
+		testResult("Google", "8.8.8.8:53", "example.com", "TIMEOUT", 0),
+		testResult("Google", "8.8.4.4:53", "example.com", "SUCCESS", 20*time.Millisecond),
+		testResult("Quad9", "9.9.9.9:53", "example.com", "SUCCESS", 10*time.Millisecond),
+	}
+	results[2].Iteration, results[3].Iteration = 1, 1
+
+	pairs := aggregate(results).pairStats()
+	// Quad9 has a single address
+	if len(pairs) != 1 || pairs[0].ServerName != "Google" {
+		t.Fatalf("pairs = %v, want Google only", pairs)
+	}
+	pair := pairs[0]
+	if pair.Attempts != 2 || pair.Answered != 2 || pair.Rescued != 1 || pair.AvgRTT() != 15*time.Millisecond {
+		t.Errorf("pair = %d/%d answered, %d rescued, avg %v, want 2/2, 1 rescued, avg 15ms", pair.Answered, pair.Attempts, pair.Rescued, pair.AvgRTT())
+	}
+	if pair.BestSingle != 15*time.Millisecond {
+		t.Errorf("best single = %v, want 15ms", pair.BestSingle)
+	}
+}
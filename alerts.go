@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Alert states of AlertEvent
+const (
+	AlertFiring    = "firing"
+	AlertRecovered = "recovered"
+)
+
+// alertRule is one condition of --alert: a round loss ("loss>20%") or
+// average RTT ("rtt>100ms") a resolver must stay within
+type alertRule struct {
+	Spec string
+	Loss float64       // percent, for loss rules
+	RTT  time.Duration // for rtt rules
+}
+
+var (
+	// alertRules are checked against every resolver each monitor round
+	alertRules []alertRule
+	// alertWebhook receives every AlertEvent as JSON; empty to only print them
+	alertWebhook string
+	// alertAfter is how many consecutive rounds a condition must hold (or be
+	// gone) before an alert fires (or recovers), so one slow round is no alert
+	alertAfter = 2
+	// alertRepeat re-sends a firing alert while it lasts; 0 sends it once
+	alertRepeat time.Duration
+)
+
+// configureAlerts parses --alert and checks the other alert flags
+func configureAlerts(spec, webhook string, after int, repeat time.Duration) error {
+	alertRules = nil
+	for _, part := range splitList(spec) {
+		rule := alertRule{Spec: strings.ReplaceAll(part, " ", "")}
+		metric, value, found := strings.Cut(rule.Spec, ">")
+		switch {
+		case found && metric == "loss":
+			loss, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if err != nil || loss < 0 || loss >= 100 {
+				return fmt.Errorf("--alert: %q: want a percentage like 20%%", value)
+			}
+			rule.Loss = loss
+		case found && metric == "rtt":
+			rtt, err := time.ParseDuration(value)
+			if err != nil || rtt <= 0 {
+				return fmt.Errorf("--alert: %q: want a duration like 100ms", value)
+			}
+			rule.RTT = rtt
+		default:
+			return fmt.Errorf("--alert: %q: want e.g. loss>20%% or rtt>100ms", part)
+		}
+		alertRules = append(alertRules, rule)
+	}
+	if webhook != "" {
+		if len(alertRules) == 0 {
+			return fmt.Errorf("--alert-webhook requires --alert")
+		}
+		if u, err := url.Parse(webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("--alert-webhook must be an http:// or https:// URL")
+		}
+	}
+	if after < 1 {
+		return fmt.Errorf("--alert-after must be at least 1")
+	}
+	alertWebhook, alertAfter, alertRepeat = webhook, after, repeat
+	return nil
+}
+
+// breaches returns the rules a resolver's round breaks, as readable reasons
+func breaches(bucket hourBucket) []string {
+	var reasons []string
+	for _, rule := range alertRules {
+		if rule.RTT > 0 {
+			// A round without answers has no RTT; loss rules cover it
+			if bucket.Success > 0 && bucket.avg() > rule.RTT {
+				reasons = append(reasons, fmt.Sprintf("avg RTT %.1f ms > %v", ms(bucket.avg()), rule.RTT))
+			}
+			continue
+		}
+		if loss := bucketLoss(bucket); loss > rule.Loss {
+			reasons = append(reasons, fmt.Sprintf("loss %.0f%% > %g%%", loss, rule.Loss))
+		}
+	}
+	return reasons
+}
+
+func bucketLoss(bucket hourBucket) float64 {
+	if bucket.Total == 0 {
+		return 0
+	}
+	return float64(bucket.Total-bucket.Success) / float64(bucket.Total) * 100
+}
+
+// AlertEvent is sent when a resolver starts breaking the --alert rules and
+// when it is back within them; it is the JSON body of webhook deliveries
+type AlertEvent struct {
+	Time     time.Time `json:"time"`
+	Resolver string    `json:"resolver"`
+	State    string    `json:"state"` // firing or recovered
+	Repeat   bool      `json:"repeat,omitempty"`
+	Since    time.Time `json:"since"` // when the condition started
+	Reasons  []string  `json:"reasons,omitempty"`
+	Loss     float64   `json:"loss_percent"`
+	AvgRTT   float64   `json:"avg_rtt_ms"`
+	// Text is a one-line summary so Slack-style webhooks display something
+	Text string `json:"text"`
+}
+
+// alertState is one resolver's alert: whether it fires, and how many
+// rounds in a row disagreed with that
+type alertState struct {
+	firing   bool
+	since    time.Time
+	notified time.Time
+	streak   int
+	first    time.Time // first round of the current streak
+}
+
+// alerter turns round results into alerts, sending one when a condition
+// starts and one when it ends instead of one per round
+type alerter struct {
+	states map[string]*alertState
+}
+
+// newAlerter returns nil without --alert
+func newAlerter() *alerter {
+	if len(alertRules) == 0 {
+		return nil
+	}
+	return &alerter{states: make(map[string]*alertState)}
+}
+
+// round updates every resolver's state with a round started at and sends
+// the alerts whose state changed
+func (a *alerter) round(at time.Time, roundResults []*BenchmarkResult) {
+	if a == nil {
+		return
+	}
+	for name, bucket := range roundBuckets(roundResults) {
+		state, ok := a.states[name]
+		if !ok {
+			state = &alertState{}
+			a.states[name] = state
+		}
+		reasons := breaches(*bucket)
+		if (len(reasons) > 0) == state.firing {
+			state.streak = 0
+			if state.firing && alertRepeat > 0 && at.Sub(state.notified) >= alertRepeat {
+				a.send(&AlertEvent{Time: at, Resolver: name, State: AlertFiring, Repeat: true, Since: state.since, Reasons: reasons}, *bucket, state)
+			}
+			continue
+		}
+		if state.streak == 0 {
+			state.first = at
+		}
+		state.streak++
+		if state.streak < alertAfter {
+			continue
+		}
+
+		state.firing, state.streak = !state.firing, 0
+		if state.firing {
+			state.since = state.first
+			a.send(&AlertEvent{Time: at, Resolver: name, State: AlertFiring, Since: state.since, Reasons: reasons}, *bucket, state)
+		} else {
+			a.send(&AlertEvent{Time: at, Resolver: name, State: AlertRecovered, Since: state.since}, *bucket, state)
+		}
+	}
+}
+
+// send prints an alert and posts it to --alert-webhook
+func (a *alerter) send(event *AlertEvent, bucket hourBucket, state *alertState) {
+	state.notified = event.Time
+	event.Loss, event.AvgRTT = bucketLoss(bucket), ms(bucket.avg())
+	if event.State == AlertFiring {
+		event.Text = fmt.Sprintf("%s: %s", event.Resolver, strings.Join(event.Reasons, ", "))
+		if event.Repeat {
+			event.Text += fmt.Sprintf(" (ongoing for %v)", event.Time.Sub(event.Since).Round(time.Second))
+		}
+		fmt.Printf("    %s[!] ALERT %s%s\n", ColorRed, event.Text, ColorReset)
+	} else {
+		// The condition ended with the first of the rounds that recovered it
+		event.Text = fmt.Sprintf("%s recovered after %v", event.Resolver, state.first.Sub(event.Since).Round(time.Second))
+		fmt.Printf("    %s[✓] %s%s\n", ColorGreen, event.Text, ColorReset)
+	}
+	if alertWebhook != "" {
+		if err := postWebhook(alertWebhook, event); err != nil {
+			slog.Warn("Alert delivery failed", "resolver", event.Resolver, "err", err)
+		}
+	}
+}
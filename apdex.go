@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// apdexThreshold is the RTT a query must stay under to count as satisfying;
+// queries up to four times as slow are tolerated, slower ones and failures
+// frustrate (https://www.apdex.org)
+var apdexThreshold = 50 * time.Millisecond
+
+// addApdex classifies one result against apdexThreshold
+func (s *ServerStats) addApdex(result *BenchmarkResult) {
+	if result.Status != "SUCCESS" {
+		return
+	}
+	switch {
+	case result.RTT <= apdexThreshold:
+		s.Satisfied++
+	case result.RTT <= 4*apdexThreshold:
+		s.Tolerating++
+	}
+}
+
+// UnderThreshold returns the percentage of queries answered within
+// apdexThreshold
+func (s *ServerStats) UnderThreshold() float64 {
+	if s.TotalQueries == 0 {
+		return 0
+	}
+	return float64(s.Satisfied) / float64(s.TotalQueries) * 100
+}
+
+// Apdex returns the score from 0 (all frustrated) to 1 (all satisfied)
+func (s *ServerStats) Apdex() float64 {
+	if s.TotalQueries == 0 {
+		return 0
+	}
+	return (float64(s.Satisfied) + float64(s.Tolerating)/2) / float64(s.TotalQueries)
+}
+
+// apdexRating names a score using the Apdex rating bands
+func apdexRating(score float64) (string, string) {
+	switch {
+	case score >= 0.94:
+		return "Excellent", ColorGreen
+	case score >= 0.85:
+		return "Good", ColorGreen
+	case score >= 0.70:
+		return "Fair", ColorYellow
+	case score >= 0.50:
+		return "Poor", ColorRed
+	}
+	return "Unacceptable", ColorRed
+}
+
+// printApdex shows the share of queries under the threshold and the Apdex
+// score per server, best first
+func printApdex(statsList []*ServerStats) {
+	fmt.Printf("\n%s[*] Responsiveness (Apdex, T = %v):%s\n\n", ColorBlue, apdexThreshold, ColorReset)
+	fmt.Printf("%s%-30s | %-12s | %-12s | %-6s | %-12s%s\n",
+		ColorWhite, "Server", fmt.Sprintf("Under %v", apdexThreshold), fmt.Sprintf("Under %v", 4*apdexThreshold), "Apdex", "Rating", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼──────────────┼──────────────┼────────┼─────────────", ColorReset)
+
+	for _, stats := range sortedByApdex(statsList) {
+		tolerable := 0.0
+		if stats.TotalQueries > 0 {
+			tolerable = float64(stats.Satisfied+stats.Tolerating) / float64(stats.TotalQueries) * 100
+		}
+		rating, color := apdexRating(stats.Apdex())
+		serverDisplay := fmt.Sprintf("%s (%s)", stats.ServerName, stats.ServerAddr)
+		fmt.Printf("%-30s | %11.1f%% | %11.1f%% | %s%6.2f%s | %s%s%s\n",
+			serverDisplay, stats.UnderThreshold(), tolerable, color, stats.Apdex(), ColorReset, color, rating, ColorReset)
+	}
+	fmt.Printf("\n%s    Apdex counts queries under T as satisfied, under 4T as half, slower ones and failures as zero%s\n", ColorCyan, ColorReset)
+}
+
+// writeMarkdownApdex adds the responsiveness table to Markdown output
+func writeMarkdownApdex(w io.Writer, statsList []*ServerStats) {
+	fmt.Fprintf(w, "### Responsiveness (Apdex, T = %v)\n\n", apdexThreshold)
+	fmt.Fprintf(w, "| Server | Under %v | Apdex | Rating |\n", apdexThreshold)
+	fmt.Fprintf(w, "|--------|------:|------:|--------|\n")
+	for _, stats := range sortedByApdex(statsList) {
+		rating, _ := apdexRating(stats.Apdex())
+		fmt.Fprintf(w, "| %s (`%s`) | %.1f%% | %.2f | %s |\n",
+			markdownEscape(stats.ServerName), stats.ServerAddr, stats.UnderThreshold(), stats.Apdex(), rating)
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+func sortedByApdex(statsList []*ServerStats) []*ServerStats {
+	sorted := append([]*ServerStats(nil), statsList...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Apdex() > sorted[j].Apdex()
+	})
+	return sorted
+}
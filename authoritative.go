@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// nsProviders maps NS host suffixes to the DNS hosting provider behind them
+var nsProviders = []struct {
+	Suffix   string
+	Provider string
+}{
+	{".ns.cloudflare.com.", "Cloudflare"},
+	{".nsone.net.", "NS1"},
+	{".googledomains.com.", "Google Cloud DNS"},
+	{".google.com.", "Google"},
+	{".azure-dns.com.", "Azure DNS"},
+	{".azure-dns.net.", "Azure DNS"},
+	{".azure-dns.org.", "Azure DNS"},
+	{".azure-dns.info.", "Azure DNS"},
+	{".ultradns.net.", "UltraDNS"},
+	{".ultradns.com.", "UltraDNS"},
+	{".dynect.net.", "Dyn"},
+	{".akam.net.", "Akamai"},
+	{".domaincontrol.com.", "GoDaddy"},
+	{".digitalocean.com.", "DigitalOcean"},
+	{".linode.com.", "Linode"},
+	{".hetzner.com.", "Hetzner"},
+	{".registrar-servers.com.", "Namecheap"},
+	{".gtld-servers.net.", "Verisign"},
+	{".afilias-nst.info.", "Identity Digital"},
+	{".afilias-nst.org.", "Identity Digital"},
+}
+
+// nsProvider guesses the hosting provider from an NS host name
+func nsProvider(host string) string {
+	host = dns.Fqdn(strings.ToLower(host))
+	if strings.Contains(host, ".awsdns-") {
+		return "Route 53"
+	}
+	if operator, ok := rootOperators[host]; ok {
+		return operator
+	}
+	for _, p := range nsProviders {
+		if strings.HasSuffix(host, p.Suffix) {
+			return p.Provider
+		}
+	}
+	return "-"
+}
+
+// authServer is one address of a zone's authoritative name server
+type authServer struct {
+	Zone string
+	Host string
+	Addr string
+}
+
+// zoneQuery is one name and type asked of the authoritative servers
+type zoneQuery struct {
+	Name  string
+	Qtype uint16
+}
+
+// authStats holds the results of benchmarking one authoritative address
+type authStats struct {
+	server        authServer
+	rtts          []time.Duration
+	total         int
+	authoritative int
+}
+
+// runAuthoritative looks up each zone's NS set and queries the authoritative
+// servers directly with recursion disabled, comparing NS providers
+func runAuthoritative(config *BenchmarkConfig, zones []string) {
+	// NS and glue lookups go through the first selected resolver
+	var resolver string
+	for _, server := range config.Servers {
+		if addrs := server.endpoints(config.Transport); len(addrs) > 0 {
+			resolver = addrs[0]
+			break
+		}
+	}
+	if resolver == "" {
+		fmt.Printf("%s[!] No resolver available to look up NS records%s\n", ColorRed, ColorReset)
+		return
+	}
+
+	var servers []authServer
+	for _, zone := range zones {
+		found, err := lookupNameServers(config.Transport, resolver, zone)
+		if err != nil {
+			fmt.Printf("%s[!] %s: %v%s\n", ColorRed, zone, err, ColorReset)
+			continue
+		}
+		servers = append(servers, found...)
+	}
+	if len(servers) == 0 {
+		fmt.Printf("%s[!] No authoritative servers found%s\n", ColorRed, ColorReset)
+		return
+	}
+
+	printAuthoritative(benchmarkAuthServers(servers, config.Domains, config.QueryNum))
+}
+
+// benchmarkAuthServers queries every authoritative address queryNum times
+// per zone query
+func benchmarkAuthServers(servers []authServer, domains []string, queryNum int) []*authStats {
+	fmt.Printf("%s[*] Benchmarking %d authoritative addresses directly (recursion disabled)...%s\n\n", ColorBlue, len(servers), ColorReset)
+
+	var statsList []*authStats
+	for _, server := range servers {
+		stats := &authStats{server: server}
+		for _, name := range zoneQueries(server.Zone, domains) {
+			for i := 0; i < queryNum; i++ {
+				rtt, aa, err := queryAuthoritative(server.Addr, name.Name, name.Qtype)
+				stats.total++
+				if err != nil {
+					continue
+				}
+				stats.rtts = append(stats.rtts, rtt)
+				if aa {
+					stats.authoritative++
+				}
+			}
+		}
+		statsList = append(statsList, stats)
+	}
+	return statsList
+}
+
+// lookupNameServers resolves a zone's NS set and each name server's addresses
+func lookupNameServers(transport string, resolver string, zone string) ([]authServer, error) {
+	zone = queryName(zone)
+
+	m := &dns.Msg{}
+	m.SetQuestion(zone, dns.TypeNS)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	r, err := exchange(ctx, m, transport, resolver)
+	if err != nil {
+		return nil, err
+	}
+
+	var servers []authServer
+	for _, rr := range r.Answer {
+		ns, ok := rr.(*dns.NS)
+		if !ok {
+			continue
+		}
+		ips, _, err := lookupA(transport, resolver, ns.Ns)
+		if err != nil || len(ips) == 0 {
+			fmt.Printf("%s[!] %s: cannot resolve name server %s%s\n", ColorYellow, zone, ns.Ns, ColorReset)
+			continue
+		}
+		for _, ip := range ips {
+			servers = append(servers, authServer{Zone: zone, Host: ns.Ns, Addr: net.JoinHostPort(ip, "53")})
+		}
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no NS records (rcode %s)", dns.RcodeToString[r.Rcode])
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Host < servers[j].Host
+	})
+	return servers, nil
+}
+
+// zoneQueries returns the names to ask authoritative servers: the zone apex
+// SOA and NS plus any test domains inside the zone
+func zoneQueries(zone string, domains []string) []zoneQuery {
+	queries := []zoneQuery{
+		{Name: zone, Qtype: dns.TypeSOA},
+		{Name: zone, Qtype: dns.TypeNS},
+	}
+	for _, domain := range domains {
+		if name := queryName(domain); dns.IsSubDomain(zone, name) {
+			queries = append(queries, zoneQuery{Name: name, Qtype: dns.TypeA})
+		}
+	}
+	return queries
+}
+
+// queryAuthoritative sends a non-recursive query and reports the RTT and
+// whether the answer carried the AA flag
+func queryAuthoritative(addr string, name string, qtype uint16) (time.Duration, bool, error) {
+	m := &dns.Msg{}
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	m.RecursionDesired = false
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	start := time.Now()
+	r, err := exchange(ctx, m, TransportUDP, addr)
+	rtt := time.Since(start)
+	if err != nil {
+		return rtt, false, err
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return rtt, false, fmt.Errorf("rcode: %s", dns.RcodeToString[r.Rcode])
+	}
+	return rtt, r.Authoritative, nil
+}
+
+func printAuthoritative(statsList []*authStats) {
+	sort.SliceStable(statsList, func(i, j int) bool {
+		// Unreachable servers go last
+		if (len(statsList[i].rtts) == 0) != (len(statsList[j].rtts) == 0) {
+			return len(statsList[j].rtts) == 0
+		}
+		return percentile(statsList[i].rtts, 50) < percentile(statsList[j].rtts, 50)
+	})
+
+	fmt.Printf("%s[*] Authoritative Servers (sorted by p50 RTT):%s\n\n", ColorBlue, ColorReset)
+	fmt.Printf("%s%-40s | %-16s | %-12s | %-12s | %-12s | %-8s | %-8s%s\n",
+		ColorWhite, "Name Server", "Operator", "Min RTT", "p50 RTT", "p95 RTT", "Success", "AA", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "─────────────────────────────────────────┼──────────────────┼──────────────┼──────────────┼──────────────┼──────────┼─────────", ColorReset)
+
+	for _, stats := range statsList {
+		var minRTT time.Duration
+		for i, rtt := range stats.rtts {
+			if i == 0 || rtt < minRTT {
+				minRTT = rtt
+			}
+		}
+		successRate := float64(len(stats.rtts)) / float64(stats.total) * 100
+		successColor := ColorGreen
+		if successRate < 100 {
+			successColor = ColorRed
+		}
+		aaColor := ColorGreen
+		if stats.authoritative < len(stats.rtts) {
+			// Answers without AA mean the server is not authoritative (lame delegation)
+			aaColor = ColorRed
+		}
+
+		display := fmt.Sprintf("%s (%s)", strings.TrimSuffix(stats.server.Host, "."), stats.server.Addr)
+		fmt.Printf("%-40s | %-16s | %8.2f ms | %8.2f ms | %8.2f ms | %s%6.1f%%%s | %s%4d/%-3d%s\n",
+			display, nsProvider(stats.server.Host),
+			ms(minRTT), ms(percentile(stats.rtts, 50)), ms(percentile(stats.rtts, 95)),
+			successColor, successRate, ColorReset,
+			aaColor, stats.authoritative, len(stats.rtts), ColorReset,
+		)
+	}
+	fmt.Printf("\n")
+}
@@ -0,0 +1,29 @@
+//go:build darwin
+
+package main
+
+import (
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// bindInterface returns a socket control function that scopes the socket to
+// the interface with IP_BOUND_IF (IPV6_BOUND_IF for IPv6)
+func bindInterface(_ string, index int) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			if strings.HasSuffix(network, "6") {
+				sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_BOUND_IF, index)
+			} else {
+				sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_BOUND_IF, index)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
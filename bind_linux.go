@@ -0,0 +1,25 @@
+//go:build linux
+
+package main
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// bindInterface returns a socket control function that binds to the
+// interface with SO_BINDTODEVICE, so packets leave through it whatever the
+// routing table prefers (needs CAP_NET_RAW before Linux 5.7)
+func bindInterface(name string, _ int) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			sockErr = unix.SetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE, name)
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package main
+
+import "syscall"
+
+// bindInterface returns nil: elsewhere --interface only selects the source
+// address and the routing table picks the outgoing interface
+func bindInterface(string, int) func(network, address string, c syscall.RawConn) error {
+	return nil
+}
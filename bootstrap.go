@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// bootstrapResolver looks up DoT and DoH hostnames (--bootstrap) instead
+	// of the system resolver; empty keeps the system resolver
+	bootstrapResolver string
+
+	// hostOverrides pins hostnames to addresses (--resolve), skipping the
+	// lookup altogether, as curl's --resolve does
+	hostOverrides = make(map[string][]string)
+
+	// bootstrapCache holds the addresses looked up through bootstrapResolver,
+	// so each hostname is resolved once instead of before every connection
+	bootstrapCache   = make(map[string][]string)
+	bootstrapCacheMu sync.Mutex
+)
+
+// resolveFlag collects --resolve host=ip,ip
+type resolveFlag struct{}
+
+func (resolveFlag) String() string { return "" }
+
+func (resolveFlag) Set(value string) error {
+	host, list, found := strings.Cut(value, "=")
+	host = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+	if !found || host == "" || list == "" {
+		return fmt.Errorf("want host=ip[,ip], e.g. dns.google=8.8.8.8")
+	}
+	for _, addr := range splitList(list) {
+		if net.ParseIP(addr) == nil {
+			return fmt.Errorf("invalid IP address %q for %s", addr, host)
+		}
+		hostOverrides[host] = append(hostOverrides[host], addr)
+	}
+	return nil
+}
+
+// configureBootstrap checks --bootstrap, which must be an IP address so it
+// needs no lookup itself
+func configureBootstrap(addr string) error {
+	if addr == "" {
+		return nil
+	}
+	hostport, err := hostPort(addr, "53")
+	if err != nil {
+		return fmt.Errorf("invalid bootstrap resolver %q: %v", addr, err)
+	}
+	host, _, _ := net.SplitHostPort(hostport)
+	if net.ParseIP(host) == nil {
+		return fmt.Errorf("bootstrap resolver %q must be an IP address", addr)
+	}
+	bootstrapResolver = hostport
+	return nil
+}
+
+// bootstrapAddrs returns the addresses to dial for addr (host:port) when
+// --resolve or --bootstrap applies to its hostname, nil for IP addresses and
+// hostnames left to the system resolver
+func bootstrapAddrs(ctx context.Context, addr string) ([]string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return nil, nil
+	}
+	ips, err := bootstrapLookup(ctx, host)
+	if err != nil || ips == nil {
+		return nil, err
+	}
+	targets := make([]string, len(ips))
+	for i, ip := range ips {
+		targets[i] = net.JoinHostPort(ip, port)
+	}
+	return targets, nil
+}
+
+// bootstrapLookup returns the pinned or bootstrap-resolved addresses of host,
+// nil when neither flag applies
+func bootstrapLookup(ctx context.Context, host string) ([]string, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if ips, ok := hostOverrides[host]; ok {
+		return ips, nil
+	}
+	if bootstrapResolver == "" {
+		return nil, nil
+	}
+
+	bootstrapCacheMu.Lock()
+	ips, ok := bootstrapCache[host]
+	bootstrapCacheMu.Unlock()
+	if ok {
+		return ips, nil
+	}
+	found, err := resolverVia(bootstrapResolver).LookupIP(ctx, "ip", host)
+	if err != nil {
+		return nil, fmt.Errorf("bootstrap lookup of %s via %s: %w", host, bootstrapResolver, err)
+	}
+	for _, ip := range found {
+		ips = append(ips, ip.String())
+	}
+	bootstrapCacheMu.Lock()
+	bootstrapCache[host] = ips
+	bootstrapCacheMu.Unlock()
+	return ips, nil
+}
+
+// resolveBootstrap looks up the DoT and DoH hostnames of the run before the
+// first query, so no query's RTT includes the bootstrap lookup, and lists
+// the addresses they will be dialed at
+func resolveBootstrap(config *BenchmarkConfig) {
+	if bootstrapResolver == "" && len(hostOverrides) == 0 {
+		return
+	}
+	if config.Transport != TransportDoT && config.Transport != TransportDoH {
+		return
+	}
+
+	hosts := make(map[string]bool)
+	for _, server := range config.Servers {
+		for _, endpoint := range server.endpoints(config.Transport) {
+			if host := endpointHost(endpoint); host != "" && net.ParseIP(host) == nil {
+				hosts[strings.ToLower(host)] = true
+			}
+		}
+	}
+	if len(hosts) == 0 {
+		return
+	}
+	names := make([]string, 0, len(hosts))
+	for host := range hosts {
+		names = append(names, host)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("%s[*] Bootstrap addresses of the encrypted endpoints:%s\n", ColorBlue, ColorReset)
+	for _, host := range names {
+		ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+		ips, err := bootstrapLookup(ctx, host)
+		cancel()
+		switch {
+		case err != nil:
+			slog.Warn("Bootstrap lookup failed, queries to this endpoint will fail", "host", host, "err", err)
+		case ips == nil:
+			fmt.Printf("    %-30s system resolver\n", host)
+		case len(hostOverrides[host]) > 0:
+			fmt.Printf("    %-30s %s %s(--resolve)%s\n", host, strings.Join(ips, ", "), ColorCyan, ColorReset)
+		default:
+			fmt.Printf("    %-30s %s\n", host, strings.Join(ips, ", "))
+		}
+	}
+	fmt.Printf("\n")
+}
+
+// endpointHost returns the hostname of a DoT (host:port) or DoH (URL) endpoint
+func endpointHost(endpoint string) string {
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return ""
+		}
+		return u.Hostname()
+	}
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	return host
+}
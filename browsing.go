@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// thirdPartyDomains are hostnames typical pages load scripts, fonts and
+// trackers from; --third-parties takes the first N
+var thirdPartyDomains = []string{
+	"fonts.googleapis.com",
+	"fonts.gstatic.com",
+	"www.googletagmanager.com",
+	"www.google-analytics.com",
+	"cdn.jsdelivr.net",
+	"cdnjs.cloudflare.com",
+	"ajax.googleapis.com",
+	"connect.facebook.net",
+	"static.doubleclick.net",
+	"code.jquery.com",
+	"unpkg.com",
+	"www.gstatic.com",
+}
+
+// browseVisit is one simulated page visit through one resolver
+type browseVisit struct {
+	PageDNS      time.Duration // lookup of the page's own hostname
+	Fetch        time.Duration // connect, TLS and time to the first response
+	ThirdParty   time.Duration // slowest of the parallel third-party lookups
+	Blocked      int           // third-party names without an address
+	ThirdParties int           // third-party names looked up
+	Err          error
+}
+
+// pageStart is the critical path of the visit: the page's lookup, its
+// response, then the third-party lookups the page triggers
+func (v browseVisit) pageStart() time.Duration {
+	return v.PageDNS + v.Fetch + v.ThirdParty
+}
+
+// runBrowsing simulates first visits to every test domain through each
+// resolver: resolve the page, fetch it from the address that resolver gave,
+// then resolve the page's third-party hosts in parallel, as a browser does
+// once the HTML references them. The sum is the page-start latency a user
+// waits for before the page can render.
+func runBrowsing(config *BenchmarkConfig, thirdParties int) {
+	pages := config.pages()
+	typical := thirdPartyDomains[:min(thirdParties, len(thirdPartyDomains))]
+	// With --subresources, each fetched page brings the hosts it really uses
+	thirdPartyOf := func(page string) []string {
+		if hosts, ok := config.Subresources[page]; ok {
+			return hosts
+		}
+		return typical
+	}
+	if config.Subresources != nil {
+		fmt.Printf("%s[*] Simulating %d visits to each of %d pages with the third-party hosts they reference through %d resolvers...%s\n\n",
+			ColorBlue, config.QueryNum, len(pages), len(config.Servers), ColorReset)
+	} else {
+		fmt.Printf("%s[*] Simulating %d visits to each of %d pages with %d third-party hosts through %d resolvers...%s\n\n",
+			ColorBlue, config.QueryNum, len(pages), len(typical), len(config.Servers), ColorReset)
+	}
+
+	type resolverVisits struct {
+		name, addr string
+		visits     []browseVisit
+	}
+	var resolvers []*resolverVisits
+	for _, server := range config.Servers {
+		if addrs := server.endpoints(config.Transport); len(addrs) > 0 {
+			resolvers = append(resolvers, &resolverVisits{name: server.Name, addr: addrs[0]})
+		}
+	}
+
+	// Resolvers take turns on every page, so a slow moment of the network
+	// or a site is spread over all of them
+	for i := 0; i < config.QueryNum; i++ {
+		for _, domain := range pages {
+			for _, resolver := range resolvers {
+				visit := visitPage(config, resolver.addr, domain, thirdPartyOf(domain))
+				resolver.visits = append(resolver.visits, visit)
+				if visit.Err != nil {
+					slog.Debug("visit failed", "resolver", resolver.name, "page", domain, "err", visit.Err)
+				}
+			}
+		}
+	}
+
+	type browseSummary struct {
+		name, addr                     string
+		pageDNS, fetch, thirdParty     time.Duration
+		median, p95                    time.Duration
+		visits, failed, blocked, total int
+		lastErr                        error
+	}
+	var summaries []browseSummary
+	for _, resolver := range resolvers {
+		summary := browseSummary{name: resolver.name, addr: resolver.addr, visits: len(resolver.visits)}
+		var starts []time.Duration
+		for _, visit := range resolver.visits {
+			if visit.Err != nil {
+				summary.failed++
+				summary.lastErr = visit.Err
+				continue
+			}
+			summary.pageDNS += visit.PageDNS
+			summary.fetch += visit.Fetch
+			summary.thirdParty += visit.ThirdParty
+			summary.blocked += visit.Blocked
+			summary.total += visit.ThirdParties
+			starts = append(starts, visit.pageStart())
+		}
+		if n := len(starts); n > 0 {
+			summary.pageDNS /= time.Duration(n)
+			summary.fetch /= time.Duration(n)
+			summary.thirdParty /= time.Duration(n)
+			summary.median, summary.p95 = percentile(starts, 50), percentile(starts, 95)
+		}
+		summaries = append(summaries, summary)
+	}
+	// Fastest median first; resolvers without a successful visit last
+	slices.SortStableFunc(summaries, func(a, b browseSummary) int {
+		switch {
+		case a.median == b.median:
+			return 0
+		case a.median == 0:
+			return 1
+		case b.median == 0:
+			return -1
+		case a.median < b.median:
+			return -1
+		}
+		return 1
+	})
+
+	fmt.Printf("%s%-30s | %-10s | %-10s | %-11s | %-12s | %-10s | %-9s | %s%s\n",
+		ColorWhite, "Server", "Page DNS", "Fetch", "3rd-party", "Page start", "P95", "No addr", "Failed", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼────────────┼────────────┼─────────────┼──────────────┼────────────┼───────────┼───────", ColorReset)
+	for i, summary := range summaries {
+		display := fmt.Sprintf("%s (%s)", summary.name, summary.addr)
+		if summary.median == 0 {
+			fmt.Printf("%-30s | %sno successful visit: %v%s\n", display, ColorRed, summary.lastErr, ColorReset)
+			continue
+		}
+		color := ""
+		if i == 0 {
+			color = ColorGreen
+		}
+		failedColor := ColorGreen
+		if summary.failed > 0 {
+			failedColor = ColorRed
+		}
+		fmt.Printf("%-30s | %7.1f ms | %7.1f ms | %8.1f ms | %s%9.1f ms%s | %7.1f ms | %4d/%-4d | %s%d/%d%s\n",
+			display, ms(summary.pageDNS), ms(summary.fetch), ms(summary.thirdParty),
+			color, ms(summary.median), ColorReset, ms(summary.p95),
+			summary.blocked, summary.total, failedColor, summary.failed, summary.visits, ColorReset)
+	}
+	fmt.Printf("\n%s    Page start (median) = page DNS + fetch (connect, TLS, first response) + the slowest third-party lookup;%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s    fetch also reflects the server each resolver steers you to. No addr counts blocked or failed third-party names.%s\n\n", ColorCyan, ColorReset)
+}
+
+// visitPage runs one simulated visit of domain through the resolver at addr
+func visitPage(config *BenchmarkConfig, addr string, domain string, thirdParty []string) browseVisit {
+	var visit browseVisit
+	var ips []string
+	ips, visit.PageDNS, visit.Err = browseLookup(config.Transport, addr, domain)
+	if visit.Err != nil {
+		return visit
+	}
+	if len(ips) == 0 {
+		visit.Err = fmt.Errorf("%s has no address", domain)
+		return visit
+	}
+
+	visit.Fetch, visit.Err = fetchPage(config, domain, ips[0])
+	if visit.Err != nil {
+		return visit
+	}
+
+	visit.ThirdParties = len(thirdParty)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, host := range thirdParty {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ips, elapsed, err := browseLookup(config.Transport, addr, host)
+			mu.Lock()
+			defer mu.Unlock()
+			visit.ThirdParty = max(visit.ThirdParty, elapsed)
+			if err != nil || len(ips) == 0 {
+				visit.Blocked++
+			}
+		}()
+	}
+	wg.Wait()
+	return visit
+}
+
+// browseLookup resolves the A records of host, returning how long the
+// answer (or the failure) took
+func browseLookup(transport string, addr string, host string) ([]string, time.Duration, error) {
+	m := &dns.Msg{}
+	m.SetQuestion(queryName(host), dns.TypeA)
+	m.RecursionDesired = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutFor(addr))
+	defer cancel()
+	start := time.Now()
+	r, err := exchange(ctx, m, transport, addr)
+	elapsed := time.Since(start)
+	if err != nil {
+		return nil, elapsed, err
+	}
+	var ips []string
+	for _, rr := range r.Answer {
+		if a, ok := rr.(*dns.A); ok {
+			ips = append(ips, a.A.String())
+		}
+	}
+	return ips, elapsed, nil
+}
+
+// fetchPage requests https://domain/ from ip over a fresh connection and
+// returns the time until the response headers arrived; redirects are not
+// followed, as they would need another lookup
+func fetchPage(config *BenchmarkConfig, domain string, ip string) (time.Duration, error) {
+	client := &http.Client{
+		Timeout: config.HTTPTimeout,
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			DialContext: func(ctx context.Context, network string, hostport string) (net.Conn, error) {
+				_, port, err := net.SplitHostPort(hostport)
+				if err != nil {
+					return nil, err
+				}
+				return dialContext(ctx, network, net.JoinHostPort(ip, port))
+			},
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	start := time.Now()
+	resp, err := client.Get("https://" + domain + "/")
+	elapsed := time.Since(start)
+	if err != nil {
+		return elapsed, err
+	}
+	resp.Body.Close()
+	return elapsed, nil
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// bulkProgressEvery controls how often progress is printed during bulk runs
+const bulkProgressEvery = 5 * time.Second
+
+// bulkAnswer is the outcome of resolving one hostname
+type bulkAnswer struct {
+	Name    string
+	RTT     time.Duration
+	Status  string // NOERROR, NXDOMAIN, SERVFAIL, ..., TIMEOUT or ERROR
+	Answers []string
+	Err     error
+}
+
+// runBulk implements "dnsbench bulk": it resolves every hostname of a file
+// (one per line, # comments allowed) through one resolver at high
+// concurrency and reports throughput, latency and errors
+func runBulk(args []string) {
+	fs := flag.NewFlagSet("bulk", flag.ExitOnError)
+	file := fs.String("file", "", "file with one hostname per line (- for stdin, required)")
+	server := fs.String("server", "", "resolver: IP[:port], tls://host[:port] or a DoH URL (default: first resolv.conf nameserver)")
+	transport := fs.String("transport", TransportUDP, "transport for plain server addresses: udp or tcp")
+	qtype := fs.String("type", "A", "record type to resolve")
+	concurrency := fs.Int("concurrency", 64, "maximum number of in-flight queries")
+	timeout := fs.Duration("timeout", 3*time.Second, "per-query timeout")
+	out := fs.String("o", "", "write name,status,rtt_ms,answers for every hostname to this CSV file")
+	fs.Parse(args)
+
+	if *file == "" {
+		fmt.Printf("%s[!] bulk requires --file hosts.txt%s\n", ColorRed, ColorReset)
+		os.Exit(2)
+	}
+	rrtype, ok := dns.StringToType[strings.ToUpper(*qtype)]
+	if !ok {
+		fmt.Printf("%s[!] Unknown record type %q%s\n", ColorRed, *qtype, ColorReset)
+		os.Exit(2)
+	}
+	addr, proto, err := queryServer(*server, *transport)
+	if err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(2)
+	}
+	*concurrency = max(*concurrency, 1)
+
+	var input io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	var csvOut *csv.Writer
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		defer f.Close()
+		csvOut = csv.NewWriter(f)
+		csvOut.Write([]string{"name", "status", "rtt_ms", "answers"})
+		defer csvOut.Flush()
+	}
+
+	fmt.Printf("%s[*] Resolving %s records from %s via %s (%s), concurrency %d...%s\n\n",
+		ColorBlue, dns.TypeToString[rrtype], *file, addr, strings.ToUpper(proto), *concurrency, ColorReset)
+
+	names := make(chan string)
+	answers := make(chan *bulkAnswer, *concurrency)
+	var wg sync.WaitGroup
+	for w := 0; w < *concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				answers <- resolveBulk(proto, addr, name, rrtype, *timeout)
+			}
+		}()
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(input)
+		for scanner.Scan() {
+			line, _, _ := strings.Cut(scanner.Text(), "#")
+			// hosts-file style lines keep the name in the last field
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			names <- fields[len(fields)-1]
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Printf("%s[!] Reading %s: %v%s\n", ColorYellow, *file, err, ColorReset)
+		}
+		close(names)
+		wg.Wait()
+		close(answers)
+	}()
+
+	start := time.Now()
+	lastProgress := start
+	statusCounts := make(map[string]int)
+	var rtts []time.Duration
+	var slowest []*bulkAnswer
+	total := 0
+	for answer := range answers {
+		total++
+		statusCounts[answer.Status]++
+		if answer.Err == nil {
+			rtts = append(rtts, answer.RTT)
+			slowest = append(slowest, answer)
+			if len(slowest) > 20 {
+				sort.Slice(slowest, func(i, j int) bool { return slowest[i].RTT > slowest[j].RTT })
+				slowest = slowest[:10]
+			}
+		}
+		if csvOut != nil {
+			csvOut.Write([]string{answer.Name, answer.Status, strconv.FormatFloat(ms(answer.RTT), 'f', 3, 64), strings.Join(answer.Answers, " ")})
+		}
+		if time.Since(lastProgress) >= bulkProgressEvery {
+			lastProgress = time.Now()
+			fmt.Printf("%s    %d names resolved (%.0f/s)%s\n", ColorCyan, total, float64(total)/time.Since(start).Seconds(), ColorReset)
+		}
+	}
+	elapsed := time.Since(start)
+
+	printBulkReport(total, elapsed, statusCounts, rtts, slowest)
+}
+
+// resolveBulk resolves one hostname and classifies the outcome
+func resolveBulk(transport string, addr string, name string, rrtype uint16, timeout time.Duration) *bulkAnswer {
+	answer := &bulkAnswer{Name: name}
+	fqdn, err := toASCII(name)
+	if err != nil {
+		answer.Status, answer.Err = "INVALID", err
+		return answer
+	}
+
+	m := &dns.Msg{}
+	m.SetQuestion(dns.Fqdn(fqdn), rrtype)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	start := time.Now()
+	r, err := exchange(ctx, m, transport, addr)
+	answer.RTT = time.Since(start)
+	switch {
+	case err != nil && isTimeout(err):
+		answer.Status, answer.Err = "TIMEOUT", err
+		return answer
+	case err != nil:
+		answer.Status, answer.Err = "ERROR", err
+		return answer
+	}
+
+	answer.Status = dns.RcodeToString[r.Rcode]
+	for _, rr := range r.Answer {
+		if rr.Header().Rrtype == rrtype {
+			answer.Answers = append(answer.Answers, strings.TrimPrefix(rr.String(), rr.Header().String()))
+		}
+	}
+	return answer
+}
+
+// printBulkReport prints throughput, the status breakdown, RTT percentiles
+// and the slowest names
+func printBulkReport(total int, elapsed time.Duration, statusCounts map[string]int, rtts []time.Duration, slowest []*bulkAnswer) {
+	fmt.Printf("\n%s╔════════════════════════════════════════════════════════════╗%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s║                    BULK RESOLUTION REPORT                  ║%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s╚════════════════════════════════════════════════════════════╝%s\n\n", ColorCyan, ColorReset)
+
+	if total == 0 {
+		fmt.Printf("%s[!] No hostnames in input%s\n", ColorYellow, ColorReset)
+		return
+	}
+	fmt.Printf("    Names: %d in %v (%.0f queries/s)\n\n", total, elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds())
+
+	var statuses []string
+	for status := range statusCounts {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statusCounts[statuses[i]] > statusCounts[statuses[j]] })
+	fmt.Printf("%s%-12s | %-10s | %-8s%s\n", ColorWhite, "Status", "Count", "Share", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "─────────────┼────────────┼─────────", ColorReset)
+	for _, status := range statuses {
+		color := ColorRed
+		if status == "NOERROR" {
+			color = ColorGreen
+		} else if status == "NXDOMAIN" {
+			color = ColorYellow
+		}
+		fmt.Printf("%s%-12s%s | %10d | %7.1f%%\n", color, status, ColorReset, statusCounts[status], float64(statusCounts[status])/float64(total)*100)
+	}
+
+	if len(rtts) > 0 {
+		fmt.Printf("\n%s[*] Latency of answered queries:%s p50 %.2f ms, p90 %.2f ms, p99 %.2f ms, max %.2f ms\n",
+			ColorBlue, ColorReset, ms(percentile(rtts, 50)), ms(percentile(rtts, 90)), ms(percentile(rtts, 99)), ms(percentile(rtts, 100)))
+
+		sort.Slice(slowest, func(i, j int) bool { return slowest[i].RTT > slowest[j].RTT })
+		fmt.Printf("\n%s[*] Slowest names:%s\n", ColorBlue, ColorReset)
+		for _, answer := range slowest[:min(len(slowest), 10)] {
+			fmt.Printf("    %-40s %8.2f ms  %s\n", answer.Name, ms(answer.RTT), answer.Status)
+		}
+	}
+	fmt.Printf("\n")
+}
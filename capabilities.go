@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/quic"
+)
+
+// Capability cells: supported, not supported, not offered by the provider
+// (no DoT or DoH endpoint listed) and probe failed
+const (
+	capYes     = "yes"
+	capNo      = "no"
+	capNone    = "-"
+	capUnknown = "?"
+)
+
+// qnameMinTestName answers "HOORAY" when the resolver minimises query names
+// (RFC 9156) and "NO" when it sends the full name to every zone
+const qnameMinTestName = "qnamemintest.internet.nl."
+
+// signedTestName is a DNSSEC-signed zone used to check validation
+const signedTestName = "isc.org"
+
+// providerCapabilities holds the probe outcomes of one provider, by column
+type providerCapabilities struct {
+	ServerName string
+	Cells      map[string]string
+}
+
+// capabilityColumns lists the capability table in display order
+var capabilityColumns = []string{"DNSSEC", "ECS", "Cookies", "TCP", "DoT", "DoH", "DoQ", "QNAME Min", "Padding"}
+
+// runCapabilities probes every provider for the protocol features the
+// other modes test one at a time and prints them as one table, apart from
+// any latency numbers: DNSSEC validation, EDNS Client Subnet, DNS cookies,
+// TCP, the encrypted transports, QNAME minimisation and EDNS padding
+func runCapabilities(config *BenchmarkConfig) {
+	fmt.Printf("%s[*] Probing provider capabilities...%s\n\n", ColorBlue, ColorReset)
+
+	results := make([]*providerCapabilities, len(config.Servers))
+	var wg sync.WaitGroup
+	for i, server := range config.Servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = probeCapabilities(config.Transport, server)
+		}()
+	}
+	wg.Wait()
+	printCapabilities(results)
+}
+
+// probeCapabilities runs every probe against one provider. Plain DNS probes
+// go to the primary over UDP, or to the configured transport for providers
+// without a plain address.
+func probeCapabilities(transport string, server *DNSServer) *providerCapabilities {
+	caps := &providerCapabilities{ServerName: server.Name, Cells: make(map[string]string)}
+	baseTransport, base := TransportUDP, server.Primary
+	if base == "" {
+		baseTransport = transport
+		if endpoints := server.endpoints(transport); len(endpoints) > 0 {
+			base = endpoints[0]
+		}
+	}
+	if base == "" {
+		for _, column := range capabilityColumns {
+			caps.Cells[column] = capUnknown
+		}
+		return caps
+	}
+
+	caps.Cells["DNSSEC"] = probeValidation(baseTransport, base)
+	caps.Cells["ECS"] = probeECS(baseTransport, base)
+	caps.Cells["Cookies"] = probeCookies(baseTransport, base)
+	caps.Cells["TCP"] = capNone
+	if server.Primary != "" {
+		caps.Cells["TCP"] = probeTransport(TransportTCP, server.Primary)
+	}
+	caps.Cells["DoT"], caps.Cells["DoH"] = capNone, capNone
+	if endpoints := server.endpoints(TransportDoT); len(endpoints) > 0 {
+		caps.Cells["DoT"] = probeTransport(TransportDoT, endpoints[0])
+	}
+	if endpoints := server.endpoints(TransportDoH); len(endpoints) > 0 {
+		caps.Cells["DoH"] = probeTransport(TransportDoH, endpoints[0])
+	}
+	caps.Cells["DoQ"] = probeDoQ(server)
+	caps.Cells["QNAME Min"] = probeQNAMEMinimisation(baseTransport, base)
+
+	// Padding only hides anything on an encrypted transport, so prefer DoT
+	paddingTransport, paddingAddr := baseTransport, base
+	if caps.Cells["DoT"] == capYes {
+		paddingTransport, paddingAddr = TransportDoT, server.endpoints(TransportDoT)[0]
+	}
+	padded, err := probePadding(paddingTransport, paddingAddr)
+	caps.Cells["Padding"] = capabilityCell(padded, err)
+	return caps
+}
+
+// capabilityCell renders a probe outcome as a capability cell
+func capabilityCell(supported bool, err error) string {
+	switch {
+	case err != nil:
+		return capUnknown
+	case supported:
+		return capYes
+	}
+	return capNo
+}
+
+// capabilityQuery sends m with the resolver's timeout
+func capabilityQuery(transport, addr string, m *dns.Msg) (*dns.Msg, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), max(timeoutFor(addr), 3*time.Second))
+	defer cancel()
+	return exchange(ctx, m, transport, addr)
+}
+
+// probeTransport reports whether addr answers a plain A query over transport
+func probeTransport(transport, addr string) string {
+	m := &dns.Msg{}
+	m.SetQuestion("example.com.", dns.TypeA)
+	r, err := capabilityQuery(transport, addr, m)
+	return capabilityCell(err == nil && r.Rcode == dns.RcodeSuccess, nil)
+}
+
+// probeValidation reports whether the resolver sets the AD flag on a
+// signed answer
+func probeValidation(transport, addr string) string {
+	_, r, err := queryDO(transport, addr, signedTestName)
+	if err != nil || r.Rcode != dns.RcodeSuccess {
+		return capUnknown
+	}
+	return capabilityCell(r.AuthenticatedData, nil)
+}
+
+// probeECS reports whether the resolver forwards a client subnet to
+// authoritative servers, with the prefix length when it does
+func probeECS(transport, addr string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), max(timeoutFor(addr), 3*time.Second))
+	defer cancel()
+	egress, err := lookupEgress(ctx, transport, addr)
+	switch {
+	case err != nil:
+		return capUnknown
+	case egress.ECS == "":
+		return capNo
+	}
+	if _, prefix, found := strings.Cut(egress.ECS, "/"); found {
+		return capYes + " /" + prefix
+	}
+	return capYes
+}
+
+// probeCookies sends an RFC 7873 client cookie and reports whether the
+// response carries a server cookie after it
+func probeCookies(transport, addr string) string {
+	client := make([]byte, 8)
+	rand.Read(client)
+	m := &dns.Msg{}
+	m.SetQuestion("example.com.", dns.TypeA)
+	m.SetEdns0(dns.DefaultMsgSize, false)
+	opt := m.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: hex.EncodeToString(client)})
+
+	r, err := capabilityQuery(transport, addr, m)
+	if err != nil {
+		return capUnknown
+	}
+	if opt := r.IsEdns0(); opt != nil {
+		for _, option := range opt.Option {
+			// The client cookie is 16 hex digits, a server cookie adds 16 to 64
+			if cookie, ok := option.(*dns.EDNS0_COOKIE); ok && len(cookie.Cookie) > 16 {
+				return capYes
+			}
+		}
+	}
+	return capNo
+}
+
+// probeQNAMEMinimisation asks internet.nl's test zone, whose answer depends
+// on the query names its servers saw
+func probeQNAMEMinimisation(transport, addr string) string {
+	m := &dns.Msg{}
+	m.SetQuestion(qnameMinTestName, dns.TypeTXT)
+	r, err := capabilityQuery(transport, addr, m)
+	if err != nil {
+		return capUnknown
+	}
+	for _, rr := range r.Answer {
+		if txt, ok := rr.(*dns.TXT); ok {
+			text := strings.Join(txt.Txt, "")
+			switch {
+			case strings.HasPrefix(text, "HOORAY"):
+				return capYes
+			case strings.HasPrefix(text, "NO"):
+				return capNo
+			}
+		}
+	}
+	return capUnknown
+}
+
+// probeDoQ tries DNS over QUIC (RFC 9250) on port 853 of the DoT host, or of
+// the primary address when no DoT endpoint is listed. QUIC cannot run
+// through --proxy, so the probe is skipped there.
+func probeDoQ(server *DNSServer) string {
+	if proxyURL != nil {
+		return capNone
+	}
+	host := server.DoT
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "" {
+		h, _, err := net.SplitHostPort(server.Primary)
+		if err != nil {
+			return capNone
+		}
+		host = h
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), max(timeoutFor(host), 3*time.Second))
+	defer cancel()
+	m := &dns.Msg{}
+	m.SetQuestion("example.com.", dns.TypeA)
+	r, err := exchangeDoQ(ctx, m, net.JoinHostPort(host, "853"))
+	return capabilityCell(err == nil && r.Rcode == dns.RcodeSuccess, nil)
+}
+
+// exchangeDoQ sends one query on a fresh QUIC connection: a stream per
+// query, the message prefixed with its length and ID 0 as RFC 9250 requires
+func exchangeDoQ(ctx context.Context, m *dns.Msg, addr string) (*dns.Msg, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := quic.Listen("udp", ":0", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		endpoint.Close(closeCtx)
+	}()
+
+	socketsOpened.Add(1)
+	conn, err := endpoint.Dial(ctx, "udp", addr, &quic.Config{TLSConfig: &tls.Config{
+		ServerName: host,
+		NextProtos: []string{"doq"},
+		MinVersion: tls.VersionTLS13,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Abort(nil)
+
+	stream, err := conn.NewStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream.SetReadContext(ctx)
+	stream.SetWriteContext(ctx)
+
+	q := m.Copy()
+	q.Id = 0
+	packed, err := q.Pack()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stream.Write(binary.BigEndian.AppendUint16(nil, uint16(len(packed)))); err != nil {
+		return nil, err
+	}
+	if _, err := stream.Write(packed); err != nil {
+		return nil, err
+	}
+	stream.CloseWrite()
+
+	var length [2]byte
+	if _, err := io.ReadFull(stream, length[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(stream, buf); err != nil {
+		return nil, err
+	}
+	r := &dns.Msg{}
+	if err := r.Unpack(buf); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func printCapabilities(results []*providerCapabilities) {
+	fmt.Printf("%s%-24s", ColorWhite, "Provider")
+	separator := "─────────────────────────"
+	for _, column := range capabilityColumns {
+		fmt.Printf(" | %-9s", column)
+		separator += "┼───────────"
+	}
+	fmt.Printf("%s\n", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, separator, ColorReset)
+
+	for _, caps := range results {
+		fmt.Printf("%-24s", caps.ServerName)
+		for _, column := range capabilityColumns {
+			cell := caps.Cells[column]
+			color := ColorReset
+			switch {
+			case column == "ECS" && strings.HasPrefix(cell, capYes):
+				// Forwarding the client subnet helps CDNs but leaks part of the address
+				color = ColorYellow
+			case cell == capYes:
+				color = ColorGreen
+			case cell == capNo:
+				color = ColorRed
+			case cell == capUnknown:
+				color = ColorYellow
+			}
+			fmt.Printf(" | %s%-9s%s", color, cell, ColorReset)
+		}
+		fmt.Printf("\n")
+	}
+
+	fmt.Printf("\n%s    DNSSEC: validates signed answers (AD flag)   ECS: forwards your subnet to authoritative servers%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s    Cookies: returns an RFC 7873 server cookie   QNAME Min: sends authoritatives only the labels they need%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s    Padding: pads responses (RFC 7830), over DoT where offered   - = no endpoint listed, ? = probe got no usable answer%s\n\n", ColorCyan, ColorReset)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	// catalogURL is where update-providers fetches the catalog; the detached
+	// signature is expected at the same URL with a ".sig" suffix
+	catalogURL = "https://raw.githubusercontent.com/andrizan/DNSBench/main/providers.json"
+
+	// catalogPublicKey is the base64 Ed25519 key catalogs must be signed with,
+	// set at build time from providers.json.pub with
+	// -ldflags "-X main.catalogPublicKey=..."
+	catalogPublicKey = ""
+)
+
+// maxCatalogSize bounds the catalog download
+const maxCatalogSize = 1 << 20
+
+// ProviderCatalog is the signed JSON list of public resolvers that replaces
+// the embedded catalog once installed
+type ProviderCatalog struct {
+	Version   int                 `json:"version"`
+	Updated   time.Time           `json:"updated"`
+	Defaults  []string            `json:"defaults,omitempty"`
+	Regions   map[string][]string `json:"regions,omitempty"`
+	Providers []*DNSServer        `json:"providers"`
+}
+
+// catalogPath returns where the installed catalog lives in the user's config directory
+func catalogPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "dnsbench", "providers.json"), nil
+}
+
+// parseCatalog decodes a catalog and checks every provider is usable
+func parseCatalog(data []byte) (*ProviderCatalog, error) {
+	catalog := &ProviderCatalog{}
+	if err := json.Unmarshal(data, catalog); err != nil {
+		return nil, fmt.Errorf("invalid catalog: %w", err)
+	}
+	if len(catalog.Providers) == 0 {
+		return nil, fmt.Errorf("invalid catalog: no providers")
+	}
+
+	seen := make(map[string]bool)
+	for _, server := range catalog.Providers {
+		if server.ID == "" || server.Name == "" {
+			return nil, fmt.Errorf("invalid catalog: provider without id or name")
+		}
+		if seen[server.ID] {
+			return nil, fmt.Errorf("invalid catalog: duplicate provider %q", server.ID)
+		}
+		seen[server.ID] = true
+		if server.Primary == "" && server.DoT == "" && server.DoH == "" {
+			return nil, fmt.Errorf("invalid catalog: provider %q has no address", server.ID)
+		}
+		for _, addr := range []*string{&server.Primary, &server.Secondary} {
+			if *addr == "" {
+				continue
+			}
+			var err error
+			if *addr, err = hostPort(*addr, defaultDNSPort); err != nil {
+				return nil, fmt.Errorf("invalid catalog: provider %q: %w", server.ID, err)
+			}
+		}
+		switch server.Category {
+		case "", CategoryUnfiltered, CategorySecurity, CategoryFamily:
+		default:
+			return nil, fmt.Errorf("invalid catalog: provider %q has unknown category %q", server.ID, server.Category)
+		}
+	}
+	for _, id := range catalog.Defaults {
+		if !seen[id] {
+			return nil, fmt.Errorf("invalid catalog: default provider %q is not listed", id)
+		}
+	}
+	for region, ids := range catalog.Regions {
+		for _, id := range ids {
+			if !seen[id] {
+				return nil, fmt.Errorf("invalid catalog: region %q lists unknown provider %q", region, id)
+			}
+		}
+	}
+	return catalog, nil
+}
+
+// loadInstalledCatalog returns the catalog installed by update-providers,
+// or nil when none is installed
+func loadInstalledCatalog() (*ProviderCatalog, error) {
+	path, err := catalogPath()
+	if err != nil {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := parseCatalog(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return catalog, nil
+}
+
+// useCatalog replaces the embedded providers with an installed catalog.
+// Default providers missing from the catalog are dropped from the default run.
+func useCatalog(catalog *ProviderCatalog) {
+	builtinProviders = catalog.Providers
+	if len(catalog.Regions) > 0 {
+		regionPresets = catalog.Regions
+	} else {
+		regionPresets = nil
+	}
+	if len(catalog.Defaults) > 0 {
+		defaultProviders = catalog.Defaults
+		return
+	}
+	ids := providerIDs(catalog.Providers)
+	defaultProviders = slices.DeleteFunc(slices.Clone(defaultProviders), func(id string) bool {
+		return !slices.Contains(ids, id)
+	})
+	if len(defaultProviders) == 0 {
+		defaultProviders = ids
+	}
+}
+
+// runUpdateProviders implements "dnsbench update-providers": it downloads
+// the catalog and its signature, verifies both and installs the catalog
+func runUpdateProviders(args []string) {
+	fs := flag.NewFlagSet("update-providers", flag.ExitOnError)
+	source := fs.String("url", catalogURL, "catalog URL (signature is fetched from URL.sig)")
+	pubKey := fs.String("pubkey", catalogPublicKey, "base64 Ed25519 public key the catalog must be signed with")
+	reset := fs.Bool("reset", false, "remove the installed catalog and go back to the built-in providers")
+	fs.Parse(args)
+
+	path, err := catalogPath()
+	if err != nil {
+		fmt.Printf("%s[!] Cannot locate config directory: %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+
+	if *reset {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		fmt.Printf("%s[✓] Removed installed catalog, using built-in providers%s\n", ColorGreen, ColorReset)
+		return
+	}
+
+	if *pubKey == "" {
+		fmt.Printf("%s[!] No catalog key compiled in; pass --pubkey (the key is in providers.json.pub)%s\n", ColorRed, ColorReset)
+		os.Exit(2)
+	}
+	key, err := base64.StdEncoding.DecodeString(*pubKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		fmt.Printf("%s[!] Invalid public key: want %d base64-encoded bytes%s\n", ColorRed, ed25519.PublicKeySize, ColorReset)
+		os.Exit(2)
+	}
+
+	fmt.Printf("%s[*] Fetching provider catalog from %s...%s\n", ColorBlue, *source, ColorReset)
+	data, err := fetchCatalogFile(*source)
+	if err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	sig, err := fetchCatalogFile(*source + ".sig")
+	if err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	sig, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		fmt.Printf("%s[!] Invalid catalog signature encoding: %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	if !ed25519.Verify(key, data, sig) {
+		fmt.Printf("%s[!] Catalog signature verification failed, nothing installed%s\n", ColorRed, ColorReset)
+		os.Exit(1)
+	}
+
+	catalog, err := parseCatalog(data)
+	if err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	if installed, _ := loadInstalledCatalog(); installed != nil && installed.Version > catalog.Version {
+		fmt.Printf("%s[!] Refusing to downgrade catalog from version %d to %d%s\n", ColorRed, installed.Version, catalog.Version, ColorReset)
+		os.Exit(1)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s[✓] Installed catalog version %d (%d providers, updated %s)%s\n",
+		ColorGreen, catalog.Version, len(catalog.Providers), catalog.Updated.Format("2006-01-02"), ColorReset)
+	fmt.Printf("    %s\n", path)
+}
+
+// fetchCatalogFile downloads a catalog or signature file
+func fetchCatalogFile(source string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch %s: %s", source, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxCatalogSize))
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// domainCategories tags domains with a category (--domain-category), e.g.
+// cdn or local, for the per-category latency table
+var domainCategories = make(map[string]string)
+
+// defaultDomainCategories tag the built-in domain list when neither
+// --domains nor --domain-category is given
+var defaultDomainCategories = map[string]string{
+	"google.com":     "global",
+	"youtube.com":    "global",
+	"microsoft.com":  "global",
+	"apple.com":      "global",
+	"openai.com":     "global",
+	"facebook.com":   "social",
+	"x.com":          "social",
+	"github.com":     "dev",
+	"gitlab.com":     "dev",
+	"netflix.com":    "cdn",
+	"cloudflare.com": "cdn",
+	"shopee.co.id":   "local",
+}
+
+// domainCategoryFlag collects --domain-category category=domain,domain
+type domainCategoryFlag struct{}
+
+func (domainCategoryFlag) String() string { return "" }
+
+func (domainCategoryFlag) Set(value string) error {
+	category, list, found := strings.Cut(value, "=")
+	category = strings.ToLower(strings.TrimSpace(category))
+	if !found || category == "" || list == "" {
+		return fmt.Errorf("want category=domain,domain, e.g. local=tokopedia.com,detik.com")
+	}
+	for _, domain := range splitList(list) {
+		if _, err := toASCII(domain); err != nil {
+			return fmt.Errorf("invalid domain %q: %v", domain, err)
+		}
+		if other, ok := domainCategories[domain]; ok && other != category {
+			return fmt.Errorf("%s is in both %s and %s", domain, other, category)
+		}
+		domainCategories[domain] = category
+	}
+	return nil
+}
+
+// categorizedDomains returns the tagged domains missing from domains, so
+// tagging a domain also benchmarks it
+func categorizedDomains(domains []string) []string {
+	var extra []string
+	for domain := range domainCategories {
+		if !slices.Contains(domains, domain) {
+			extra = append(extra, domain)
+		}
+	}
+	sort.Strings(extra)
+	return extra
+}
+
+// CategoryStats holds one server's latency for one domain category
+type CategoryStats struct {
+	Category       string
+	AvgRTT         time.Duration
+	TotalQueries   int
+	SuccessQueries int
+
+	rttTotal time.Duration
+}
+
+// addCategory accumulates a result under its server and category; results
+// without a category are not tracked
+func (a *aggregator) addCategory(key string, result *BenchmarkResult) {
+	if result.Category == "" {
+		return
+	}
+	if a.categories[key] == nil {
+		a.categories[key] = make(map[string]*CategoryStats)
+	}
+	stats := a.categories[key][result.Category]
+	if stats == nil {
+		stats = &CategoryStats{Category: result.Category}
+		a.categories[key][result.Category] = stats
+	}
+	stats.TotalQueries++
+	if result.Status == "SUCCESS" {
+		stats.SuccessQueries++
+		stats.rttTotal += result.RTT
+	}
+}
+
+// categoryStats returns a snapshot of the per-category statistics keyed by
+// server ("name - addr") and category
+func (a *aggregator) categoryStats() map[string]map[string]*CategoryStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	snapshot := make(map[string]map[string]*CategoryStats, len(a.categories))
+	for key, categories := range a.categories {
+		snapshot[key] = make(map[string]*CategoryStats, len(categories))
+		for category, stats := range categories {
+			c := *stats
+			if c.SuccessQueries > 0 {
+				c.AvgRTT = c.rttTotal / time.Duration(c.SuccessQueries)
+			}
+			snapshot[key][category] = &c
+		}
+	}
+	return snapshot
+}
+
+// printCategoryBreakdown shows each server's average RTT per domain
+// category when the domains span more than one; categories at least 1.5x
+// slower than the server's fastest one are highlighted, e.g. a resolver
+// that is fast for global CDNs but slow for local sites
+func printCategoryBreakdown(agg *aggregator) {
+	byServer := agg.categoryStats()
+	var categories []string
+	for _, server := range byServer {
+		for category := range server {
+			if !slices.Contains(categories, category) {
+				categories = append(categories, category)
+			}
+		}
+	}
+	if len(categories) < 2 {
+		return
+	}
+	sort.Strings(categories)
+
+	fmt.Printf("\n%s[*] Latency per Domain Category (average RTT):%s\n\n", ColorBlue, ColorReset)
+	fmt.Printf("%s%-30s", ColorWhite, "Server")
+	separator := "───────────────────────────────"
+	for _, category := range categories {
+		fmt.Printf(" | %-11s", category)
+		separator += "┼─────────────"
+	}
+	fmt.Printf(" | %s%s\n", "Slowest", ColorReset)
+	fmt.Printf("%s%s┼──────────────%s\n", ColorYellow, separator, ColorReset)
+
+	for _, stats := range agg.serverStats() {
+		server := byServer[stats.ServerName+" - "+stats.ServerAddr]
+		var fastest, slowest *CategoryStats
+		for _, c := range server {
+			if c.SuccessQueries == 0 {
+				continue
+			}
+			if fastest == nil || c.AvgRTT < fastest.AvgRTT {
+				fastest = c
+			}
+			if slowest == nil || c.AvgRTT > slowest.AvgRTT {
+				slowest = c
+			}
+		}
+
+		fmt.Printf("%-30s", fmt.Sprintf("%s (%s)", stats.ServerName, stats.ServerAddr))
+		for _, category := range categories {
+			c := server[category]
+			if c == nil || c.SuccessQueries == 0 {
+				fmt.Printf(" | %s%11s%s", ColorRed, "-", ColorReset)
+				continue
+			}
+			color := ColorGreen
+			if float64(c.AvgRTT) >= 1.5*float64(fastest.AvgRTT) {
+				color = ColorYellow
+			}
+			fmt.Printf(" | %s%8.2f ms%s", color, ms(c.AvgRTT), ColorReset)
+		}
+		if fastest != nil && float64(slowest.AvgRTT) >= 1.5*float64(fastest.AvgRTT) {
+			fmt.Printf(" | %s%s %.1fx%s\n", ColorYellow, slowest.Category, float64(slowest.AvgRTT)/float64(fastest.AvgRTT), ColorReset)
+		} else {
+			fmt.Printf(" | %s\n", "-")
+		}
+	}
+	fmt.Printf("\n%s    Slowest names the category at least 1.5x slower than the server's fastest one%s\n", ColorCyan, ColorReset)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// checkpointInterval is how often benchmark progress is flushed to disk
+const checkpointInterval = 5 * time.Second
+
+// Checkpoint holds benchmark progress persisted between runs
+type Checkpoint struct {
+	Servers   []*DNSServer       `json:"servers"`
+	Domains   []string           `json:"domains"`
+	QTypes    []string           `json:"qtypes,omitempty"`
+	QueryNum  int                `json:"query_num"`
+	Transport string             `json:"transport"`
+	SavedAt   time.Time          `json:"saved_at"`
+	Results   []*BenchmarkResult `json:"results"`
+}
+
+func (j queryJob) key() string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%d", j.ServerName, j.Transport, j.ServerAddr, j.Domain, j.qtype(), j.Iteration)
+}
+
+// qtype returns the record type, A for jobs and results from before --types
+func (j queryJob) qtype() string {
+	return cmp.Or(j.QType, "A")
+}
+
+func (r *BenchmarkResult) jobKey() string {
+	return queryJob{
+		ServerName: r.ServerName,
+		ServerAddr: r.ServerAddr,
+		Transport:  r.Transport,
+		Domain:     r.Domain,
+		QType:      r.QType,
+		Iteration:  r.Iteration,
+	}.key()
+}
+
+// loadCheckpoint reads completed results from a checkpoint written for the same configuration
+func loadCheckpoint(path string, config *BenchmarkConfig) ([]*BenchmarkResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+
+	// Checkpoints from before --types only queried A
+	if len(cp.QTypes) == 0 {
+		cp.QTypes = []string{"A"}
+	}
+	if cp.QueryNum != config.QueryNum || cp.Transport != config.Transport || !slices.Equal(cp.Domains, config.Domains) || !slices.Equal(cp.QTypes, config.QTypes) || !sameServers(cp.Servers, config.Servers) {
+		return nil, fmt.Errorf("checkpoint %s was written for a different configuration", path)
+	}
+
+	return cp.Results, nil
+}
+
+// saveCheckpoint atomically writes the results collected so far
+func saveCheckpoint(path string, config *BenchmarkConfig) error {
+	mu.Lock()
+	cp := Checkpoint{
+		Servers:   config.Servers,
+		Domains:   config.Domains,
+		QTypes:    config.QTypes,
+		QueryNum:  config.QueryNum,
+		Transport: config.Transport,
+		SavedAt:   time.Now(),
+		Results:   slices.Clone(results),
+	}
+	mu.Unlock()
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func removeCheckpoint(path string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		slog.Warn("Failed to remove checkpoint", "err", err)
+	}
+}
+
+// startCheckpointer periodically saves progress and flushes it on interrupt.
+// The returned function stops checkpointing.
+func startCheckpointer(config *BenchmarkConfig) func() {
+	ticker := time.NewTicker(checkpointInterval)
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case <-ticker.C:
+				if err := saveCheckpoint(config.CheckpointPath, config); err != nil {
+					slog.Warn("Failed to save checkpoint", "err", err)
+				}
+			case <-sigChan:
+				if err := saveCheckpoint(config.CheckpointPath, config); err != nil {
+					fmt.Printf("\n")
+					slog.Error("Interrupted, failed to save checkpoint", "err", err)
+					os.Exit(130)
+				}
+				fmt.Printf("\n")
+				slog.Warn("Interrupted, progress saved (rerun with --resume)", "path", config.CheckpointPath)
+				os.Exit(130)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		signal.Stop(sigChan)
+		close(done)
+		<-exited
+	}
+}
+
+func sameServers(a, b []*DNSServer) bool {
+	return slices.EqualFunc(a, b, func(x, y *DNSServer) bool {
+		return *x == *y
+	})
+}
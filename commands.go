@@ -0,0 +1,448 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// command is a dnsbench subcommand with its own flags
+type command struct {
+	Name    string
+	Summary string
+	Run     func(args []string)
+}
+
+// commands lists the subcommands; "bench" also runs when the first argument
+// is a flag or missing, so existing invocations keep working
+var commands = []command{
+	{"bench", "benchmark DNS resolvers (default)", func(args []string) { runBench("bench", args) }},
+	{"monitor", "query resolvers periodically and report rolling and time-of-day stats", func(args []string) { runBench("monitor", args) }},
+	{"serve", "monitor and expose the current state over an HTTP API", func(args []string) { runBench("serve", args) }},
+	{"query", "one-shot dig-like lookup: query NAME [TYPE] [@SERVER]", runQuery},
+	{"propagation", "ask every resolver for a record and compare answers and TTLs", runPropagation},
+	{"watch", "poll a record across resolvers and report (or webhook) every answer change", runWatch},
+	{"bulk", "resolve a file of hostnames at high throughput and report latency and errors", runBulk},
+	{"export", "convert saved results (bench --save) to CSV, Markdown or a template", runExport},
+	{"report", "re-render saved results as text, Markdown, HTML or CSV", runReport},
+	{"compare", "compare two saved runs per resolver", runCompare},
+	{"history", "list and show past runs kept in ~/.dnsbench/history, or prune them", runHistory},
+	{"merge", "combine saved runs (e.g. from several machines) into one results file", runMerge},
+	{"current", "show the OS resolvers, search domains and OS/browser DoH settings (--bench benchmarks them)", runCurrent},
+	{"discover", "scan a local subnet for DNS servers and offer to benchmark them", runDiscover},
+	{"install-service", "run monitor or serve as a systemd or Windows service (install-service -- [flags])", runInstallService},
+	{"uninstall-service", "stop and remove the service installed by install-service", runUninstallService},
+	{"init", "interactively choose providers, domains and output and write a settings file", runInit},
+	{"providers", "list the provider catalog or update it (providers update)", runProviders},
+}
+
+// runCommand dispatches to the subcommand named by the first argument
+func runCommand(args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		runBench("bench", args)
+		return
+	}
+	switch args[0] {
+	case "help":
+		printUsage(os.Stdout)
+		return
+	case "update-providers":
+		// Spelling from before the providers command existed
+		runUpdateProviders(args[1:])
+		return
+	}
+	for _, cmd := range commands {
+		if cmd.Name == args[0] {
+			cmd.Run(args[1:])
+			return
+		}
+	}
+	fmt.Printf("%s[!] Unknown command %q%s\n\n", ColorRed, args[0], ColorReset)
+	printUsage(os.Stdout)
+	os.Exit(2)
+}
+
+// printUsage lists the subcommands
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: dnsbench [command] [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-18s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Fprintf(w, "\nRun \"dnsbench <command> -h\" for the flags of a command.\n")
+}
+
+// runProviders implements "dnsbench providers [list|update]"
+func runProviders(args []string) {
+	if len(args) > 0 && args[0] == "update" {
+		runUpdateProviders(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "list" {
+		args = args[1:]
+	}
+
+	fs := flag.NewFlagSet("providers", flag.ExitOnError)
+	category := fs.String("category", "", "comma-separated categories to list: unfiltered, security, family")
+	fs.Parse(args)
+
+	catalog, err := loadInstalledCatalog()
+	if err != nil {
+		fmt.Printf("%s[!] Ignoring installed provider catalog: %v%s\n", ColorYellow, err, ColorReset)
+	} else if catalog != nil {
+		useCatalog(catalog)
+		fmt.Printf("Catalog version %d, updated %s\n\n", catalog.Version, catalog.Updated.Format("2006-01-02"))
+	}
+
+	providers := builtinProviders
+	if *category != "" {
+		providers, err = filterCategories(providers, splitList(*category))
+		if err != nil {
+			fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+			os.Exit(2)
+		}
+	}
+
+	fmt.Printf("%s  %-24s | %-24s | %-10s | %-18s | %-3s | %-3s%s\n", ColorWhite, "ID", "Name", "Category", "Primary", "DoT", "DoH", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "──────────────────────────┼──────────────────────────┼────────────┼────────────────────┼─────┼────", ColorReset)
+	for _, p := range providers {
+		marker := " "
+		if slices.Contains(defaultProviders, p.ID) {
+			marker = "*"
+		}
+		primary := p.Primary
+		if primary == "" {
+			primary = "-"
+		}
+		fmt.Printf("%s %-24s | %-24s | %-10s | %-18s | %-3s | %-3s\n",
+			marker, p.ID, p.Name, p.Category, primary, yesNo(p.DoT != ""), yesNo(p.DoH != ""))
+	}
+	fmt.Printf("\n* benchmarked by default\n")
+
+	if len(regionPresets) > 0 {
+		var regions []string
+		for region, ids := range regionPresets {
+			regions = append(regions, fmt.Sprintf("  %-4s %s", region, strings.Join(ids, ", ")))
+		}
+		slices.Sort(regions)
+		fmt.Printf("\nRegions (--region):\n%s\n", strings.Join(regions, "\n"))
+	}
+}
+
+func yesNo(ok bool) string {
+	if ok {
+		return "yes"
+	}
+	return "no"
+}
+
+// runExport implements "dnsbench export": it renders saved results without
+// re-running any queries
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	from := fs.String("from", "", "results file written by bench --save (required)")
+	format := fs.String("format", "csv", "output format: csv (one row per query) or markdown")
+	formatTemplate := fs.String("format-template", "", "render through a Go text/template file instead of --format")
+	out := fs.String("o", "", "output file (default stdout)")
+	label := fs.String("label", "", "only export runs saved with this --label")
+	timezone := fs.String("timezone", "local", "time zone of exported timestamps: local, utc or a name such as Europe/Berlin")
+	fs.Parse(args)
+	if err := configureTime(*timezone, TimeClock); err != nil {
+		fmt.Fprintf(os.Stderr, "%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(2)
+	}
+
+	if *from == "" {
+		fmt.Fprintf(os.Stderr, "%s[!] export requires --from results.json%s\n", ColorRed, ColorReset)
+		os.Exit(2)
+	}
+	file, err := loadResults(*from)
+	if err == nil {
+		file, err = file.withLabel(*from, *label)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+
+	if *formatTemplate != "" {
+		tmpl, err := parseFormatTemplate(*formatTemplate)
+		if err == nil {
+			err = renderFormatTemplate(tmpl, file.report(), *out)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s[!] --format-template: %v%s\n", ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s[!] %v%s\n", ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *format {
+	case "csv":
+		err = writeResultsCSV(w, file.Results)
+	case OutputMarkdown:
+		err = writeMarkdown(w, file.report())
+	default:
+		fmt.Fprintf(os.Stderr, "%s[!] Unknown export format %q (want csv or markdown)%s\n", ColorRed, *format, ColorReset)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+}
+
+// runReport implements "dnsbench report --from results.json --output html":
+// it renders a saved run in any summary format without re-running queries
+func runReport(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	from := fs.String("from", "", "results file written by bench or monitor --save (required)")
+	output := fs.String("output", OutputText, "format: text, markdown, html or csv (one row per query)")
+	out := fs.String("o", "", "output file (default stdout)")
+	share := fs.String("share", "", "upload the report as HTML and print a link: paste (public paste service) or an http(s) endpoint")
+	label := fs.String("label", "", "only report runs saved with this --label")
+	timezone := fs.String("timezone", "local", "time zone of report timestamps and the monitor heatmap hours: local, utc or a name such as Europe/Berlin")
+	fs.Parse(args)
+	if err := configureTime(*timezone, TimeClock); err != nil {
+		fmt.Fprintf(os.Stderr, "%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(2)
+	}
+	if err := validShareTarget(*share); err != nil {
+		fmt.Fprintf(os.Stderr, "%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(2)
+	}
+
+	if *from == "" {
+		fmt.Fprintf(os.Stderr, "%s[!] report requires --from results.json%s\n", ColorRed, ColorReset)
+		os.Exit(2)
+	}
+	switch *output {
+	case OutputMarkdown, OutputHTML, "csv":
+	case OutputText:
+		if *out != "" {
+			fmt.Fprintf(os.Stderr, "%s[!] -o is not supported for text output%s\n", ColorRed, ColorReset)
+			os.Exit(2)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "%s[!] Unknown output format %q (want text, markdown, html or csv)%s\n", ColorRed, *output, ColorReset)
+		os.Exit(2)
+	}
+	file, err := loadResults(*from)
+	if err == nil {
+		file, err = file.withLabel(*from, *label)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s[!] %v%s\n", ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *output {
+	case OutputText:
+		if len(file.Labels) > 0 {
+			fmt.Printf("    Labels: %s\n", strings.Join(file.Labels, ", "))
+		}
+		if env := file.environment(); env != nil {
+			fmt.Printf("    Environment: %s\n", env)
+		}
+		for _, source := range file.Sources {
+			fmt.Printf("    %-20s %s, saved %s", source.Label, strings.ToUpper(source.Transport), source.SavedAt.Format("2006-01-02 15:04"))
+			if len(source.Labels) > 0 {
+				fmt.Printf(" [%s]", strings.Join(source.Labels, ", "))
+			}
+			if source.Metadata != nil && source.Metadata.Environment != nil {
+				fmt.Printf(", %s", source.Metadata.Environment)
+			}
+			fmt.Printf("\n")
+		}
+		printResults(file.Results, aggregate(file.Results), false)
+	case OutputMarkdown:
+		err = writeMarkdown(w, file.report())
+	case OutputHTML:
+		err = writeHTML(w, file.report())
+	case "csv":
+		err = writeResultsCSV(w, file.Results)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	if *share != "" {
+		printShare(*share, OutputHTML, file.report())
+	}
+}
+
+// writeResultsCSV writes one row per query
+func writeResultsCSV(w io.Writer, results []*BenchmarkResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"timestamp", "server_name", "server_addr", "transport", "domain", "qtype", "iteration", "rtt_ms", "status", "rcode", "error", "attempts", "time_to_answer_ms"})
+	for _, r := range results {
+		cw.Write([]string{
+			machineTime(r.Timestamp),
+			r.ServerName, r.ServerAddr, r.Transport, r.Domain, r.qtype(),
+			strconv.Itoa(r.Iteration),
+			strconv.FormatFloat(ms(r.RTT), 'f', 3, 64),
+			r.Status, r.Rcode, r.Error,
+			strconv.Itoa(r.Attempts),
+			strconv.FormatFloat(ms(r.TimeToAnswer), 'f', 3, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// runCompare implements "dnsbench compare before.json after.json"
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: dnsbench compare before.json after.json\n       dnsbench compare --before-label vpn-off --after-label vpn-on merged.json\n")
+		fs.PrintDefaults()
+	}
+	beforeLabel := fs.String("before-label", "", "only compare runs of the first file saved with this --label, e.g. vpn-off")
+	afterLabel := fs.String("after-label", "", "only compare runs of the second file saved with this --label, e.g. vpn-on (both may be one merged file)")
+	paired := fs.Bool("paired", false, "also compare query by query (same server, domain, type and iteration) with a paired t-test")
+	args = parseInterspersed(fs, args)
+	if len(args) == 1 && *beforeLabel != "" && *afterLabel != "" {
+		// Both labelled runs in one merged file
+		args = append(args, args[0])
+	}
+	if len(args) != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var runs [2]*ResultsFile
+	labels := [2]string{*beforeLabel, *afterLabel}
+	for i, path := range args {
+		file, err := loadResults(path)
+		if err == nil {
+			file, err = file.withLabel(path, labels[i])
+		}
+		if err != nil {
+			fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+			os.Exit(1)
+		}
+		runs[i] = file
+	}
+	names := args
+	for i, label := range labels {
+		if label != "" {
+			names[i] += " [" + label + "]"
+		}
+	}
+	printComparison(names[0], runs[0], names[1], runs[1])
+	if *paired {
+		printPairedComparison(runs[0], runs[1])
+	}
+}
+
+// printComparison shows per-endpoint average RTT and success rate of two
+// runs side by side; endpoints present in only one run show "-"
+func printComparison(nameA string, a *ResultsFile, nameB string, b *ResultsFile) {
+	fmt.Printf("%s[*] Comparing %s (%s) with %s (%s)%s\n\n", ColorBlue,
+		nameA, a.SavedAt.Format("2006-01-02 15:04"), nameB, b.SavedAt.Format("2006-01-02 15:04"), ColorReset)
+	for i, env := range []*RunEnvironment{a.environment(), b.environment()} {
+		if env == nil {
+			continue
+		}
+		color := ""
+		if env.wireless() {
+			color = ColorYellow
+		}
+		fmt.Printf("    %-7s %s%s%s\n", []string{"Before:", "After:"}[i], color, env, ColorReset)
+	}
+	if envA, envB := a.environment(), b.environment(); envA != nil && envB != nil && envA.InterfaceType != envB.InterfaceType {
+		fmt.Printf("%s    The runs used different links (%s vs %s); differences may come from the link, not the resolvers%s\n", ColorYellow, envA.InterfaceType, envB.InterfaceType, ColorReset)
+	}
+	fmt.Printf("\n")
+
+	before := make(map[string]*ServerStats)
+	for _, stats := range summarizeServers(a.Results) {
+		before[stats.ServerName+" ("+stats.ServerAddr+")"] = stats
+	}
+	var keys []string
+	after := make(map[string]*ServerStats)
+	for _, stats := range summarizeServers(b.Results) {
+		key := stats.ServerName + " (" + stats.ServerAddr + ")"
+		after[key] = stats
+		keys = append(keys, key)
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	// By name, so two comparisons of the same files line up row for row
+	slices.Sort(keys)
+
+	fmt.Printf("%s%-30s | %-12s | %-12s | %-9s | %-8s | %-8s%s\n",
+		ColorWhite, "Server", "Avg Before", "Avg After", "Change", "Success", "Success", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼──────────────┼──────────────┼───────────┼──────────┼─────────", ColorReset)
+
+	for _, key := range keys {
+		old, cur := before[key], after[key]
+		fmt.Printf("%-30s | %s | %s | %s | %s | %s\n", key,
+			compareRTT(old), compareRTT(cur), compareChange(old, cur), compareRate(old), compareRate(cur))
+	}
+	fmt.Printf("\n%s    * marks changes significant at 95%% confidence (Welch's t-test); changes in white are within noise%s\n", ColorCyan, ColorReset)
+}
+
+func compareRTT(stats *ServerStats) string {
+	if stats == nil || stats.SuccessQueries == 0 {
+		return fmt.Sprintf("%12s", "-")
+	}
+	return fmt.Sprintf("%9.2f ms", ms(stats.AvgRTT))
+}
+
+func compareRate(stats *ServerStats) string {
+	if stats == nil {
+		return fmt.Sprintf("%8s", "-")
+	}
+	return fmt.Sprintf("%7.1f%%", stats.SuccessRate())
+}
+
+// compareChange colors the relative RTT change: faster green, >10% slower red,
+// and white when Welch's t-test cannot tell the runs apart
+func compareChange(old *ServerStats, cur *ServerStats) string {
+	if old == nil || cur == nil || old.SuccessQueries == 0 || cur.SuccessQueries == 0 {
+		return fmt.Sprintf("%9s", "-")
+	}
+	change := (float64(cur.AvgRTT) - float64(old.AvgRTT)) / float64(old.AvgRTT) * 100
+	color := ColorGreen
+	if change > 10 {
+		color = ColorRed
+	} else if change > 0 {
+		color = ColorYellow
+	}
+	marker := "*"
+	if !significantlyDifferent(old.rtts, cur.rtts) {
+		// Within noise at 95% confidence
+		color, marker = ColorWhite, " "
+	}
+	return fmt.Sprintf("%s%+7.1f%%%s%s", color, change, marker, ColorReset)
+}
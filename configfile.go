@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultConfigPath is read by bench, monitor and serve when it exists in
+// the working directory; dnsbench init writes it
+const defaultConfigPath = "dnsbench.conf"
+
+// configFile is the settings file in use, empty when there is none
+var configFile string
+
+// configFileArgs reads a settings file of "flag = value" lines (the flag
+// names of the command line, without dashes) and returns them as arguments.
+// Repeatable flags such as server take one line per value. Settings of
+// other commands (defined on other) are skipped, so one file can serve
+// bench, monitor and serve.
+func configFileArgs(path string, fs *flag.FlagSet, other *flag.FlagSet) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var args []string
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, found := strings.Cut(line, "=")
+		name, value = strings.TrimSpace(name), strings.Trim(strings.TrimSpace(value), `"`)
+		if !found || name == "" {
+			return nil, fmt.Errorf("%s:%d: want name = value", path, lineNum)
+		}
+		switch {
+		case name == "config":
+			return nil, fmt.Errorf("%s:%d: config cannot be set in a settings file", path, lineNum)
+		case fs.Lookup(name) != nil:
+			args = append(args, "-"+name+"="+value)
+		case other.Lookup(name) == nil:
+			return nil, fmt.Errorf("%s:%d: unknown setting %q", path, lineNum, name)
+		}
+	}
+	return args, scanner.Err()
+}
+
+// configPathArg finds --config in the arguments before they are parsed,
+// so the file's settings can be placed in front of them
+func configPathArg(args []string) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value, true
+		}
+		if i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// withConfigFile prepends the settings file to args, so flags given on the
+// command line override it. An explicit --config must exist; the default
+// file is optional.
+func withConfigFile(args []string, fs *flag.FlagSet, other *flag.FlagSet) ([]string, error) {
+	path, explicit := configPathArg(args)
+	if !explicit {
+		if _, err := os.Stat(defaultConfigPath); err != nil {
+			return args, nil
+		}
+		path = defaultConfigPath
+	}
+	if path == "" {
+		return args, nil
+	}
+	fileArgs, err := configFileArgs(path, fs, other)
+	if err != nil {
+		return nil, err
+	}
+	configFile = path
+	return append(fileArgs, args...), nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// maxIdleConns caps the idle connections kept per resolver address
+const maxIdleConns = 64
+
+var (
+	// reuseConns keeps UDP sockets and TCP/DoT connections open between
+	// queries to the same resolver instead of opening one per query
+	reuseConns bool
+
+	connPools   = make(map[string]*connPool)
+	connPoolsMu sync.Mutex
+
+	// socketsOpened counts UDP sockets and TCP connections opened for
+	// queries, showing how many ports (and NAT entries) a run used
+	socketsOpened atomic.Int64
+)
+
+// connPool holds idle connections to one resolver address
+type connPool struct {
+	mu   sync.Mutex
+	idle []*dns.Conn
+}
+
+func poolFor(transport string, addr string) *connPool {
+	connPoolsMu.Lock()
+	defer connPoolsMu.Unlock()
+	key := transport + "|" + addr
+	pool, ok := connPools[key]
+	if !ok {
+		pool = &connPool{}
+		connPools[key] = pool
+	}
+	return pool
+}
+
+func (p *connPool) get() *dns.Conn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.idle) == 0 {
+		return nil
+	}
+	co := p.idle[len(p.idle)-1]
+	p.idle = p.idle[:len(p.idle)-1]
+	return co
+}
+
+func (p *connPool) put(co *dns.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.idle) >= maxIdleConns {
+		co.Close()
+		return
+	}
+	p.idle = append(p.idle, co)
+}
+
+// exchangePooled sends a query over an idle connection to addr, opening one
+// when none is available. A reused TCP or DoT connection the server closed
+// in the meantime is replaced once; connections that time out are dropped so
+// a late reply cannot be read as the answer to the next query.
+func exchangePooled(ctx context.Context, m *dns.Msg, transport string, addr string) (*dns.Msg, session, error) {
+	pool := poolFor(transport, addr)
+	co := pool.get()
+	reused := co != nil
+	if !reused {
+		var err error
+		if co, err = dialPooled(ctx, transport, addr); err != nil {
+			return nil, session{}, err
+		}
+	}
+
+	r, err := exchangeConn(ctx, co, m)
+	if err != nil && reused && transport != TransportUDP && !isTimeout(err) {
+		co.Close()
+		if co, err = dialPooled(ctx, transport, addr); err != nil {
+			return nil, session{}, err
+		}
+		r, err = exchangeConn(ctx, co, m)
+	}
+	s := connSession(co.Conn)
+	if err != nil {
+		co.Close()
+		return nil, s, err
+	}
+	pool.put(co)
+	return r, s, nil
+}
+
+// dialPooled opens a connection for the pool
+func dialPooled(ctx context.Context, transport string, addr string) (*dns.Conn, error) {
+	if transport == TransportUDP {
+		conn, err := newDialer("udp", addr).DialContext(ctx, "udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		socketsOpened.Add(1)
+		return &dns.Conn{Conn: conn}, nil
+	}
+
+	var nextProtos []string
+	if transport == TransportDoT {
+		nextProtos = []string{"dot"}
+	}
+	conn, err := connect(ctx, addr, nextProtos)
+	if err != nil {
+		return nil, err
+	}
+	return &dns.Conn{Conn: conn}, nil
+}
+
+// exchangeConn writes the query and reads until the response with the
+// matching ID arrives
+func exchangeConn(ctx context.Context, co *dns.Conn, m *dns.Msg) (*dns.Msg, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(3 * time.Second)
+	}
+	co.SetDeadline(deadline)
+	co.UDPSize = dns.MinMsgSize
+	if opt := m.IsEdns0(); opt != nil {
+		co.UDPSize = max(opt.UDPSize(), dns.MinMsgSize)
+	}
+
+	if err := co.WriteMsg(m); err != nil {
+		return nil, err
+	}
+	co.SetReadDeadline(readDeadline(deadline))
+	for {
+		r, err := co.ReadMsg()
+		if err != nil {
+			return nil, err
+		}
+		if r.Id == m.Id {
+			return r, nil
+		}
+		if _, ok := co.Conn.(net.PacketConn); !ok {
+			return nil, errors.New("response ID mismatch")
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// centerText pads text with spaces to center it within width columns
+func centerText(text string, width int) string {
+	n := utf8.RuneCountInString(text)
+	if n >= width {
+		return text
+	}
+	left := (width - n) / 2
+	return strings.Repeat(" ", left) + text + strings.Repeat(" ", width-n-left)
+}
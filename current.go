@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// systemCommandTimeout bounds the OS tools the settings are read with
+const systemCommandTimeout = 5 * time.Second
+
+// SystemDNS is the resolver configuration of the OS and its browsers
+type SystemDNS struct {
+	Source    string   // where the resolvers were read from
+	Stub      string   // local stub the OS sends queries to, if any
+	Resolvers []string // host:port of the configured (upstream) resolvers
+	Search    []string
+	Encrypted []EncryptedDNS
+}
+
+// EncryptedDNS is one DoH or DoT setting of the OS or a browser
+type EncryptedDNS struct {
+	Scope    string // e.g. systemd-resolved, Windows, Chrome, Firefox (profile)
+	Protocol string // DoH or DoT
+	Mode     string
+	Server   string // DoH template or server, empty for the default
+}
+
+// runCurrent implements "dnsbench current": it prints the resolvers, search
+// domains and DoH settings in use and, with --bench, benchmarks the
+// resolvers against the built-in providers
+func runCurrent(args []string) {
+	fs := flag.NewFlagSet("current", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: dnsbench current [--bench [bench flags]]\n")
+		fs.PrintDefaults()
+	}
+	bench := fs.Bool("bench", false, "then benchmark the configured resolvers against the built-in providers; the arguments after it are bench flags")
+	var benchArgs []string
+	for i, arg := range args {
+		if arg == "--bench" || arg == "-bench" {
+			args, benchArgs = args[:i+1], args[i+1:]
+			break
+		}
+	}
+	fs.Parse(args)
+
+	system := readSystemDNS()
+	system.Encrypted = append(system.Encrypted, browserDoH()...)
+	printSystemDNS(system)
+
+	if *bench {
+		fmt.Printf("\n")
+		runBench("bench", append([]string{"--include-system"}, benchArgs...))
+	}
+}
+
+// printSystemDNS lists the settings read by readSystemDNS and browserDoH
+func printSystemDNS(system *SystemDNS) {
+	fmt.Printf("%s[*] System DNS settings (%s):%s\n", ColorBlue, system.Source, ColorReset)
+	if system.Stub != "" {
+		fmt.Printf("    Stub resolver: %s\n", system.Stub)
+	}
+	if len(system.Resolvers) == 0 {
+		fmt.Printf("    Resolvers: %snone found%s\n", ColorYellow, ColorReset)
+	} else {
+		fmt.Printf("    Resolvers: %s\n", strings.Join(system.Resolvers, ", "))
+	}
+	if len(system.Search) > 0 {
+		fmt.Printf("    Search domains: %s\n", strings.Join(system.Search, ", "))
+	}
+
+	fmt.Printf("\n%s[*] Encrypted DNS (OS and browsers):%s\n", ColorBlue, ColorReset)
+	if len(system.Encrypted) == 0 {
+		fmt.Printf("    None configured; queries leave this machine as plain DNS\n")
+		return
+	}
+	for _, setting := range system.Encrypted {
+		color := ColorGreen
+		if setting.Mode == "off" || setting.Mode == "default" {
+			color = ColorYellow
+		}
+		fmt.Printf("    %-32s %-4s %s%s%s", setting.Scope, setting.Protocol, color, setting.Mode, ColorReset)
+		if setting.Server != "" {
+			fmt.Printf(" (%s)", setting.Server)
+		}
+		fmt.Printf("\n")
+	}
+	fmt.Printf("\n%s    Browsers with DoH on bypass the resolvers above for web traffic%s\n", ColorCyan, ColorReset)
+}
+
+// systemResolvers returns the configured resolvers as servers for
+// --include-system, paired into primary and secondary like the providers
+func systemResolvers() []*DNSServer {
+	var servers []*DNSServer
+	addrs := readSystemDNS().Resolvers
+	for i := 0; i < len(addrs); i += 2 {
+		server := &DNSServer{ID: "system", Name: "System", Primary: addrs[i]}
+		if i > 0 {
+			server.Name = fmt.Sprintf("System %d", i/2+1)
+		}
+		if i+1 < len(addrs) {
+			server.Secondary = addrs[i+1]
+		}
+		servers = append(servers, server)
+	}
+	return servers
+}
+
+// systemCommand runs an OS tool and returns its output, "" when it is
+// missing or fails
+func systemCommand(name string, args ...string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), systemCommandTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// resolverAddr adds port 53 to a resolver IP; it returns "" for anything
+// else, including link-local addresses with a zone
+func resolverAddr(ip string) string {
+	if parsed := net.ParseIP(ip); parsed == nil {
+		return ""
+	}
+	return net.JoinHostPort(ip, "53")
+}
+
+// appendResolver adds a resolver address unless it is invalid or present
+func appendResolver(resolvers []string, ip string) []string {
+	addr := resolverAddr(ip)
+	if addr == "" {
+		return resolvers
+	}
+	for _, existing := range resolvers {
+		if existing == addr {
+			return resolvers
+		}
+	}
+	return append(resolvers, addr)
+}
+
+// browserDoH reads the DoH settings of Chromium-based browsers (Local
+// State and, on Linux, managed policies) and of Firefox profiles
+func browserDoH() []EncryptedDNS {
+	var settings []EncryptedDNS
+	for _, browser := range chromiumDirs() {
+		if setting, ok := chromiumDoH(browser.name, browser.dir); ok {
+			settings = append(settings, setting)
+		}
+	}
+	for _, dir := range firefoxDirs() {
+		prefs, _ := filepath.Glob(filepath.Join(dir, "*", "prefs.js"))
+		for _, path := range prefs {
+			profile := filepath.Base(filepath.Dir(path))
+			settings = append(settings, firefoxDoH("Firefox ("+profile+")", path))
+		}
+	}
+	return settings
+}
+
+// browserDir is a browser's user data directory
+type browserDir struct {
+	name string
+	dir  string
+}
+
+// chromiumDirs returns the user data directories of Chrome, Chromium, Edge
+// and Brave for this OS
+func chromiumDirs() []browserDir {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		local := os.Getenv("LOCALAPPDATA")
+		return []browserDir{
+			{"Chrome", filepath.Join(local, "Google", "Chrome", "User Data")},
+			{"Chromium", filepath.Join(local, "Chromium", "User Data")},
+			{"Edge", filepath.Join(local, "Microsoft", "Edge", "User Data")},
+			{"Brave", filepath.Join(local, "BraveSoftware", "Brave-Browser", "User Data")},
+		}
+	case "darwin":
+		support := filepath.Join(home, "Library", "Application Support")
+		return []browserDir{
+			{"Chrome", filepath.Join(support, "Google", "Chrome")},
+			{"Chromium", filepath.Join(support, "Chromium")},
+			{"Edge", filepath.Join(support, "Microsoft Edge")},
+			{"Brave", filepath.Join(support, "BraveSoftware", "Brave-Browser")},
+		}
+	}
+	config := filepath.Join(home, ".config")
+	return []browserDir{
+		{"Chrome", filepath.Join(config, "google-chrome")},
+		{"Chromium", filepath.Join(config, "chromium")},
+		{"Edge", filepath.Join(config, "microsoft-edge")},
+		{"Brave", filepath.Join(config, "BraveSoftware", "Brave-Browser")},
+	}
+}
+
+// chromiumPolicyDirs holds the managed policies of Chrome and Chromium on
+// Linux, which override the user's setting
+var chromiumPolicyDirs = map[string]string{
+	"Chrome":   "/etc/opt/chrome/policies/managed",
+	"Chromium": "/etc/chromium/policies/managed",
+}
+
+// chromiumDoH reads the secure DNS setting of a Chromium-based browser;
+// without an explicit setting the browser upgrades to DoH automatically
+// when the system resolver offers it
+func chromiumDoH(name string, dir string) (EncryptedDNS, bool) {
+	var state struct {
+		DoH *struct {
+			Mode      string `json:"mode"`
+			Templates string `json:"templates"`
+		} `json:"dns_over_https"`
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "Local State"))
+	if err != nil {
+		return EncryptedDNS{}, false
+	}
+	setting := EncryptedDNS{Scope: name, Protocol: "DoH", Mode: "automatic (default)"}
+	if json.Unmarshal(data, &state) == nil && state.DoH != nil && state.DoH.Mode != "" {
+		setting.Mode = state.DoH.Mode
+		setting.Server = state.DoH.Templates
+	}
+
+	if runtime.GOOS == "linux" && chromiumPolicyDirs[name] != "" {
+		policies, _ := filepath.Glob(filepath.Join(chromiumPolicyDirs[name], "*.json"))
+		for _, path := range policies {
+			var policy struct {
+				Mode      string `json:"DnsOverHttpsMode"`
+				Templates string `json:"DnsOverHttpsTemplates"`
+			}
+			if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &policy) == nil && policy.Mode != "" {
+				setting.Mode = policy.Mode + " (policy)"
+				setting.Server = policy.Templates
+			}
+		}
+	}
+	return setting, true
+}
+
+// firefoxDirs returns the directories holding Firefox profiles
+func firefoxDirs() []string {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		return []string{filepath.Join(os.Getenv("APPDATA"), "Mozilla", "Firefox", "Profiles")}
+	case "darwin":
+		return []string{filepath.Join(home, "Library", "Application Support", "Firefox", "Profiles")}
+	}
+	return []string{
+		filepath.Join(home, ".mozilla", "firefox"),
+		filepath.Join(home, "snap", "firefox", "common", ".mozilla", "firefox"),
+	}
+}
+
+// firefoxTRRModes names the values of network.trr.mode
+var firefoxTRRModes = map[string]string{
+	"0": "default",
+	"1": "default",
+	"2": "DoH first, plain DNS fallback",
+	"3": "DoH only",
+	"5": "off",
+}
+
+// firefoxDoH reads network.trr.mode and network.trr.uri from a profile's
+// prefs.js
+func firefoxDoH(scope string, path string) EncryptedDNS {
+	setting := EncryptedDNS{Scope: scope, Protocol: "DoH", Mode: "default"}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return setting
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(line), "user_pref(")
+		if !ok {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimSuffix(rest, ");"), ",")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		switch strings.Trim(key, `"`) {
+		case "network.trr.mode":
+			if mode, ok := firefoxTRRModes[value]; ok {
+				setting.Mode = mode
+			}
+		case "network.trr.uri":
+			setting.Server = value
+		}
+	}
+	return setting
+}
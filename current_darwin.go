@@ -0,0 +1,52 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// readSystemDNS reads the default resolvers and search domains from
+// scutil --dns; resolvers scoped to a domain (e.g. local) are skipped.
+// Encrypted DNS set by configuration profiles is not visible there.
+func readSystemDNS() *SystemDNS {
+	system := &SystemDNS{Source: "scutil --dns"}
+	out := systemCommand("scutil", "--dns")
+
+	var servers, search []string
+	scoped := false
+	flush := func() {
+		if !scoped {
+			for _, server := range servers {
+				system.Resolvers = appendResolver(system.Resolvers, server)
+			}
+			for _, domain := range search {
+				if !slices.Contains(system.Search, domain) {
+					system.Search = append(system.Search, domain)
+				}
+			}
+		}
+		servers, search, scoped = nil, nil, false
+	}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "resolver #") || strings.HasPrefix(line, "DNS configuration") {
+			flush()
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(key, "nameserver["):
+			servers = append(servers, value)
+		case strings.HasPrefix(key, "search domain["):
+			search = append(search, value)
+		case key == "domain":
+			scoped = true
+		}
+	}
+	flush()
+	return system
+}
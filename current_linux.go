@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// readSystemDNS reads resolv.conf; when it points at the systemd-resolved
+// stub, the upstream resolvers and DNS over TLS mode come from resolvectl
+func readSystemDNS() *SystemDNS {
+	system := &SystemDNS{Source: resolvConfPath}
+	conf, err := dns.ClientConfigFromFile(resolvConfPath)
+	if err != nil {
+		return system
+	}
+	system.Search = conf.Search
+	for _, server := range conf.Servers {
+		if server == "127.0.0.53" {
+			system.Stub = net.JoinHostPort(server, "53")
+			continue
+		}
+		system.Resolvers = appendResolver(system.Resolvers, server)
+	}
+	if system.Stub == "" {
+		return system
+	}
+
+	// "Global: 1.1.1.1" and "Link 2 (eth0): 192.168.1.1 fe80::1%eth0"
+	out := systemCommand("resolvectl", "dns")
+	if out == "" {
+		return system
+	}
+	system.Source = "systemd-resolved"
+	for _, line := range strings.Split(out, "\n") {
+		_, servers, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		for _, server := range strings.Fields(servers) {
+			// DNS over TLS servers may carry "#hostname" and a port
+			server, _, _ = strings.Cut(server, "#")
+			if host, _, err := net.SplitHostPort(server); err == nil {
+				server = host
+			}
+			system.Resolvers = appendResolver(system.Resolvers, server)
+		}
+	}
+
+	// "DNSOverTLS setting: opportunistic" or "Protocols: ... +DNSOverTLS"
+	mode := "off"
+	for _, line := range strings.Split(systemCommand("resolvectl", "status"), "\n") {
+		line = strings.TrimSpace(line)
+		if value, ok := strings.CutPrefix(line, "DNSOverTLS setting:"); ok {
+			mode = strings.TrimSpace(value)
+		} else if strings.HasPrefix(line, "Protocols:") && strings.Contains(line, "+DNSOverTLS") && mode == "off" {
+			mode = "yes"
+		}
+	}
+	system.Encrypted = append(system.Encrypted, EncryptedDNS{Scope: "systemd-resolved", Protocol: "DoT", Mode: mode})
+	return system
+}
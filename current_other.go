@@ -0,0 +1,20 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import "github.com/miekg/dns"
+
+// readSystemDNS reads resolv.conf, which the BSDs and other Unix systems
+// use directly
+func readSystemDNS() *SystemDNS {
+	system := &SystemDNS{Source: resolvConfPath}
+	conf, err := dns.ClientConfigFromFile(resolvConfPath)
+	if err != nil {
+		return system
+	}
+	system.Search = conf.Search
+	for _, server := range conf.Servers {
+		system.Resolvers = appendResolver(system.Resolvers, server)
+	}
+	return system
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net"
+	"slices"
+	"strings"
+)
+
+// currentScript prints the interface resolvers, the suffix search list and
+// the DoH servers Windows knows (address|template|auto-upgrade), one
+// section per marker line
+const currentScript = `"#servers"
+Get-DnsClientServerAddress | ForEach-Object { $_.ServerAddresses }
+"#search"
+(Get-DnsClientGlobalSetting).SuffixSearchList
+"#doh"
+Get-DnsClientDohServerAddress | ForEach-Object { $_.ServerAddress + '|' + $_.DohTemplate + '|' + $_.AutoUpgrade }`
+
+// readSystemDNS asks PowerShell for the resolvers of every interface, the
+// search suffixes and which of the resolvers Windows upgrades to DoH
+func readSystemDNS() *SystemDNS {
+	system := &SystemDNS{Source: "Get-DnsClientServerAddress"}
+	out := systemCommand("powershell", "-NoProfile", "-NonInteractive", "-Command", currentScript)
+
+	var section string
+	type dohServer struct{ template, autoUpgrade string }
+	doh := make(map[string]dohServer)
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			section = line
+			continue
+		}
+		switch section {
+		case "#servers":
+			system.Resolvers = appendResolver(system.Resolvers, line)
+		case "#search":
+			if !slices.Contains(system.Search, line) {
+				system.Search = append(system.Search, line)
+			}
+		case "#doh":
+			fields := strings.Split(line, "|")
+			if len(fields) == 3 {
+				doh[net.JoinHostPort(fields[0], "53")] = dohServer{fields[1], fields[2]}
+			}
+		}
+	}
+
+	// Windows 11 encrypts queries to resolvers with a DoH template when
+	// auto-upgrade is on for them
+	for _, addr := range system.Resolvers {
+		server, ok := doh[addr]
+		if !ok {
+			continue
+		}
+		mode := "template known, not used"
+		if strings.EqualFold(server.autoUpgrade, "True") {
+			mode = "automatic upgrade"
+		}
+		system.Encrypted = append(system.Encrypted, EncryptedDNS{Scope: "Windows (" + addr + ")", Protocol: "DoH", Mode: mode, Server: server.template})
+	}
+	return system
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// diagnoseFailures runs the failure diagnostics after a live benchmark
+// (--diagnose)
+var diagnoseFailures = true
+
+// diagnoseMax bounds how many failing domains are diagnosed, since each
+// costs a few extra queries
+const diagnoseMax = 10
+
+// failingDomain is a domain and type that failed on some resolvers while
+// others answered it
+type failingDomain struct {
+	Domain  string
+	QType   string
+	Working int
+	Failing []*failingEndpoint
+}
+
+// failingEndpoint is a resolver address that never answered the domain,
+// with its most frequent failure
+type failingEndpoint struct {
+	ServerName string
+	ServerAddr string
+	Transport  string
+	Failure    string // rcode, TIMEOUT, NO_RECORDS or another status
+	Error      string // of the last failed query
+}
+
+// findFailingDomains lists the domains answered by at least one resolver
+// address and failed by every query to at least one other
+func findFailingDomains(results []*BenchmarkResult) []*failingDomain {
+	type domainKey struct{ domain, qtype string }
+	type endpointKey struct{ name, addr string }
+	type endpointState struct {
+		transport string
+		answered  bool
+		failures  map[string]int
+		lastError string
+	}
+	byDomain := make(map[domainKey]map[endpointKey]*endpointState)
+	for _, result := range results {
+		key := domainKey{result.Domain, result.qtype()}
+		if byDomain[key] == nil {
+			byDomain[key] = make(map[endpointKey]*endpointState)
+		}
+		endpoint := endpointKey{result.ServerName, result.ServerAddr}
+		state := byDomain[key][endpoint]
+		if state == nil {
+			state = &endpointState{transport: result.Transport, failures: make(map[string]int)}
+			byDomain[key][endpoint] = state
+		}
+		if result.Status == "SUCCESS" {
+			state.answered = true
+			continue
+		}
+		state.lastError = result.Error
+		if result.Rcode != "" {
+			state.failures[result.Rcode]++
+		} else {
+			state.failures[result.Status]++
+		}
+	}
+
+	var failing []*failingDomain
+	for key, endpoints := range byDomain {
+		domain := failingDomain{Domain: key.domain, QType: key.qtype}
+		for endpoint, state := range endpoints {
+			if state.answered {
+				domain.Working++
+				continue
+			}
+			var failure string
+			for f, count := range state.failures {
+				if failure == "" || count > state.failures[failure] || (count == state.failures[failure] && f < failure) {
+					failure = f
+				}
+			}
+			domain.Failing = append(domain.Failing, &failingEndpoint{endpoint.name, endpoint.addr, state.transport, failure, state.lastError})
+		}
+		if domain.Working > 0 && len(domain.Failing) > 0 {
+			sort.Slice(domain.Failing, func(i, j int) bool {
+				return domain.Failing[i].ServerName+domain.Failing[i].ServerAddr < domain.Failing[j].ServerName+domain.Failing[j].ServerAddr
+			})
+			failing = append(failing, &domain)
+		}
+	}
+	sort.Slice(failing, func(i, j int) bool {
+		if len(failing[i].Failing) != len(failing[j].Failing) {
+			return len(failing[i].Failing) > len(failing[j].Failing)
+		}
+		return failing[i].Domain+failing[i].QType < failing[j].Domain+failing[j].QType
+	})
+	return failing
+}
+
+// diagnosticQuery sends one query for the diagnostics, optionally with the
+// Checking Disabled bit so a validating resolver skips DNSSEC validation
+func diagnosticQuery(transport string, addr string, name string, qtype uint16, checkingDisabled bool) (*dns.Msg, error) {
+	m := &dns.Msg{}
+	m.SetQuestion(queryName(name), qtype)
+	m.SetEdns0(dns.DefaultMsgSize, true)
+	m.CheckingDisabled = checkingDisabled
+
+	timeout := 5 * time.Second
+	if transport != TransportDoH {
+		timeout = timeoutFor(addr)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return exchange(ctx, m, transport, addr)
+}
+
+// answered reports whether a diagnostic query got records back
+func answered(r *dns.Msg, err error) bool {
+	return err == nil && r != nil && r.Rcode == dns.RcodeSuccess && len(r.Answer) > 0
+}
+
+// controlOutcome describes the control resolver's answer, e.g. "NOERROR,
+// 2 records, DNSSEC signed"
+func controlOutcome(r *dns.Msg, err error) string {
+	if err != nil {
+		if isTimeout(err) {
+			return "unreachable (no answer in time)"
+		}
+		return "unreachable (" + err.Error() + ")"
+	}
+	outcome := fmt.Sprintf("%s, %d records", dns.RcodeToString[r.Rcode], len(r.Answer))
+	if r.AuthenticatedData {
+		outcome += ", DNSSEC signed"
+	}
+	return outcome
+}
+
+// diagnoseHint explains why one resolver fails the domain from the control
+// resolver's view and a retry with checking disabled; control is nil when
+// the control resolver could not be reached
+func diagnoseHint(domain *failingDomain, endpoint *failingEndpoint, control *dns.Msg, controlCD bool, nsOK bool) string {
+	qtype := dns.StringToType[domain.QType]
+	controlOK := answered(control, nil)
+	switch endpoint.Failure {
+	case "SERVFAIL":
+		if control != nil && !controlOK && controlCD {
+			return "the domain's DNSSEC is broken; validating resolvers fail it and answer only with checking disabled"
+		}
+		if control != nil && !controlOK && !nsOK {
+			return "the domain's name servers do not answer; it is broken for every resolver, not just this one"
+		}
+		if answered(diagnosticQuery(endpoint.Transport, endpoint.ServerAddr, domain.Domain, qtype, true)) {
+			return "this resolver fails DNSSEC validation for the domain (it answers with checking disabled); a stale trust anchor or a wrong clock"
+		}
+		return "this resolver cannot get an answer from the domain's name servers; an upstream or network path problem on its side"
+	case "NXDOMAIN", "NO_RECORDS", StatusMismatch:
+		if control == nil {
+			return "other resolvers answer, so this one likely blocks, filters or rewrites the domain"
+		}
+		if controlOK {
+			return "the control resolver answers normally, so this resolver blocks, filters or rewrites the domain"
+		}
+		return "the control resolver has no answer either; the record may have changed or be split-horizon"
+	case "REFUSED":
+		return "this resolver refuses the query; an access list, rate limit or a filtering policy"
+	case "TIMEOUT":
+		if control != nil && !controlOK {
+			return "no answer in time and the control resolver fails too; the domain's name servers are slow or unreachable"
+		}
+		return "no answer in time while other resolvers answer; the resolver is slow to recurse for this domain or drops the queries"
+	case "FAILED":
+		return "the query did not get through: " + endpoint.Error
+	}
+	return "failed with " + endpoint.Failure
+}
+
+// printDiagnostics looks into domains that failed on some resolvers but not
+// others: it asks the control resolver, checks the delegation and retries
+// SERVFAILs with checking disabled, then prints a short cause per resolver
+func printDiagnostics(results []*BenchmarkResult) {
+	failing := findFailingDomains(results)
+	if len(failing) == 0 {
+		return
+	}
+
+	fmt.Printf("\n%s[*] Failure Diagnostics (domains failing on some resolvers only, control: %s):%s\n", ColorBlue, controlResolver, ColorReset)
+	if len(failing) > diagnoseMax {
+		fmt.Printf("%s    %d domains fail on some resolvers; diagnosing the %d failing most widely%s\n", ColorCyan, len(failing), diagnoseMax, ColorReset)
+		failing = failing[:diagnoseMax]
+	}
+
+	for _, domain := range failing {
+		qtype := dns.StringToType[domain.QType]
+		control, controlErr := diagnosticQuery(controlTransport, controlResolver, domain.Domain, qtype, false)
+		if controlErr != nil {
+			control = nil
+		}
+		controlCD := control != nil && !answered(control, nil) && answered(diagnosticQuery(controlTransport, controlResolver, domain.Domain, qtype, true))
+		ns, nsErr := diagnosticQuery(controlTransport, controlResolver, domain.Domain, dns.TypeNS, false)
+		nsOK := nsErr == nil && (ns.Rcode == dns.RcodeSuccess || ns.Rcode == dns.RcodeNameError)
+
+		total := domain.Working + len(domain.Failing)
+		fmt.Printf("\n    %s%s%s (%s): fails on %d of %d resolver addresses; control: %s\n",
+			ColorWhite, domain.Domain, ColorReset, domain.QType, len(domain.Failing), total, controlOutcome(control, controlErr))
+		for _, endpoint := range domain.Failing {
+			hint := diagnoseHint(domain, endpoint, control, controlCD, nsOK)
+			fmt.Printf("      %s%-9s%s %s (%s): %s\n", ColorRed, endpoint.Failure, ColorReset, endpoint.ServerName, endpoint.ServerAddr, hint)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// maxDiscoverHosts bounds a discovery scan to a /16
+const maxDiscoverHosts = 1 << 16
+
+// discoveredServer is an address that answered a DNS query during discovery
+type discoveredServer struct {
+	Addr      string
+	Software  string
+	RTT       time.Duration
+	Recursive bool
+	Rcode     string
+}
+
+// runDiscover implements "dnsbench discover 192.168.1.0/24": it probes every
+// address of a subnet on port 53, at a limited rate and only after explicit
+// confirmation, and offers to benchmark the DNS servers that answer
+func runDiscover(args []string) {
+	fs := flag.NewFlagSet("discover", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: dnsbench discover CIDR [flags]\n")
+		fs.PrintDefaults()
+	}
+	rate := fs.Int("rate", 50, "probes per second")
+	timeout := fs.Duration("timeout", time.Second, "time to wait for each address to answer")
+	yes := fs.Bool("yes", false, "scan without asking for confirmation")
+	allowPublic := fs.Bool("allow-public", false, "allow scanning addresses outside private ranges (only networks you are authorized to scan)")
+	bench := fs.Bool("bench", false, "benchmark the discovered resolvers right away instead of asking")
+	positional := parseInterspersed(fs, args)
+	if len(positional) != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	prefix, err := netip.ParsePrefix(positional[0])
+	if err != nil {
+		fmt.Printf("%s[!] Invalid subnet %q: %v%s\n", ColorRed, positional[0], err, ColorReset)
+		os.Exit(2)
+	}
+	prefix = prefix.Masked()
+	if bits := prefix.Addr().BitLen() - prefix.Bits(); bits > 16 {
+		fmt.Printf("%s[!] %s has more than %d addresses; scan a /16 or smaller%s\n", ColorRed, prefix, maxDiscoverHosts, ColorReset)
+		os.Exit(2)
+	}
+	if !prefix.Addr().IsPrivate() && !prefix.Addr().IsLoopback() && !*allowPublic {
+		fmt.Printf("%s[!] %s is not a private network; only scan networks you are authorized to (--allow-public)%s\n", ColorRed, prefix, ColorReset)
+		os.Exit(2)
+	}
+	*rate = max(*rate, 1)
+
+	hosts := subnetHosts(prefix)
+	estimate := time.Duration(len(hosts)) * time.Second / time.Duration(*rate)
+	if !*yes && !confirm(fmt.Sprintf("Probe %d addresses on %s for DNS servers at %d/s (about %v)?", len(hosts), prefix, *rate, estimate.Round(time.Second))) {
+		fmt.Printf("Aborted.\n")
+		return
+	}
+
+	found := discoverServers(hosts, *rate, *timeout)
+	if len(found) == 0 {
+		fmt.Printf("\n%s[!] No DNS servers answered on %s%s\n", ColorYellow, prefix, ColorReset)
+		return
+	}
+
+	fmt.Printf("\n%s%-22s | %-30s | %-10s | %-9s | %s%s\n", ColorWhite, "Address", "Software", "RTT", "Recursive", "Rcode", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────┼────────────────────────────────┼────────────┼───────────┼────────", ColorReset)
+	var serverArgs []string
+	for i, server := range found {
+		recursive := ColorRed + "no" + ColorReset
+		if server.Recursive {
+			recursive = ColorGreen + "yes" + ColorReset
+			serverArgs = append(serverArgs, "--server", fmt.Sprintf("lan%d=%s", i+1, server.Addr))
+		}
+		fmt.Printf("%-22s | %-30s | %7.2f ms | %-18s | %s\n", server.Addr, server.Software, ms(server.RTT), recursive, server.Rcode)
+	}
+	fmt.Printf("\n")
+
+	if len(serverArgs) == 0 {
+		fmt.Printf("%s[!] None of them resolves recursively, so there is nothing to benchmark%s\n", ColorYellow, ColorReset)
+		return
+	}
+	fmt.Printf("%s[✓] Benchmark the recursive ones with:%s\n    dnsbench bench %s\n\n", ColorGreen, ColorReset, strings.Join(serverArgs, " "))
+	if *bench || (!*yes && confirm("Run the benchmark now?")) {
+		runBench("bench", serverArgs)
+	}
+}
+
+// subnetHosts lists the addresses of prefix, leaving out the network and
+// broadcast addresses of IPv4 subnets larger than a /31
+func subnetHosts(prefix netip.Prefix) []netip.Addr {
+	var hosts []netip.Addr
+	for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+		hosts = append(hosts, addr)
+		if !addr.Next().IsValid() {
+			break
+		}
+	}
+	if prefix.Addr().Is4() && prefix.Bits() < 31 && len(hosts) > 2 {
+		hosts = hosts[1 : len(hosts)-1]
+	}
+	return hosts
+}
+
+// discoverServers probes the hosts at the given rate and returns the ones
+// that answered, in address order
+func discoverServers(hosts []netip.Addr, rate int, timeout time.Duration) []*discoveredServer {
+	var mu sync.Mutex
+	var found []*discoveredServer
+	var wg sync.WaitGroup
+
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+	for i, host := range hosts {
+		if i > 0 {
+			<-ticker.C
+		}
+		if i%rate == 0 {
+			fmt.Printf("\r%s[*] Probed %d/%d addresses, %d answered%s", ColorBlue, i, len(hosts), len(found), ColorReset)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if server := probeDiscovered(net.JoinHostPort(host.String(), defaultDNSPort), timeout); server != nil {
+				mu.Lock()
+				found = append(found, server)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("\r%s[*] Probed %d/%d addresses, %d answered%s\n", ColorBlue, len(hosts), len(hosts), len(found), ColorReset)
+
+	slices.SortFunc(found, func(a, b *discoveredServer) int {
+		return netip.MustParseAddrPort(a.Addr).Compare(netip.MustParseAddrPort(b.Addr))
+	})
+	return found
+}
+
+// probeDiscovered sends one recursive query to addr
+func probeDiscovered(addr string, timeout time.Duration) *discoveredServer {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	m := &dns.Msg{}
+	m.SetQuestion("example.com.", dns.TypeA)
+	start := time.Now()
+	r, err := exchange(ctx, m, TransportUDP, addr)
+	if err != nil {
+		return nil
+	}
+	server := &discoveredServer{
+		Addr:      addr,
+		RTT:       time.Since(start),
+		Recursive: r.RecursionAvailable && r.Rcode == dns.RcodeSuccess,
+		Rcode:     dns.RcodeToString[r.Rcode],
+		Software:  "-",
+	}
+	if software := resolverSoftware(ctx, addr); !strings.HasPrefix(addr, software+":") {
+		server.Software = software
+	}
+	return server
+}
+
+// stdinLines reads answers to confirm; it is shared so answers piped in
+// ahead of time are not lost to an earlier prompt's buffer
+var stdinLines = bufio.NewReader(os.Stdin)
+
+// confirm asks a yes/no question on the terminal; anything but y/yes is no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := stdinLines.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
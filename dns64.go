@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Names probed for DNS64 synthesis
+const (
+	// dns64WellKnownName only has A records, 192.0.0.170 and .171 (RFC 7050)
+	dns64WellKnownName = "ipv4only.arpa"
+	// dns64IPv4OnlyName is a real IPv4-only site
+	dns64IPv4OnlyName = "ipv4.google.com"
+	// dns64DualStackName has real AAAA records a DNS64 must pass through
+	dns64DualStackName = "www.google.com"
+)
+
+// dns64WellKnownAddrs are the A records of ipv4only.arpa
+var dns64WellKnownAddrs = []netip.Addr{netip.MustParseAddr("192.0.0.170"), netip.MustParseAddr("192.0.0.171")}
+
+// dns64PrefixLengths are the NAT64 prefix lengths of RFC 6052
+var dns64PrefixLengths = []int{96, 64, 56, 48, 40, 32}
+
+// dns64Result is what one resolver does with AAAA queries for IPv4-only names
+type dns64Result struct {
+	Prefix    netip.Prefix // NAT64 prefix of the synthesized answers, zero without DNS64
+	IPv4Only  string       // what the real IPv4-only name got
+	DualStack string       // whether real AAAA records were kept
+	Err       error
+}
+
+// embeddedIPv4 returns the IPv4 address RFC 6052 embeds in ip for a NAT64
+// prefix of length bits; bits 64-71 (the u octet) are skipped
+func embeddedIPv4(ip netip.Addr, bits int) netip.Addr {
+	b := ip.As16()
+	var v4 [4]byte
+	switch bits {
+	case 96:
+		copy(v4[:], b[12:16])
+	case 64:
+		copy(v4[:], b[9:13])
+	case 56:
+		v4 = [4]byte{b[7], b[9], b[10], b[11]}
+	case 48:
+		v4 = [4]byte{b[6], b[7], b[9], b[10]}
+	case 40:
+		v4 = [4]byte{b[5], b[6], b[7], b[9]}
+	case 32:
+		copy(v4[:], b[4:8])
+	}
+	return netip.AddrFrom4(v4)
+}
+
+// dns64Prefix finds the NAT64 prefix of synthesized answers for
+// ipv4only.arpa, or returns false when none embeds its known addresses
+func dns64Prefix(answers []netip.Addr) (netip.Prefix, bool) {
+	for _, ip := range answers {
+		for _, bits := range dns64PrefixLengths {
+			for _, known := range dns64WellKnownAddrs {
+				if embeddedIPv4(ip, bits) == known {
+					prefix, _ := ip.Prefix(bits)
+					return prefix, true
+				}
+			}
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+// lookupAAAA returns the AAAA records of name
+func lookupAAAA(transport string, addr string, name string) ([]netip.Addr, error) {
+	m := &dns.Msg{}
+	m.SetQuestion(queryName(name), dns.TypeAAAA)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	r, err := exchange(ctx, m, transport, addr)
+	if err != nil {
+		return nil, err
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return nil, fmt.Errorf("rcode: %s", dns.RcodeToString[r.Rcode])
+	}
+	var ips []netip.Addr
+	for _, rr := range r.Answer {
+		if aaaa, ok := rr.(*dns.AAAA); ok {
+			if ip, ok := netip.AddrFromSlice(aaaa.AAAA); ok {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	return ips, nil
+}
+
+// probeDNS64 checks whether a resolver synthesizes AAAA records and, if
+// so, whether it does so for real IPv4-only names and leaves real AAAA
+// records alone as RFC 6147 requires
+func probeDNS64(transport string, addr string) dns64Result {
+	var result dns64Result
+	answers, err := lookupAAAA(transport, addr, dns64WellKnownName)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	prefix, synthesized := dns64Prefix(answers)
+	if !synthesized {
+		return result
+	}
+	result.Prefix = prefix
+
+	switch answers, err := lookupAAAA(transport, addr, dns64IPv4OnlyName); {
+	case err != nil:
+		result.IPv4Only = "error"
+	case len(answers) == 0:
+		result.IPv4Only = "no AAAA"
+	case prefix.Contains(answers[0]):
+		result.IPv4Only = "synthesized"
+	default:
+		result.IPv4Only = "not synthesized"
+	}
+
+	switch answers, err := lookupAAAA(transport, addr, dns64DualStackName); {
+	case err != nil:
+		result.DualStack = "error"
+	case len(answers) == 0:
+		result.DualStack = "dropped"
+	case prefix.Contains(answers[0]):
+		result.DualStack = "replaced"
+	default:
+		result.DualStack = "kept"
+	}
+	return result
+}
+
+// runDNS64 reports which resolvers synthesize AAAA records for IPv4-only
+// names (DNS64) and, knowing whether this network has NAT64, which of them
+// keep IPv4-only sites reachable
+func runDNS64(config *BenchmarkConfig) {
+	fmt.Printf("%s[*] Checking DNS64 synthesis (AAAA records for IPv4-only names)...%s\n\n", ColorBlue, ColorReset)
+
+	// The network's own resolver tells whether there is a NAT64 gateway here
+	var local netip.Prefix
+	localNAT64, localKnown := false, false
+	if resolvConf, err := dns.ClientConfigFromFile(resolvConfPath); err == nil && len(resolvConf.Servers) > 0 {
+		addr := net.JoinHostPort(resolvConf.Servers[0], resolvConf.Port)
+		probe := probeDNS64(TransportUDP, addr)
+		localKnown = probe.Err == nil
+		if localKnown && probe.Prefix.IsValid() {
+			local, localNAT64 = probe.Prefix, true
+			fmt.Printf("%s[*] This network uses NAT64 (prefix %s, from %s): IPv6-only hosts need a DNS64 resolver to reach IPv4-only sites%s\n\n", ColorYellow, local, addr, ColorReset)
+		} else if localKnown {
+			fmt.Printf("%s[*] The network resolver (%s) does no DNS64, so this network most likely has no NAT64%s\n\n", ColorBlue, addr, ColorReset)
+		}
+	}
+	if !localKnown {
+		fmt.Printf("%s[!] Could not ask the network resolver whether this network has NAT64; the Here column is left out%s\n\n", ColorYellow, ColorReset)
+	}
+
+	fmt.Printf("%s%-30s | %-6s | %-22s | %-15s | %-10s | %s%s\n",
+		ColorWhite, "Server", "DNS64", "Prefix", "IPv4-only name", "Real AAAA", "Here", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼────────┼────────────────────────┼─────────────────┼────────────┼──────────────", ColorReset)
+
+	var breaking []string
+	for _, server := range config.Servers {
+		addrs := server.endpoints(config.Transport)
+		if len(addrs) == 0 {
+			continue
+		}
+		display := fmt.Sprintf("%s (%s)", server.Name, addrs[0])
+		result := probeDNS64(config.Transport, addrs[0])
+		if result.Err != nil {
+			fmt.Printf("%-30s | %sERROR: %v%s\n", display, ColorRed, result.Err, ColorReset)
+			continue
+		}
+
+		if !result.Prefix.IsValid() {
+			verdict := ColorGreen + "preserves" + ColorReset
+			switch {
+			case !localKnown:
+				verdict = "-"
+			case localNAT64:
+				verdict = ColorRed + "breaks" + ColorReset
+				breaking = append(breaking, server.Name)
+			}
+			fmt.Printf("%-30s | %-6s | %-22s | %-15s | %-10s | %s\n", display, "no", "-", "-", "-", verdict)
+			continue
+		}
+
+		// Synthesis only helps when the prefix leads to a NAT64 gateway
+		verdict := ColorGreen + "preserves" + ColorReset
+		switch {
+		case !localKnown:
+			verdict = "-"
+		case !localNAT64:
+			verdict = ColorYellow + "detours" + ColorReset
+			breaking = append(breaking, server.Name)
+		case result.Prefix != local:
+			verdict = ColorYellow + "other prefix" + ColorReset
+			breaking = append(breaking, server.Name)
+		}
+		dualStackColor := ColorGreen
+		if result.DualStack != "kept" {
+			dualStackColor = ColorRed
+		}
+		fmt.Printf("%-30s | %s%-6s%s | %-22s | %-15s | %s%-10s%s | %s\n",
+			display, ColorYellow, "yes", ColorReset, result.Prefix, result.IPv4Only, dualStackColor, result.DualStack, ColorReset, verdict)
+	}
+
+	fmt.Printf("\n%s    DNS64 resolvers answer AAAA queries for IPv4-only names with addresses inside a NAT64 prefix (64:ff9b::/96 is well-known);%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s    only a NAT64 gateway on the network can route them. Real AAAA records must be kept.%s\n", ColorCyan, ColorReset)
+	if len(breaking) > 0 && localNAT64 {
+		fmt.Printf("%s[!] On this NAT64 network, IPv6-only devices cannot reach IPv4-only sites through: %s%s\n\n", ColorRed, strings.Join(breaking, ", "), ColorReset)
+	} else if len(breaking) > 0 {
+		fmt.Printf("%s[!] Without NAT64 here, IPv6-preferring devices first try unreachable addresses for IPv4-only sites before falling back to IPv4 through: %s%s\n\n", ColorYellow, strings.Join(breaking, ", "), ColorReset)
+	} else if localKnown {
+		fmt.Printf("%s[✓] Every resolver keeps IPv4-only sites reachable on this network%s\n\n", ColorGreen, ColorReset)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// dnssecDomains are used unless --domains is given: zones known to be signed
+// and popular ones that are not, so both groups are about equally cacheable
+var dnssecDomains = []string{
+	"cloudflare.com", "isc.org", "ietf.org", "nic.cz", "verisign.com", "paypal.com",
+	"google.com", "amazon.com", "microsoft.com", "apple.com", "facebook.com", "netflix.com",
+}
+
+// signedTiming collects answers with the DO bit set to one resolver address
+type signedTiming struct {
+	ServerName string
+	ServerAddr string
+	Signed     []time.Duration
+	Unsigned   []time.Duration
+	Failed     int
+	Validated  int // signed answers carrying the AD flag
+}
+
+// runDNSSECOverhead tags every test domain as signed or unsigned, then
+// queries each resolver with the DO bit set and compares the two groups,
+// the extra time a validating resolver spends fetching and checking
+// signatures. Without --domains a built-in mix of both is used.
+func runDNSSECOverhead(config *BenchmarkConfig, domains []string) {
+	if len(domains) == 0 {
+		domains = dnssecDomains
+	}
+
+	fmt.Printf("%s[*] Tagging %d domains as DNSSEC-signed or unsigned...%s\n", ColorBlue, len(domains), ColorReset)
+	signed := tagSigned(config, domains)
+	var signedNames, unsignedNames []string
+	for _, domain := range domains {
+		if signed[domain] {
+			signedNames = append(signedNames, domain)
+		} else {
+			unsignedNames = append(unsignedNames, domain)
+		}
+	}
+	fmt.Printf("    Signed:   %s\n", listOrNone(signedNames))
+	fmt.Printf("    Unsigned: %s\n\n", listOrNone(unsignedNames))
+	if len(signedNames) == 0 || len(unsignedNames) == 0 {
+		fmt.Printf("%s[!] Need both signed and unsigned domains to compare%s\n", ColorRed, ColorReset)
+		return
+	}
+
+	fmt.Printf("%s[*] Querying with DO set, %d rounds...%s\n\n", ColorBlue, config.QueryNum, ColorReset)
+	var timings []*signedTiming
+	for _, server := range config.Servers {
+		for _, addr := range server.endpoints(config.Transport) {
+			timing := &signedTiming{ServerName: server.Name, ServerAddr: addr}
+			for i := 0; i < config.QueryNum; i++ {
+				for _, domain := range domains {
+					rtt, r, err := queryDO(config.Transport, addr, domain)
+					switch {
+					case err != nil || r.Rcode != dns.RcodeSuccess:
+						timing.Failed++
+					case signed[domain]:
+						timing.Signed = append(timing.Signed, rtt)
+						if r.AuthenticatedData {
+							timing.Validated++
+						}
+					default:
+						timing.Unsigned = append(timing.Unsigned, rtt)
+					}
+				}
+			}
+			timings = append(timings, timing)
+		}
+	}
+	printDNSSECOverhead(timings)
+}
+
+// tagSigned asks the resolvers for each domain with DO set; a domain counts
+// as signed when any of them returns RRSIGs with the answer
+func tagSigned(config *BenchmarkConfig, domains []string) map[string]bool {
+	signed := make(map[string]bool)
+	for _, domain := range domains {
+		for _, server := range config.Servers {
+			for _, addr := range server.endpoints(config.Transport) {
+				_, r, err := queryDO(config.Transport, addr, domain)
+				if err != nil {
+					continue
+				}
+				for _, rr := range r.Answer {
+					if _, ok := rr.(*dns.RRSIG); ok {
+						signed[domain] = true
+					}
+				}
+				if signed[domain] {
+					break
+				}
+			}
+			if signed[domain] {
+				break
+			}
+		}
+	}
+	return signed
+}
+
+// queryDO sends an A query with the DNSSEC OK bit set
+func queryDO(transport, addr, domain string) (time.Duration, *dns.Msg, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutFor(addr))
+	defer cancel()
+
+	m := &dns.Msg{}
+	m.SetQuestion(queryName(domain), dns.TypeA)
+	m.SetEdns0(dns.DefaultMsgSize, true)
+	start := time.Now()
+	r, err := exchange(ctx, m, transport, addr)
+	return time.Since(start), r, err
+}
+
+func printDNSSECOverhead(timings []*signedTiming) {
+	fmt.Printf("%s%-30s | %-14s | %-14s | %-12s | %-10s | %s%s\n",
+		ColorWhite, "Server", "Signed Median", "Unsigned Med.", "Overhead", "Validates", "Failed", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼────────────────┼────────────────┼──────────────┼────────────┼───────", ColorReset)
+
+	for _, t := range timings {
+		serverDisplay := fmt.Sprintf("%s (%s)", t.ServerName, t.ServerAddr)
+		if len(t.Signed) == 0 || len(t.Unsigned) == 0 {
+			fmt.Printf("%-30s | %14s | %14s | %12s | %-10s | %d\n", serverDisplay, "-", "-", "-", "-", t.Failed)
+			continue
+		}
+		signedMedian, unsignedMedian := percentile(t.Signed, 50), percentile(t.Unsigned, 50)
+		overhead := signedMedian - unsignedMedian
+		overheadColor := ColorGreen
+		if overhead > 0 && significantlyDifferent(t.Signed, t.Unsigned) {
+			overheadColor = ColorYellow
+		}
+
+		validates := ColorRed + "no" + ColorReset
+		switch {
+		case t.Validated == len(t.Signed):
+			validates = ColorGreen + "yes" + ColorReset
+		case t.Validated > 0:
+			validates = ColorYellow + "partly" + ColorReset
+		}
+		fmt.Printf("%-30s | %11.2f ms | %11.2f ms | %s%+9.2f ms%s | %-19s | %d\n",
+			serverDisplay, ms(signedMedian), ms(unsignedMedian), overheadColor, ms(overhead), ColorReset, validates, t.Failed)
+	}
+
+	fmt.Printf("\n%s    Overhead is the signed minus the unsigned median; yellow when the difference is significant.%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s    Validation mostly costs time on cache misses, so the first round shows it best.%s\n\n", ColorCyan, ColorReset)
+}
+
+// listOrNone joins names for display
+func listOrNone(names []string) string {
+	if len(names) == 0 {
+		return "(none)"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// dohVariant is one combination of HTTP method and protocol version for DoH
+type dohVariant struct {
+	Method string
+	HTTP   int // major protocol version: 1, 2 or 3
+}
+
+// proto is the response protocol a variant expects
+func (v dohVariant) proto() string {
+	if v.HTTP == 1 {
+		return "HTTP/1.1"
+	}
+	return fmt.Sprintf("HTTP/%d.0", v.HTTP)
+}
+
+func (v dohVariant) String() string {
+	if v.HTTP == 1 {
+		return v.Method + " over HTTP/1.1"
+	}
+	return fmt.Sprintf("%s over HTTP/%d", v.Method, v.HTTP)
+}
+
+// dohVariants are the combinations compared in doh-compare mode
+var dohVariants = []dohVariant{
+	{http.MethodGet, 1},
+	{http.MethodPost, 1},
+	{http.MethodGet, 2},
+	{http.MethodPost, 2},
+	{http.MethodGet, 3},
+	{http.MethodPost, 3},
+}
+
+// dohVariantStats holds results for one DoH variant of one provider
+type dohVariantStats struct {
+	variant   dohVariant
+	rtts      []time.Duration
+	total     int
+	protoSeen string
+	noQUIC    error // set when the HTTP/3 handshake failed; later queries are skipped
+}
+
+// runDoHCompare benchmarks every DoH endpoint with GET vs POST over
+// HTTP/1.1, HTTP/2 and HTTP/3, reporting the fastest combination per provider
+func runDoHCompare(config *BenchmarkConfig) {
+	variants := dohVariants
+	if proxyURL != nil {
+		// QUIC runs over UDP, which the proxy cannot carry
+		variants = variants[:4]
+		fmt.Printf("%s[*] Comparing DoH GET vs POST over HTTP/1.1 and HTTP/2...%s\n", ColorBlue, ColorReset)
+		fmt.Printf("%s    HTTP/3 cannot go through --proxy and is skipped%s\n\n", ColorYellow, ColorReset)
+	} else {
+		fmt.Printf("%s[*] Comparing DoH GET vs POST over HTTP/1.1, HTTP/2 and HTTP/3...%s\n\n", ColorBlue, ColorReset)
+	}
+
+	for _, server := range config.Servers {
+		if server.DoH == "" {
+			continue
+		}
+
+		// Separate clients so HTTP/1.1 connections are never upgraded
+		clients := map[int]*http.Client{
+			1: newDoHClient(false),
+			2: newDoHClient(true),
+			3: {Transport: newH3Transport()},
+		}
+
+		stats := make([]*dohVariantStats, len(variants))
+		for i, variant := range variants {
+			stats[i] = &dohVariantStats{variant: variant}
+		}
+
+		// Interleave variants so none benefits from warmer caches
+		for i := 0; i < config.QueryNum; i++ {
+			for _, domain := range config.Domains {
+				for _, qtype := range config.QTypes {
+					for _, stat := range stats {
+						if stat.noQUIC != nil {
+							continue
+						}
+						m := &dns.Msg{}
+						m.SetQuestion(queryName(domain), dns.StringToType[qtype])
+						applyQueryFlags(m)
+
+						ctx, cancel := context.WithTimeout(context.Background(), timeoutFor(server.DoH))
+						start := time.Now()
+						r, sess, err := exchangeDoHWith(ctx, clients[stat.variant.HTTP], stat.variant.Method, m, server.DoH)
+						rtt := time.Since(start)
+						cancel()
+
+						// Endpoints without HTTP/3 would time out every query
+						var connErr *connectError
+						if stat.variant.HTTP == 3 && len(stat.rtts) == 0 && errors.As(err, &connErr) {
+							stat.noQUIC = connErr.err
+							continue
+						}
+						stat.total++
+						if sess.Proto != "" {
+							stat.protoSeen = sess.Proto
+						}
+						if err == nil && r.Rcode == dns.RcodeSuccess {
+							stat.rtts = append(stat.rtts, rtt)
+						}
+					}
+				}
+			}
+		}
+
+		for _, client := range clients {
+			client.CloseIdleConnections()
+		}
+		printDoHCompare(server, stats)
+	}
+}
+
+func printDoHCompare(server *DNSServer, stats []*dohVariantStats) {
+	fmt.Printf("%s[*] %s (%s)%s\n", ColorBlue, server.Name, server.DoH, ColorReset)
+	fmt.Printf("%s%-22s | %-12s | %-12s | %-12s | %-12s%s\n",
+		ColorWhite, "Variant", "Avg RTT", "p50 RTT", "p95 RTT", "Success Rate", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────┼──────────────┼──────────────┼──────────────┼─────────────", ColorReset)
+
+	var fastest *dohVariantStats
+	var fastestAvg time.Duration
+	for _, stat := range stats {
+		if stat.noQUIC != nil {
+			fmt.Printf("%-22s | %sno QUIC handshake: %v%s\n", stat.variant, ColorYellow, stat.noQUIC, ColorReset)
+			continue
+		}
+		var avg time.Duration
+		for _, rtt := range stat.rtts {
+			avg += rtt
+		}
+		if len(stat.rtts) > 0 {
+			avg /= time.Duration(len(stat.rtts))
+			if fastest == nil || avg < fastestAvg {
+				fastest, fastestAvg = stat, avg
+			}
+		}
+
+		successRate := float64(len(stat.rtts)) / float64(stat.total) * 100
+		successColor := ColorGreen
+		if successRate < 100 {
+			successColor = ColorRed
+		}
+
+		label := stat.variant.String()
+		if stat.variant.HTTP == 2 && stat.protoSeen != "" && stat.protoSeen != stat.variant.proto() {
+			// Server refused to negotiate HTTP/2 and fell back
+			label += "*"
+		}
+		fmt.Printf("%-22s | %8.2f ms | %8.2f ms | %8.2f ms | %s%6.1f%%%s\n",
+			label, ms(avg), ms(percentile(stat.rtts, 50)), ms(percentile(stat.rtts, 95)),
+			successColor, successRate, ColorReset,
+		)
+	}
+
+	for _, stat := range stats {
+		if stat.variant.HTTP == 2 && stat.protoSeen != "" && stat.protoSeen != stat.variant.proto() {
+			fmt.Printf("%s    * HTTP/2 not negotiated, server answered with %s%s\n", ColorYellow, stat.protoSeen, ColorReset)
+			break
+		}
+	}
+	if fastest != nil {
+		fmt.Printf("\n%s[✓] Fastest: %s (avg %.2f ms)%s\n\n", ColorGreen, fastest.variant, ms(fastestAvg), ColorReset)
+	} else {
+		fmt.Printf("\n%s[!] No successful DoH queries%s\n\n", ColorRed, ColorReset)
+	}
+}
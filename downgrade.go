@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// session is the protocol a query actually went over
+type session struct {
+	TLSVersion string // empty when the query was sent in the clear
+	ALPN       string // negotiated application protocol, e.g. dot or h2
+	Proto      string // HTTP protocol of DoH responses, e.g. HTTP/2.0
+}
+
+// connSession returns the session of a stream connection
+func connSession(conn net.Conn) session {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		cs := tlsConn.ConnectionState()
+		return session{TLSVersion: tls.VersionName(cs.Version), ALPN: cs.NegotiatedProtocol}
+	}
+	return session{}
+}
+
+// responseSession returns the session a DoH response arrived over; after a
+// redirect to http:// it is a plaintext one
+func responseSession(resp *http.Response) session {
+	s := session{Proto: resp.Proto}
+	if resp.TLS != nil {
+		s.TLSVersion, s.ALPN = tls.VersionName(resp.TLS.Version), resp.TLS.NegotiatedProtocol
+	}
+	return s
+}
+
+// tlsRank orders TLS versions by strength; plaintext ranks lowest
+var tlsRank = map[string]int{"": 0, "TLS 1.0": 1, "TLS 1.1": 2, "TLS 1.2": 3, "TLS 1.3": 4}
+
+// weakerThan describes how s falls short of the first session seen on an
+// endpoint, or returns "" when it does not
+func (s session) weakerThan(first session) string {
+	switch {
+	case s.TLSVersion == "":
+		return "sent in the clear"
+	case tlsRank[s.TLSVersion] < tlsRank[first.TLSVersion]:
+		return fmt.Sprintf("%s instead of %s", s.TLSVersion, first.TLSVersion)
+	case first.ALPN != "" && s.ALPN != first.ALPN:
+		return fmt.Sprintf("ALPN %q instead of %q", s.ALPN, first.ALPN)
+	}
+	return ""
+}
+
+// endpointSessions is what the encrypted transport of one endpoint did over
+// the run
+type endpointSessions struct {
+	first      session
+	downgraded int
+	queries    int
+	reason     string // of the last downgrade
+}
+
+var (
+	sessions   = make(map[string]*endpointSessions)
+	sessionsMu sync.Mutex
+)
+
+// checkDowngrade records the session of a DoT or DoH query and warns the
+// first time an endpoint falls back to plaintext, an older TLS version or
+// another ALPN than its first connection negotiated
+func checkDowngrade(transport string, addr string, s session) {
+	if transport != TransportDoT && transport != TransportDoH {
+		return
+	}
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	endpoint, ok := sessions[addr]
+	if !ok {
+		endpoint = &endpointSessions{first: s}
+		sessions[addr] = endpoint
+	}
+	endpoint.queries++
+	// A plaintext first session is itself the downgrade
+	reason := s.weakerThan(endpoint.first)
+	if reason == "" {
+		return
+	}
+	if endpoint.downgraded == 0 {
+		slog.Warn("Encrypted transport downgraded", "endpoint", addr, "transport", transport, "reason", reason)
+	}
+	endpoint.downgraded++
+	endpoint.reason = reason
+}
+
+// printDowngrades reports the negotiated protocols of every encrypted
+// endpoint and the queries that did not use them
+func printDowngrades() {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if len(sessions) == 0 {
+		return
+	}
+	var addrs []string
+	for addr := range sessions {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	fmt.Printf("%s[*] Encrypted transports:%s\n\n", ColorBlue, ColorReset)
+	fmt.Printf("%s%-45s | %-9s | %-10s | %s%s\n", ColorWhite, "Endpoint", "TLS", "ALPN", "Downgraded", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "──────────────────────────────────────────────┼───────────┼────────────┼───────────────", ColorReset)
+	var downgraded []string
+	for _, addr := range addrs {
+		endpoint := sessions[addr]
+		version, alpn := endpoint.first.TLSVersion, endpoint.first.ALPN
+		if version == "" {
+			version = "none"
+		}
+		if alpn == "" {
+			alpn = "none"
+		}
+		status := fmt.Sprintf("%s0/%d%s", ColorGreen, endpoint.queries, ColorReset)
+		if endpoint.downgraded > 0 {
+			status = fmt.Sprintf("%s%d/%d%s", ColorRed, endpoint.downgraded, endpoint.queries, ColorReset)
+			downgraded = append(downgraded, fmt.Sprintf("%s: %s", addr, endpoint.reason))
+		}
+		fmt.Printf("%-45s | %-9s | %-10s | %s\n", addr, version, alpn, status)
+	}
+	fmt.Printf("\n")
+	for _, line := range downgraded {
+		fmt.Printf("%s[!] Downgraded queries on %s%s\n", ColorRed, line, ColorReset)
+	}
+	if len(downgraded) > 0 {
+		fmt.Printf("\n")
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// dryRunRTT is the round trip assumed when estimating how long a run takes
+const dryRunRTT = 30 * time.Millisecond
+
+// connectionBytes approximates the traffic of opening one connection per
+// transport: TCP handshake and teardown, plus the TLS handshake with its
+// certificate chain for DoT and DoH
+var connectionBytes = map[string]int{
+	TransportUDP: 0,
+	TransportTCP: 400,
+	TransportDoT: 5000,
+	TransportDoH: 6000,
+}
+
+// Per-query overhead: IPv4 and UDP headers each way, or the TCP segment
+// headers and length prefix
+const (
+	datagramOverhead = 2 * 28
+	streamOverhead   = 2 * 42
+	answerBytes      = 64 // a typical A answer adds about this to the question
+)
+
+// printDryRun lists the query matrix the configuration would send, with
+// estimated duration and traffic, without sending anything
+func printDryRun(config *BenchmarkConfig) {
+	fmt.Printf("%s[*] Dry run: nothing is sent%s\n\n", ColorBlue, ColorReset)
+
+	switch config.Mode {
+	case ModeConcurrent, ModeSequential, ModeMonitor:
+	case ModeReplay:
+		printReplayDryRun(config)
+		return
+	default:
+		fmt.Printf("    Mode %s sends its own probe queries; the configuration is valid.\n\n", config.Mode)
+		return
+	}
+
+	jobs := buildJobs(config)
+	if config.Mode == ModeMonitor {
+		// One query per domain and endpoint each round
+		jobs = jobs[:0]
+		for _, job := range buildJobs(config) {
+			if job.Iteration == 0 {
+				jobs = append(jobs, job)
+			}
+		}
+	}
+	perEndpoint := make(map[string]int)
+	var order []string
+	for _, job := range jobs {
+		key := job.ServerName + " (" + job.ServerAddr + ")"
+		if _, ok := perEndpoint[key]; !ok {
+			order = append(order, key)
+		}
+		perEndpoint[key]++
+	}
+
+	fmt.Printf("%s%-45s | %s%s\n", ColorWhite, "Endpoint", "Queries", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "──────────────────────────────────────────────┼─────────", ColorReset)
+	for _, key := range order {
+		fmt.Printf("%-45s | %7d\n", key, perEndpoint[key])
+	}
+	fmt.Printf("\n    %d endpoints × %d domains × %d types", len(order), len(config.Domains), len(config.QTypes))
+	if config.Mode != ModeMonitor {
+		fmt.Printf(" × %d iterations", config.QueryNum)
+	}
+	fmt.Printf(" = %d queries", len(jobs))
+	if config.Mode == ModeMonitor {
+		fmt.Printf(" per round")
+	}
+	fmt.Printf("\n")
+
+	typical, worst := estimateDuration(config, len(jobs), perEndpoint)
+	traffic := estimateTraffic(config, jobs, len(order))
+	if config.Mode == ModeMonitor {
+		fmt.Printf("    Round: about %v (up to %v if every query times out), every %v\n", typical.Round(time.Millisecond), worst.Round(time.Millisecond), config.Monitor.Interval)
+		fmt.Printf("    Traffic: about %s per round, %s per hour\n", formatBytes(traffic), formatBytes(int(float64(traffic)*float64(time.Hour)/float64(config.Monitor.Interval))))
+	} else {
+		fmt.Printf("    Duration: about %v (up to %v if every query times out)\n", typical.Round(time.Millisecond), worst.Round(time.Millisecond))
+		if config.MaxDuration > 0 && worst > config.MaxDuration {
+			fmt.Printf("    Deadline: queries not started within %v are skipped\n", config.MaxDuration)
+		}
+		fmt.Printf("    Traffic: about %s\n", formatBytes(traffic))
+		if !config.SkipHTTP {
+			fmt.Printf("    Website test: not included above (--skip-http leaves it out)\n")
+		}
+	}
+	fmt.Printf("\n%s[✓] Configuration is valid%s\n", ColorGreen, ColorReset)
+}
+
+// estimateDuration returns the expected run time at dryRunRTT and the worst
+// case where every query waits for its full timeout
+func estimateDuration(config *BenchmarkConfig, queries int, perEndpoint map[string]int) (time.Duration, time.Duration) {
+	var typical, worst time.Duration
+	if config.Mode == ModeSequential {
+		typical = time.Duration(queries) * (config.Pacing + dryRunRTT)
+		worst = time.Duration(queries) * (config.Pacing + attemptTimeout())
+	} else {
+		batches := time.Duration((queries + config.Concurrency - 1) / config.Concurrency)
+		typical, worst = batches*dryRunRTT, batches*attemptTimeout()
+	}
+
+	// --delay spaces queries to one endpoint, so the busiest one sets a floor
+	var busiest int
+	for _, n := range perEndpoint {
+		busiest = max(busiest, n)
+	}
+	floor := time.Duration(max(busiest-1, 0)) * config.Delay
+	return max(typical, floor), max(worst, floor)
+}
+
+// estimateTraffic adds up questions, typical answers and per-query or
+// per-connection transport overhead
+func estimateTraffic(config *BenchmarkConfig, jobs []queryJob, endpoints int) int {
+	var total int
+	for _, job := range jobs {
+		m := &dns.Msg{}
+		m.SetQuestion(queryName(job.Domain), dns.StringToType[job.qtype()])
+		total += 2*m.Len() + answerBytes
+	}
+
+	overhead := streamOverhead
+	if config.Transport == TransportUDP {
+		overhead = datagramOverhead
+	}
+	total += len(jobs) * overhead
+
+	connections := len(jobs)
+	if reuseConns || config.Transport == TransportDoH {
+		// Pooled connections, and DoH keeps its HTTP connections alive
+		connections = endpoints
+	}
+	return total + connections*connectionBytes[config.Transport]
+}
+
+// formatBytes renders a byte count as B, KB or MB
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// printReplayDryRun estimates a replay: every logged query once per
+// endpoint, over the log's span with idle gaps capped and the speed applied
+func printReplayDryRun(config *BenchmarkConfig) {
+	replay := config.Replay
+	var endpoints int
+	for _, server := range config.Servers {
+		endpoints += len(server.endpoints(config.Transport))
+	}
+	var playback time.Duration
+	if replay.Speed > 0 {
+		for i := 1; i < len(replay.Queries); i++ {
+			gap := min(max(replay.Queries[i].At.Sub(replay.Queries[i-1].At), 0), replayMaxGap)
+			playback += time.Duration(float64(gap) / replay.Speed)
+		}
+	}
+	queries := len(replay.Queries) * endpoints
+	fmt.Printf("    %d endpoints × %d logged queries = %d queries\n", endpoints, len(replay.Queries), queries)
+	fmt.Printf("    Duration: about %v of playback\n", (playback + dryRunRTT).Round(time.Millisecond))
+	fmt.Printf("\n%s[✓] Configuration is valid%s\n", ColorGreen, ColorReset)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// ResolverEgress is the address a resolver uses to reach authoritative
+// servers, which CDNs use to pick the edge they send the user to
+type ResolverEgress struct {
+	IP      string
+	ECS     string // client subnet forwarded to authoritatives, if any
+	ASN     int
+	ISP     string
+	Country string
+}
+
+// lookupEgress asks Google's authoritative servers which address the query
+// came from (o-o.myaddr.l.google.com TXT), falling back to whoami.akamai.net
+func lookupEgress(ctx context.Context, transport string, addr string) (*ResolverEgress, error) {
+	m := &dns.Msg{}
+	m.SetQuestion("o-o.myaddr.l.google.com.", dns.TypeTXT)
+	if r, err := exchange(ctx, m, transport, addr); err == nil {
+		egress := &ResolverEgress{}
+		for _, rr := range r.Answer {
+			txt, ok := rr.(*dns.TXT)
+			if !ok || len(txt.Txt) == 0 {
+				continue
+			}
+			// "edns0-client-subnet 203.0.113.0/24" or the bare egress IP
+			if subnet, found := strings.CutPrefix(txt.Txt[0], "edns0-client-subnet "); found {
+				egress.ECS = subnet
+			} else if net.ParseIP(txt.Txt[0]) != nil {
+				egress.IP = txt.Txt[0]
+			}
+		}
+		if egress.IP != "" {
+			return egress, nil
+		}
+	}
+
+	ips, _, err := lookupA(transport, addr, "whoami.akamai.net")
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no egress answer")
+	}
+	return &ResolverEgress{IP: ips[0]}, nil
+}
+
+// runEgress reports each resolver's egress IP, network and country and
+// whether it exits near the user, which matters more for CDN steering than RTT
+func runEgress(config *BenchmarkConfig) {
+	fmt.Printf("%s[*] Detecting resolver egress locations...%s\n", ColorBlue, ColorReset)
+	if runMetadata == nil || runMetadata.ASN == 0 {
+		fmt.Printf("%s    Your own network is unknown, so egress cannot be compared to it%s\n", ColorYellow, ColorReset)
+	}
+	fmt.Printf("\n")
+
+	fmt.Printf("%s%-30s | %-39s | %-8s | %-7s | %-18s | %-14s%s\n",
+		ColorWhite, "Server", "Egress IP", "ASN", "Country", "ECS", "Near You", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼─────────────────────────────────────────┼──────────┼─────────┼────────────────────┼───────────────", ColorReset)
+
+	for _, server := range config.Servers {
+		addrs := server.endpoints(config.Transport)
+		if len(addrs) == 0 {
+			continue
+		}
+		addr := addrs[0]
+		display := fmt.Sprintf("%s (%s)", server.Name, addr)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		egress, err := lookupEgress(ctx, config.Transport, addr)
+		if err != nil {
+			cancel()
+			fmt.Printf("%-30s | %s%-39s%s\n", display, ColorRed, "unknown: "+err.Error(), ColorReset)
+			continue
+		}
+		egress.ASN, egress.ISP, egress.Country = lookupASN(ctx, config.Transport, addr, egress.IP, "")
+		cancel()
+
+		asn, country, ecs := "-", "-", "-"
+		if egress.Country != "" {
+			country = egress.Country
+		}
+		if egress.ASN > 0 {
+			asn = fmt.Sprintf("AS%d", egress.ASN)
+		}
+		if egress.ECS != "" {
+			ecs = egress.ECS
+		}
+		fmt.Printf("%-30s | %-39s | %-8s | %-7s | %-18s | %s\n",
+			display, egress.IP, asn, country, ecs, egressProximity(egress))
+	}
+
+	fmt.Printf("\n%s    ECS shows the client subnet a resolver forwards to authoritative servers;%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s    with it, CDNs can steer you correctly even when the resolver exits far away%s\n", ColorCyan, ColorReset)
+}
+
+// egressProximity compares the egress network with the user's own
+func egressProximity(egress *ResolverEgress) string {
+	switch {
+	case runMetadata == nil || runMetadata.ASN == 0 || egress.ASN == 0:
+		return "?"
+	case egress.ASN == runMetadata.ASN:
+		return ColorGreen + "same network" + ColorReset
+	case egress.Country != "" && egress.Country == runMetadata.Country:
+		return ColorGreen + "same country" + ColorReset
+	case egress.ECS != "":
+		return ColorYellow + "far, sends ECS" + ColorReset
+	}
+	return ColorRed + "far" + ColorReset
+}
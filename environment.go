@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Interface types of RunEnvironment
+const (
+	InterfaceEthernet = "ethernet"
+	InterfaceWiFi     = "wifi"
+	InterfaceVPN      = "vpn"
+	InterfaceCellular = "cellular"
+	InterfaceLoopback = "loopback"
+	InterfaceOther    = "other"
+)
+
+// RunEnvironment describes the machine and link a benchmark ran on, so runs
+// over flaky wireless stand out when results are compared
+type RunEnvironment struct {
+	OS            string `json:"os"`
+	Hostname      string `json:"hostname,omitempty"`
+	Interface     string `json:"interface,omitempty"`
+	InterfaceType string `json:"interface_type,omitempty"`
+	LinkSpeed     int    `json:"link_speed_mbps,omitempty"`
+	SSID          string `json:"ssid,omitempty"`       // --wifi-info only
+	SignalDBm     int    `json:"signal_dbm,omitempty"` // --wifi-info only
+}
+
+// wifiInfo enables capturing the Wi-Fi network name and signal (--wifi-info)
+var wifiInfo bool
+
+// String renders the environment for the configuration listing and reports
+func (env *RunEnvironment) String() string {
+	parts := []string{env.OS}
+	if env.Hostname != "" {
+		parts = append(parts, "host "+env.Hostname)
+	}
+	if env.Interface != "" {
+		link := env.InterfaceType + " (" + env.Interface
+		if env.SSID != "" {
+			link += fmt.Sprintf(", SSID %q", env.SSID)
+		}
+		if env.LinkSpeed > 0 {
+			link += fmt.Sprintf(", %d Mbps", env.LinkSpeed)
+		}
+		if env.SignalDBm != 0 {
+			link += fmt.Sprintf(", %d dBm", env.SignalDBm)
+		}
+		parts = append(parts, link+")")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// wireless reports whether the run went over Wi-Fi or a cellular link
+func (env *RunEnvironment) wireless() bool {
+	return env != nil && (env.InterfaceType == InterfaceWiFi || env.InterfaceType == InterfaceCellular)
+}
+
+// captureEnvironment records the OS, hostname and the interface outgoing
+// queries leave through: --interface when set, otherwise the one routing
+// to the internet
+func captureEnvironment(iface string) *RunEnvironment {
+	env := &RunEnvironment{OS: runtime.GOOS + "/" + runtime.GOARCH}
+	if name := osName(); name != "" {
+		env.OS += " (" + name + ")"
+	}
+	env.Hostname, _ = os.Hostname()
+
+	if iface == "" {
+		iface = outgoingInterface()
+	}
+	if iface == "" {
+		return env
+	}
+	env.Interface = iface
+	env.InterfaceType, env.LinkSpeed = interfaceLink(iface)
+	if wifiInfo && env.InterfaceType == InterfaceWiFi {
+		env.SSID, env.SignalDBm, env.LinkSpeed = wirelessLink(iface, env.LinkSpeed)
+	}
+	return env
+}
+
+// outgoingInterface names the interface holding the local address the OS
+// picks for an internet destination; connecting a UDP socket sends nothing
+func outgoingInterface() string {
+	conn, err := newDialer("udp", "1.1.1.1:53").Dial("udp", "1.1.1.1:53")
+	if err != nil {
+		return ""
+	}
+	local := conn.LocalAddr().(*net.UDPAddr).IP
+	conn.Close()
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	for _, ifi := range ifaces {
+		addrs, _ := ifi.Addrs()
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(local) {
+				return ifi.Name
+			}
+		}
+	}
+	return ""
+}
+
+// interfaceTypeByName guesses the link type from common interface names,
+// for platforms without a better source
+func interfaceTypeByName(iface string) string {
+	switch {
+	case strings.HasPrefix(iface, "lo"):
+		return InterfaceLoopback
+	case strings.HasPrefix(iface, "wl"), strings.HasPrefix(iface, "wifi"), strings.HasPrefix(iface, "ath"):
+		return InterfaceWiFi
+	case strings.HasPrefix(iface, "tun"), strings.HasPrefix(iface, "tap"), strings.HasPrefix(iface, "wg"),
+		strings.HasPrefix(iface, "utun"), strings.HasPrefix(iface, "ppp"), strings.HasPrefix(iface, "tailscale"):
+		return InterfaceVPN
+	case strings.HasPrefix(iface, "ww"), strings.HasPrefix(iface, "rmnet"), strings.HasPrefix(iface, "pdp_ip"):
+		return InterfaceCellular
+	case strings.HasPrefix(iface, "eth"), strings.HasPrefix(iface, "en"):
+		return InterfaceEthernet
+	}
+	return InterfaceOther
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// osName returns PRETTY_NAME from /etc/os-release, e.g. "Ubuntu 24.04 LTS"
+func osName() string {
+	f, err := os.Open("/etc/os-release")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "PRETTY_NAME="); ok {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
+
+// interfaceLink reads the link type and speed from sysfs: wireless devices
+// have a wireless or phy80211 entry, tunnels use ARPHRD_NONE (65534) or
+// ARPHRD_PPP (512) and Ethernet reports its negotiated speed
+func interfaceLink(iface string) (string, int) {
+	dir := filepath.Join("/sys/class/net", iface)
+	speed, _ := strconv.Atoi(strings.TrimSpace(readFile(filepath.Join(dir, "speed"))))
+	speed = max(speed, 0) // -1 when the driver does not know
+
+	for _, entry := range []string{"wireless", "phy80211"} {
+		if _, err := os.Stat(filepath.Join(dir, entry)); err == nil {
+			return InterfaceWiFi, speed
+		}
+	}
+	switch strings.TrimSpace(readFile(filepath.Join(dir, "type"))) {
+	case "772":
+		return InterfaceLoopback, 0
+	case "65534", "512":
+		return InterfaceVPN, 0
+	case "1":
+		if _, err := os.Stat(filepath.Join(dir, "device")); err != nil {
+			// Virtual: bridges, veth pairs and tap-style VPNs
+			if kind := interfaceTypeByName(iface); kind != InterfaceEthernet {
+				return kind, speed
+			}
+		}
+		return InterfaceEthernet, speed
+	}
+	return interfaceTypeByName(iface), speed
+}
+
+// wirelessLink asks iw for the connected network, signal and TX bitrate;
+// without iw only the signal from /proc/net/wireless is available
+func wirelessLink(iface string, speed int) (string, int, int) {
+	var ssid string
+	var signal int
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if out, err := exec.CommandContext(ctx, "iw", "dev", iface, "link").Output(); err == nil {
+		for _, line := range strings.Split(string(out), "\n") {
+			key, value, ok := strings.Cut(strings.TrimSpace(line), ": ")
+			if !ok {
+				continue
+			}
+			switch key {
+			case "SSID":
+				ssid = value
+			case "signal":
+				signal, _ = strconv.Atoi(strings.Fields(value)[0])
+			case "tx bitrate":
+				if rate, err := strconv.ParseFloat(strings.Fields(value)[0], 64); err == nil {
+					speed = int(rate)
+				}
+			}
+		}
+	}
+
+	if signal == 0 {
+		// "wlan0: 0000   54.  -56.  -256 ..." after two header lines
+		for _, line := range strings.Split(readFile("/proc/net/wireless"), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) > 3 && fields[0] == iface+":" {
+				level, _ := strconv.ParseFloat(strings.TrimSuffix(fields[3], "."), 64)
+				signal = int(level)
+			}
+		}
+	}
+	return ssid, signal, speed
+}
+
+// readFile returns a file's contents, or "" when it cannot be read
+func readFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+package main
+
+import "net"
+
+// osName is only known on Linux; GOOS identifies other systems well enough
+func osName() string {
+	return ""
+}
+
+// interfaceLink guesses the link type from the interface name; link speed
+// is not available without platform APIs
+func interfaceLink(iface string) (string, int) {
+	if ifi, err := net.InterfaceByName(iface); err == nil && ifi.Flags&net.FlagLoopback != 0 {
+		return InterfaceLoopback, 0
+	}
+	return interfaceTypeByName(iface), 0
+}
+
+// wirelessLink is not supported outside Linux
+func wirelessLink(iface string, speed int) (string, int, int) {
+	return "", 0, speed
+}
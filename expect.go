@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// StatusMismatch marks an answer that differs from the --expect rules
+const StatusMismatch = "MISMATCH"
+
+// expectations maps a domain to the answers it must resolve to, loaded from
+// the --expect file
+var expectations map[string]*ExpectRule
+
+// ExpectRule describes the correct answer for one domain. Every field is
+// optional; the ones given must all hold.
+type ExpectRule struct {
+	// IPs is the set addresses must come from; every A/AAAA record has to be
+	// in it. Only addresses of the queried family are checked.
+	IPs []string `json:"ips,omitempty"`
+
+	// CNAME is a target the answer has to contain
+	CNAME string `json:"cname,omitempty"`
+
+	// Match is a regular expression at least one answer record's data has
+	// to match, e.g. "^10\\." for an internal view
+	Match string `json:"match,omitempty"`
+
+	ips   []net.IP
+	match *regexp.Regexp
+}
+
+// configureExpectations loads expected answers from a JSON file mapping
+// domains to rules:
+//
+//	{"intranet.corp.example": {"ips": ["10.1.2.3", "10.1.2.4"]},
+//	 "www.corp.example": {"cname": "lb.corp.example", "match": "^10\\."}}
+func configureExpectations(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("--expect: %w", err)
+	}
+	rules := make(map[string]*ExpectRule)
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("--expect: invalid rules: %w", err)
+	}
+
+	expectations = make(map[string]*ExpectRule, len(rules))
+	for domain, rule := range rules {
+		if rule == nil || (len(rule.IPs) == 0 && rule.CNAME == "" && rule.Match == "") {
+			return fmt.Errorf("--expect: %s: rule needs ips, cname or match", domain)
+		}
+		for _, s := range rule.IPs {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return fmt.Errorf("--expect: %s: invalid IP %q", domain, s)
+			}
+			rule.ips = append(rule.ips, ip)
+		}
+		if rule.Match != "" {
+			if rule.match, err = regexp.Compile(rule.Match); err != nil {
+				return fmt.Errorf("--expect: %s: %w", domain, err)
+			}
+		}
+		expectations[expectKey(domain)] = rule
+	}
+	return nil
+}
+
+// expectKey normalizes a domain for rule lookups
+func expectKey(domain string) string {
+	return strings.ToLower(queryName(domain))
+}
+
+// checkExpected returns why the answer r to a qtype query for domain breaks
+// its rule, or "" when it complies or the domain has none
+func checkExpected(domain string, qtype uint16, r *dns.Msg) string {
+	rule, ok := expectations[expectKey(domain)]
+	if !ok {
+		return ""
+	}
+
+	if qtype == dns.TypeA || qtype == dns.TypeAAAA {
+		var want []net.IP
+		for _, ip := range rule.ips {
+			if (ip.To4() != nil) == (qtype == dns.TypeA) {
+				want = append(want, ip)
+			}
+		}
+		if len(want) > 0 {
+			var got []net.IP
+			for _, rr := range r.Answer {
+				switch rr := rr.(type) {
+				case *dns.A:
+					got = append(got, rr.A)
+				case *dns.AAAA:
+					got = append(got, rr.AAAA)
+				}
+			}
+			if len(got) == 0 {
+				return "no addresses, expected " + joinIPs(want)
+			}
+			for _, ip := range got {
+				if !slices.ContainsFunc(want, ip.Equal) {
+					return fmt.Sprintf("unexpected address %s", ip)
+				}
+			}
+		}
+	}
+
+	if rule.CNAME != "" {
+		found := slices.ContainsFunc(r.Answer, func(rr dns.RR) bool {
+			cname, ok := rr.(*dns.CNAME)
+			return ok && strings.EqualFold(cname.Target, queryName(rule.CNAME))
+		})
+		if !found {
+			return "no CNAME to " + rule.CNAME
+		}
+	}
+
+	if rule.match != nil {
+		found := slices.ContainsFunc(r.Answer, func(rr dns.RR) bool {
+			return rule.match.MatchString(strings.TrimPrefix(rr.String(), rr.Header().String()))
+		})
+		if !found {
+			return fmt.Sprintf("no record matches %q", rule.Match)
+		}
+	}
+	return ""
+}
+
+// printMismatches lists answers that broke the --expect rules per server and
+// domain, with the first reason seen
+func printMismatches(results []*BenchmarkResult) {
+	if expectations == nil {
+		return
+	}
+	fmt.Printf("\n%s[*] Expected Answers (%d rules):%s\n\n", ColorBlue, len(expectations), ColorReset)
+
+	type mismatch struct {
+		server string
+		domain string
+		count  int
+		reason string
+	}
+	byKey := make(map[string]*mismatch)
+	for _, result := range results {
+		if result.Status != StatusMismatch {
+			continue
+		}
+		key := result.ServerAddr + "|" + result.Domain
+		if _, ok := byKey[key]; !ok {
+			byKey[key] = &mismatch{
+				server: fmt.Sprintf("%s (%s)", result.ServerName, result.ServerAddr),
+				domain: result.Domain,
+				reason: result.Error,
+			}
+		}
+		byKey[key].count++
+	}
+	if len(byKey) == 0 {
+		fmt.Printf("%s[✓] Every answer matched the expected rules%s\n", ColorGreen, ColorReset)
+		return
+	}
+
+	var mismatches []*mismatch
+	for _, m := range byKey {
+		mismatches = append(mismatches, m)
+	}
+	sort.Slice(mismatches, func(i, j int) bool {
+		if mismatches[i].server != mismatches[j].server {
+			return mismatches[i].server < mismatches[j].server
+		}
+		return mismatches[i].domain < mismatches[j].domain
+	})
+
+	fmt.Printf("%s%-30s | %-25s | %-10s | %s%s\n", ColorWhite, "Server", "Domain", "Mismatches", "Reason", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼───────────────────────────┼────────────┼──────────────", ColorReset)
+	for _, m := range mismatches {
+		fmt.Printf("%-30s | %-25s | %s%10d%s | %s\n", m.server, m.domain, ColorRed, m.count, ColorReset, m.reason)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Failover strategies for the primary/secondary pair
+const (
+	FailoverRace       = "race"
+	FailoverSequential = "sequential"
+)
+
+// pairAttempt is the outcome of resolving one name through a server pair
+type pairAttempt struct {
+	ServerName  string
+	RTT         time.Duration
+	Answered    bool
+	BySecondary bool
+	FailedOver  bool
+}
+
+// runFailover treats each provider's primary and secondary as one unit, the
+// way stub resolvers do, and reports the pair's effective latency and availability
+func runFailover(config *BenchmarkConfig) {
+	fmt.Printf("%s[*] Simulating primary/secondary failover (%s)...%s\n", ColorBlue, config.FailoverStrategy, ColorReset)
+	if config.FailoverStrategy == FailoverSequential {
+		fmt.Printf("%s    Secondary is tried after %v without an answer from primary%s\n", ColorCyan, config.FailoverTimeout, ColorReset)
+	}
+	fmt.Printf("\n")
+
+	type pairJob struct {
+		server *DNSServer
+		domain string
+	}
+
+	var jobs []pairJob
+	for _, server := range config.Servers {
+		if len(server.endpoints(config.Transport)) < 2 {
+			fmt.Printf("%s[!] %s has no secondary address, skipped%s\n", ColorYellow, server.Name, ColorReset)
+			continue
+		}
+		for _, domain := range config.Domains {
+			for i := 0; i < config.QueryNum; i++ {
+				jobs = append(jobs, pairJob{server, domain})
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	var attemptsMu sync.Mutex
+	attempts := make(map[string][]*pairAttempt)
+	jobChan := make(chan pairJob)
+
+	for w := 0; w < config.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobChan {
+				var attempt *pairAttempt
+				if config.FailoverStrategy == FailoverRace {
+					attempt = racePair(job.server, config.Transport, job.domain)
+				} else {
+					attempt = failoverPair(job.server, config.Transport, job.domain, config.FailoverTimeout)
+				}
+				attemptsMu.Lock()
+				attempts[job.server.Name] = append(attempts[job.server.Name], attempt)
+				attemptsMu.Unlock()
+			}
+		}()
+	}
+	for _, job := range jobs {
+		jobChan <- job
+	}
+	close(jobChan)
+	wg.Wait()
+
+	printFailover(config.FailoverStrategy, attempts)
+}
+
+// resolvesOK reports whether addr answered the A query for domain successfully
+func resolvesOK(ctx context.Context, transport string, addr string, domain string) bool {
+	m := &dns.Msg{}
+	m.SetQuestion(queryName(domain), dns.TypeA)
+	r, err := exchange(ctx, m, transport, addr)
+	return err == nil && r != nil && r.Rcode == dns.RcodeSuccess && len(r.Answer) > 0
+}
+
+// racePair queries primary and secondary at once; the first good answer wins
+func racePair(server *DNSServer, transport string, domain string) *pairAttempt {
+	addrs := server.endpoints(transport)
+	// The race lasts as long as the more patient of the two timeouts
+	var timeout time.Duration
+	for _, addr := range addrs {
+		timeout = max(timeout, timeoutFor(addr))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	type answer struct {
+		secondary bool
+		ok        bool
+	}
+	answers := make(chan answer, len(addrs))
+
+	start := time.Now()
+	for i, addr := range addrs {
+		go func(secondary bool, addr string) {
+			answers <- answer{secondary, resolvesOK(ctx, transport, addr, domain)}
+		}(i > 0, addr)
+	}
+
+	attempt := &pairAttempt{ServerName: server.Name}
+	for range addrs {
+		a := <-answers
+		if a.ok {
+			attempt.RTT = time.Since(start)
+			attempt.Answered = true
+			attempt.BySecondary = a.secondary
+			return attempt
+		}
+	}
+	attempt.RTT = time.Since(start)
+	return attempt
+}
+
+// failoverPair queries primary and only falls back to secondary when primary
+// fails or stays silent for the failover timeout
+func failoverPair(server *DNSServer, transport string, domain string, failoverTimeout time.Duration) *pairAttempt {
+	addrs := server.endpoints(transport)
+	attempt := &pairAttempt{ServerName: server.Name}
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), failoverTimeout)
+	ok := resolvesOK(ctx, transport, addrs[0], domain)
+	cancel()
+	if ok {
+		attempt.RTT = time.Since(start)
+		attempt.Answered = true
+		return attempt
+	}
+
+	attempt.FailedOver = true
+	ctx, cancel = context.WithTimeout(context.Background(), timeoutFor(addrs[1]))
+	ok = resolvesOK(ctx, transport, addrs[1], domain)
+	cancel()
+	attempt.RTT = time.Since(start)
+	attempt.Answered = ok
+	attempt.BySecondary = ok
+	return attempt
+}
+
+func printFailover(strategy string, attempts map[string][]*pairAttempt) {
+	type pairStat struct {
+		name         string
+		avg          time.Duration
+		p50, p95     time.Duration
+		availability float64
+		bySecondary  float64
+		failedOver   float64
+	}
+
+	var statsList []pairStat
+	for name, list := range attempts {
+		var rtts []time.Duration
+		var total time.Duration
+		answered, bySecondary, failedOver := 0, 0, 0
+		for _, a := range list {
+			if a.Answered {
+				answered++
+				rtts = append(rtts, a.RTT)
+				total += a.RTT
+			}
+			if a.BySecondary {
+				bySecondary++
+			}
+			if a.FailedOver {
+				failedOver++
+			}
+		}
+
+		stat := pairStat{
+			name:         name,
+			p50:          percentile(rtts, 50),
+			p95:          percentile(rtts, 95),
+			availability: float64(answered) / float64(len(list)) * 100,
+			bySecondary:  float64(bySecondary) / float64(len(list)) * 100,
+			failedOver:   float64(failedOver) / float64(len(list)) * 100,
+		}
+		if answered > 0 {
+			stat.avg = total / time.Duration(answered)
+		}
+		statsList = append(statsList, stat)
+	}
+
+	sort.Slice(statsList, func(i, j int) bool {
+		return statsList[i].avg < statsList[j].avg
+	})
+
+	fmt.Printf("\n%s[*] Failover Strategy Latency (%s, sorted by average):%s\n\n", ColorBlue, strategy, ColorReset)
+	fmt.Printf("%s%-20s | %-12s | %-12s | %-12s | %-12s | %-12s | %-11s%s\n",
+		ColorWhite, "Provider", "Avg RTT", "p50 RTT", "p95 RTT", "Availability", "By Secondary", "Failed Over", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "─────────────────────┼──────────────┼──────────────┼──────────────┼──────────────┼──────────────┼────────────", ColorReset)
+
+	for _, stat := range statsList {
+		availabilityColor := ColorGreen
+		if stat.availability < 100 {
+			availabilityColor = ColorRed
+		}
+		fmt.Printf("%-20s | %8.2f ms | %8.2f ms | %8.2f ms | %s%11.1f%%%s | %11.1f%% | %10.1f%%\n",
+			stat.name, ms(stat.avg), ms(stat.p50), ms(stat.p95),
+			availabilityColor, stat.availability, ColorReset,
+			stat.bySecondary, stat.failedOver,
+		)
+	}
+	fmt.Printf("\n")
+}
@@ -2,12 +2,16 @@ module dnsbench
 
 go 1.25
 
-require github.com/miekg/dns v1.1.69
+require (
+	github.com/miekg/dns v1.1.69
+	golang.org/x/net v0.47.0
+	golang.org/x/sys v0.38.0
+)
 
 require (
+	golang.org/x/crypto v0.44.0 // indirect
 	golang.org/x/mod v0.30.0 // indirect
-	golang.org/x/net v0.47.0 // indirect
 	golang.org/x/sync v0.18.0 // indirect
-	golang.org/x/sys v0.38.0 // indirect
+	golang.org/x/text v0.31.0 // indirect
 	golang.org/x/tools v0.39.0 // indirect
 )
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Metrics offered to the Grafana JSON datasource
+const (
+	MetricRTT     = "rtt"     // average RTT of successful queries in ms
+	MetricSuccess = "success" // success rate in percent
+)
+
+// seriesValue returns a point's value for metric, or false when the point
+// has nothing to show (no successful query to time)
+func seriesValue(point seriesPoint, metric string) (float64, bool) {
+	switch metric {
+	case MetricSuccess:
+		if point.Total == 0 {
+			return 0, false
+		}
+		return float64(point.Success) / float64(point.Total) * 100, true
+	default:
+		if point.Success == 0 {
+			return 0, false
+		}
+		return ms(point.avg()), true
+	}
+}
+
+// resolverNames returns the monitored resolvers, sorted
+func (s *monitorStats) resolverNames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := slices.Clone(s.order)
+	slices.Sort(names)
+	return names
+}
+
+// grafanaQuery is the body of a JSON datasource /query request
+type grafanaQuery struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	IntervalMs int64 `json:"intervalMs"`
+	Targets    []struct {
+		Target  string          `json:"target"`
+		Hide    bool            `json:"hide"`
+		Payload json.RawMessage `json:"payload"`
+	} `json:"targets"`
+}
+
+// grafanaSeries is one time series of a /query response; datapoints are
+// [value, unix milliseconds] pairs
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// handleGrafanaMetrics lists the metrics and the resolver filter for the
+// query editor of the JSON datasource
+func (srv *monitorServer) handleGrafanaMetrics(w http.ResponseWriter, r *http.Request) {
+	options := []map[string]string{}
+	for _, name := range srv.stats.resolverNames() {
+		options = append(options, map[string]string{"label": name, "value": name})
+	}
+	payloads := []map[string]any{{"label": "Resolver", "name": "resolver", "type": "select", "placeholder": "all resolvers", "options": options}}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode([]map[string]any{
+		{"label": "Average RTT (ms)", "value": MetricRTT, "payloads": payloads},
+		{"label": "Success rate (%)", "value": MetricSuccess, "payloads": payloads},
+	})
+}
+
+// handleGrafanaQuery returns one series per resolver and requested metric,
+// bucketed to the panel's interval
+func (srv *monitorServer) handleGrafanaQuery(w http.ResponseWriter, r *http.Request) {
+	var query grafanaQuery
+	if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+		http.Error(w, fmt.Sprintf("invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+	if query.Range.To.IsZero() {
+		query.Range.To = time.Now()
+	}
+	step := time.Duration(query.IntervalMs) * time.Millisecond
+
+	series := []grafanaSeries{}
+	for _, target := range query.Targets {
+		if target.Hide {
+			continue
+		}
+		metric := target.Target
+		if metric != MetricRTT && metric != MetricSuccess {
+			http.Error(w, fmt.Sprintf("unknown metric %q (want %s or %s)", metric, MetricRTT, MetricSuccess), http.StatusBadRequest)
+			return
+		}
+		// The payload is an object in current datasource versions; a
+		// malformed one just selects every resolver
+		var payload struct {
+			Resolver string `json:"resolver"`
+		}
+		json.Unmarshal(target.Payload, &payload)
+
+		for _, name := range srv.stats.resolverNames() {
+			if payload.Resolver != "" && payload.Resolver != name {
+				continue
+			}
+			label := name
+			if len(query.Targets) > 1 {
+				label = name + " " + metric
+			}
+			s := grafanaSeries{Target: label, Datapoints: [][2]float64{}}
+			for _, point := range srv.stats.seriesRange(name, query.Range.From, query.Range.To, step) {
+				if value, ok := seriesValue(point, metric); ok {
+					s.Datapoints = append(s.Datapoints, [2]float64{value, float64(point.At.UnixMilli())})
+				}
+			}
+			series = append(series, s)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(series)
+}
+
+// SeriesRow is one resolver in one round, the flat table served to the
+// Infinity datasource and other generic JSON consumers
+type SeriesRow struct {
+	Time        time.Time `json:"time"`
+	Resolver    string    `json:"resolver"`
+	Queries     int       `json:"queries"`
+	SuccessRate float64   `json:"success_rate"`
+	AvgRTT      *float64  `json:"avg_rtt_ms"` // null when no query succeeded
+}
+
+// handleSeries serves the retained round points as rows; from and to are
+// unix milliseconds (Grafana's ${__from} and ${__to}) or RFC 3339 times
+func (srv *monitorServer) handleSeries(w http.ResponseWriter, r *http.Request) {
+	from, err := seriesTime(r.URL.Query().Get("from"), time.Time{})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := seriesTime(r.URL.Query().Get("to"), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rows := []SeriesRow{}
+	for _, name := range srv.stats.resolverNames() {
+		for _, point := range srv.stats.seriesRange(name, from, to, 0) {
+			row := SeriesRow{Time: point.At, Resolver: name, Queries: point.Total}
+			row.SuccessRate, _ = seriesValue(point, MetricSuccess)
+			if rtt, ok := seriesValue(point, MetricRTT); ok {
+				row.AvgRTT = &rtt
+			}
+			rows = append(rows, row)
+		}
+	}
+	slices.SortStableFunc(rows, func(a, b SeriesRow) int { return a.Time.Compare(b.Time) })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rows)
+}
+
+// seriesTime parses a from/to parameter, returning fallback when it is empty
+func seriesTime(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(millis), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: want unix milliseconds or RFC 3339", value)
+	}
+	return t, nil
+}
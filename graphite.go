@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// graphiteTimeout bounds connecting to and writing to the carbon receiver
+const graphiteTimeout = 5 * time.Second
+
+var (
+	// graphiteAddr is the carbon plaintext receiver; empty when disabled
+	graphiteAddr string
+	// graphitePrefix is prepended to every metric path
+	graphitePrefix string
+)
+
+// configureGraphite validates the receiver address (host:port, port 2003 if omitted)
+func configureGraphite(addr string, prefix string) error {
+	if addr == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "2003")
+	}
+	if _, err := net.ResolveTCPAddr("tcp", addr); err != nil {
+		return fmt.Errorf("invalid --graphite %q: %v", addr, err)
+	}
+	graphiteAddr = addr
+	graphitePrefix = strings.Trim(prefix, ".")
+	return nil
+}
+
+// graphiteNode turns a resolver name or address into a single path node
+func graphiteNode(value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(value) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return strings.Trim(b.String(), "_")
+}
+
+// graphiteLines aggregates results per server endpoint into plaintext
+// protocol lines: <prefix>.<resolver>.<endpoint>.<metric> <value> <timestamp>
+func graphiteLines(results []*BenchmarkResult, now time.Time) []string {
+	statsList := summarizeServers(results)
+	sort.Slice(statsList, func(i, j int) bool {
+		if statsList[i].ServerName != statsList[j].ServerName {
+			return statsList[i].ServerName < statsList[j].ServerName
+		}
+		return statsList[i].ServerAddr < statsList[j].ServerAddr
+	})
+
+	timestamp := now.Unix()
+	var lines []string
+	for _, stats := range statsList {
+		path := graphitePrefix + "." + graphiteNode(stats.ServerName) + "." + graphiteNode(stats.ServerAddr)
+		metric := func(name string, value float64) {
+			lines = append(lines, fmt.Sprintf("%s.%s %g %d", path, name, value, timestamp))
+		}
+		metric("queries", float64(stats.TotalQueries))
+		metric("failures", float64(stats.TotalQueries-stats.SuccessQueries))
+		metric("success_rate", stats.SuccessRate())
+		if stats.SuccessQueries == 0 {
+			continue
+		}
+		metric("rtt_avg_ms", ms(stats.AvgRTT))
+		metric("rtt_min_ms", ms(stats.MinRTT))
+		metric("rtt_p50_ms", ms(percentile(stats.rtts, 50)))
+		metric("rtt_p95_ms", ms(percentile(stats.rtts, 95)))
+		metric("rtt_max_ms", ms(stats.MaxRTT))
+	}
+	return lines
+}
+
+// exportGraphite pushes aggregated metrics of results over the carbon
+// plaintext protocol
+func exportGraphite(results []*BenchmarkResult) error {
+	if graphiteAddr == "" || len(results) == 0 {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", graphiteAddr, graphiteTimeout)
+	if err != nil {
+		return fmt.Errorf("graphite: %w", err)
+	}
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(graphiteTimeout))
+
+	w := bufio.NewWriter(conn)
+	for _, line := range graphiteLines(results, time.Now()) {
+		w.WriteString(line + "\n")
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("graphite: %w", err)
+	}
+	return nil
+}
+
+// reportGraphite exports results and prints the outcome
+func reportGraphite(results []*BenchmarkResult) {
+	if graphiteAddr == "" {
+		return
+	}
+	if err := exportGraphite(results); err != nil {
+		slog.Warn("Export failed", "err", err)
+		return
+	}
+	fmt.Printf("%s[✓] Pushed Graphite metrics to %s under %s.*%s\n", ColorGreen, graphiteAddr, graphitePrefix, ColorReset)
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultRetention is how long the history keeps runs unless --keep says otherwise
+const defaultRetention = "30d"
+
+// historyIDLayout names history files after the time the run was saved
+const historyIDLayout = "20060102-150405"
+
+// historyDir returns the run history directory, ~/.dnsbench/history
+func historyDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".dnsbench", "history"), nil
+}
+
+// retention is how much of the history to keep: runs younger than age, or
+// the latest runs; zero keeps everything
+type retention struct {
+	age  time.Duration
+	runs int
+}
+
+// parseRetention reads a --keep value: an age such as 30d or 12h, a number
+// of runs such as 100, or 0 for no pruning
+func parseRetention(value string) (retention, error) {
+	value = strings.TrimSpace(value)
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+		return retention{runs: n}, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return retention{age: time.Duration(n) * 24 * time.Hour}, nil
+		}
+	} else if age, err := time.ParseDuration(value); err == nil && age >= 0 {
+		return retention{age: age}, nil
+	}
+	return retention{}, fmt.Errorf("invalid --keep %q: want an age such as 30d or 12h, a number of runs, or 0", value)
+}
+
+// historyEntry is one saved run in the history directory
+type historyEntry struct {
+	ID      string
+	Path    string
+	ModTime time.Time
+}
+
+// historyEntries lists the saved runs, oldest first
+func historyEntries(dir string) ([]historyEntry, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []historyEntry
+	for _, file := range files {
+		id, ok := strings.CutSuffix(file.Name(), ".json")
+		if !ok || file.IsDir() {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, historyEntry{ID: id, Path: filepath.Join(dir, file.Name()), ModTime: info.ModTime()})
+	}
+	// IDs are timestamps, so they sort by age
+	slices.SortFunc(entries, func(a, b historyEntry) int { return strings.Compare(a.ID, b.ID) })
+	return entries, nil
+}
+
+// recordHistory saves a finished run to the history directory and prunes
+// runs that fall out of keep; it returns the path written
+func recordHistory(config *BenchmarkConfig, results []*BenchmarkResult, keep retention) (string, error) {
+	dir, err := historyDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	id := now().Format(historyIDLayout)
+	path := filepath.Join(dir, id+".json")
+	// Two runs finishing within the same second get a suffix
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.json", id, n))
+	}
+	if err := saveResults(path, config, results); err != nil {
+		return "", err
+	}
+	if _, err := pruneHistory(dir, keep); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+// pruneHistory removes the runs keep does not cover and returns how many
+// were removed
+func pruneHistory(dir string, keep retention) (int, error) {
+	if keep == (retention{}) {
+		return 0, nil
+	}
+	entries, err := historyEntries(dir)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	cutoff := time.Now().Add(-keep.age)
+	for i, entry := range entries {
+		expired := keep.age > 0 && entry.ModTime.Before(cutoff)
+		if keep.runs > 0 && i < len(entries)-keep.runs {
+			expired = true
+		}
+		if !expired {
+			continue
+		}
+		if err := os.Remove(entry.Path); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// runHistory implements "dnsbench history [list|show RUN|prune]"
+func runHistory(args []string) {
+	sub := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		sub, args = args[0], args[1:]
+	}
+	dir, err := historyDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	switch sub {
+	case "list":
+		runHistoryList(dir, args)
+	case "show":
+		runHistoryShow(dir, args)
+	case "prune":
+		runHistoryPrune(dir, args)
+	default:
+		fmt.Fprintf(os.Stderr, "%s[!] Unknown history command %q (want list, show or prune)%s\n", ColorRed, sub, ColorReset)
+		os.Exit(2)
+	}
+}
+
+// runHistoryList prints one line per saved run, newest first
+func runHistoryList(dir string, args []string) {
+	fs := flag.NewFlagSet("history list", flag.ExitOnError)
+	label := fs.String("label", "", "only list runs saved with this --label")
+	limit := fs.Int("n", 20, "number of runs to list (0 = all)")
+	fs.Parse(args)
+
+	entries, err := historyEntries(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	if len(entries) == 0 {
+		fmt.Printf("%s[*] No runs in %s yet%s\n", ColorBlue, dir, ColorReset)
+		return
+	}
+
+	fmt.Printf("%s[*] Run history (%s):%s\n\n", ColorBlue, dir, ColorReset)
+	fmt.Printf("%s%-4s | %-17s | %-16s | %-10s | %-9s | %-7s | %-8s | %-30s | %s%s\n",
+		ColorWhite, "#", "ID", "Saved", "Mode", "Transport", "Queries", "Success", "Fastest", "Labels", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "─────┼───────────────────┼──────────────────┼────────────┼───────────┼─────────┼──────────┼────────────────────────────────┼───────", ColorReset)
+	listed := 0
+	for i := len(entries) - 1; i >= 0 && (*limit == 0 || listed < *limit); i-- {
+		number := len(entries) - i
+		file, err := loadResults(entries[i].Path)
+		if err != nil {
+			fmt.Printf("%-4d | %-17s | %s%v%s\n", number, entries[i].ID, ColorRed, err, ColorReset)
+			listed++
+			continue
+		}
+		if *label != "" && !slices.Contains(file.Labels, *label) {
+			continue
+		}
+		total, success := 0, 0
+		fastest := "-"
+		for _, stats := range aggregate(file.Results).serverStats() {
+			total += stats.TotalQueries
+			success += stats.SuccessQueries
+			if fastest == "-" && stats.SuccessQueries > 0 {
+				fastest = fmt.Sprintf("%s (%.1f ms)", stats.ServerName, ms(stats.AvgRTT))
+			}
+		}
+		rate := 0.0
+		if total > 0 {
+			rate = float64(success) / float64(total) * 100
+		}
+		fmt.Printf("%-4d | %-17s | %-16s | %-10s | %-9s | %7d | %7.1f%% | %-30s | %s\n",
+			number, entries[i].ID, file.SavedAt.In(timeZone).Format("2006-01-02 15:04"), file.Mode, strings.ToUpper(file.Transport),
+			total, rate, fastest, strings.Join(file.Labels, ", "))
+		listed++
+	}
+	fmt.Printf("\n%s    Show a run with history show # or ID; its file works with report, export and compare%s\n", ColorCyan, ColorReset)
+}
+
+// findHistory resolves a run given as its number in history list (1 is the
+// latest), its ID or "latest"
+func findHistory(dir string, run string) (historyEntry, error) {
+	entries, err := historyEntries(dir)
+	if err != nil {
+		return historyEntry{}, err
+	}
+	if len(entries) == 0 {
+		return historyEntry{}, fmt.Errorf("no runs in %s yet", dir)
+	}
+	if run == "latest" {
+		return entries[len(entries)-1], nil
+	}
+	if n, err := strconv.Atoi(run); err == nil && len(run) < 8 {
+		if n < 1 || n > len(entries) {
+			return historyEntry{}, fmt.Errorf("no run #%d (history has %d)", n, len(entries))
+		}
+		return entries[len(entries)-n], nil
+	}
+	for _, entry := range entries {
+		if entry.ID == run {
+			return entry, nil
+		}
+	}
+	return historyEntry{}, fmt.Errorf("no run %q in %s", run, dir)
+}
+
+// runHistoryShow prints the summary of one saved run, like report does
+func runHistoryShow(dir string, args []string) {
+	fs := flag.NewFlagSet("history show", flag.ExitOnError)
+	pathOnly := fs.Bool("path", false, "print only the run's results file, e.g. for compare $(dnsbench history show --path 2) ...")
+	args = parseInterspersed(fs, args)
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "%s[!] usage: dnsbench history show [--path] [# | ID | latest]%s\n", ColorRed, ColorReset)
+		os.Exit(2)
+	}
+	run := "latest"
+	if len(args) == 1 {
+		run = args[0]
+	}
+	entry, err := findHistory(dir, run)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	if *pathOnly {
+		fmt.Println(entry.Path)
+		return
+	}
+	file, err := loadResults(entry.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s[*] Run %s (%s), saved %s%s\n", ColorBlue, entry.ID, strings.ToUpper(file.Transport), file.SavedAt.In(timeZone).Format("2006-01-02 15:04:05"), ColorReset)
+	fmt.Printf("    File: %s\n", entry.Path)
+	if len(file.Labels) > 0 {
+		fmt.Printf("    Labels: %s\n", strings.Join(file.Labels, ", "))
+	}
+	if env := file.environment(); env != nil {
+		fmt.Printf("    Environment: %s\n", env)
+	}
+	printResults(file.Results, aggregate(file.Results), false)
+}
+
+// runHistoryPrune removes old runs by hand, e.g. after lowering --keep
+func runHistoryPrune(dir string, args []string) {
+	fs := flag.NewFlagSet("history prune", flag.ExitOnError)
+	keepFlag := fs.String("keep", defaultRetention, "keep runs younger than this age (e.g. 30d, 12h) or the latest N runs")
+	fs.Parse(args)
+	keep, err := parseRetention(*keepFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(2)
+	}
+	removed, err := pruneHistory(dir, keep)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	fmt.Printf("%s[✓] Removed %d runs from %s%s\n", ColorGreen, removed, dir, ColorReset)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OutputHTML renders the summary as a self-contained HTML page
+const OutputHTML = "html"
+
+// htmlReport is the page layout for --output html; it needs no external
+// assets so the file can be mailed or attached to a ticket as is
+var htmlReport = htmltemplate.Must(htmltemplate.New("report").Funcs(htmltemplate.FuncMap{
+	"ms": func(d time.Duration) string {
+		return fmt.Sprintf("%.2f ms", ms(d))
+	},
+	"pct": func(v float64) string {
+		return fmt.Sprintf("%.1f%%", v)
+	},
+	"apdex": func(v float64) string {
+		rating, _ := apdexRating(v)
+		return fmt.Sprintf("%.2f (%s)", v, rating)
+	},
+	"inc":           func(i int) int { return i + 1 },
+	"apdexT":        func() string { return apdexThreshold.String() },
+	"displayDomain": displayDomain,
+	"join":          strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>DNS Benchmark Results</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.7em; }
+th { background: #f3f3f3; text-align: left; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+tr.fail td { color: #b00; }
+.meta { color: #666; }
+table.heatmap td { text-align: center; font-size: 0.85em; font-variant-numeric: tabular-nums; }
+</style>
+</head>
+<body>
+<h1>DNS Benchmark Results</h1>
+<p class="meta">Transport: {{.Transport}} · Mode: {{.Mode}} · {{.GeneratedAt.Format "2006-01-02 15:04 MST"}}{{with .Metadata}}{{if .PublicIP}} · measured from {{.String}}{{end}}{{end}}{{with .Labels}} · labels: {{join . ", "}}{{end}}{{with .Metadata}}{{with .Environment}} · {{.String}}{{end}}{{end}}</p>
+{{with .Sources}}<p>Merged from:</p>
+<ul>
+{{range .}}<li><b>{{.Label}}</b>: {{.Transport}}, {{.SavedAt.Format "2006-01-02 15:04 MST"}}{{with .Metadata}}{{if .PublicIP}} · measured from {{.String}}{{end}}{{end}}{{with .Labels}} · labels: {{join . ", "}}{{end}}{{with .Metadata}}{{with .Environment}} · {{.String}}{{end}}{{end}}</li>
+{{end}}</ul>
+{{end}}
+<h2>Servers</h2>
+<table>
+<tr><th>#</th><th>Server</th><th>Address</th><th>Min RTT</th><th>Avg RTT</th><th>95% CI</th><th>Max RTT</th><th>Success Rate</th><th>Under {{apdexT}}</th><th>Apdex</th></tr>
+{{range $i, $s := .Servers}}<tr{{if lt $s.SuccessRate 100.0}} class="fail"{{end}}><td class="num">{{inc $i}}</td><td>{{$s.ServerName}}</td><td><code>{{$s.ServerAddr}}</code></td><td class="num">{{ms $s.MinRTT}}</td><td class="num">{{ms $s.AvgRTT}}</td><td class="num">± {{ms $s.CI95}}</td><td class="num">{{ms $s.MaxRTT}}</td><td class="num">{{pct $s.SuccessRate}}</td><td class="num">{{pct $s.UnderThreshold}}</td><td>{{apdex $s.Apdex}}</td></tr>
+{{end}}</table>
+
+<h2>Domains</h2>
+<table>
+<tr><th>Domain</th><th>Avg RTT</th><th>Success Rate</th></tr>
+{{range .Domains}}<tr><td>{{displayDomain .Domain}}</td><td class="num">{{ms .AvgRTT}}</td><td class="num">{{pct .SuccessRate}}</td></tr>
+{{end}}</table>
+{{with .Heatmap}}
+<h2>Average RTT by Hour of Day (ms)</h2>
+<p class="meta">Hours in {{.Zone}}; green is the fastest hour in the map, red the slowest, grey had no answers. Hover a cell for its queries and loss.</p>
+<table class="heatmap">
+<tr><th>Server</th>{{range .Hours}}<th>{{printf "%02d" .}}</th>{{end}}</tr>
+{{range .Rows}}<tr><td>{{.Server}}</td>{{range .Cells}}{{if .Queries}}<td style="background: {{.Color}}" title="{{.Queries}} queries, {{pct .Loss}} loss">{{if .Success}}{{printf "%.0f" .RTT}}{{else}}-{{end}}</td>{{else}}<td></td>{{end}}{{end}}</tr>
+{{end}}</table>
+{{end}}
+<p class="meta">{{len .Results}} queries</p>
+</body>
+</html>
+`))
+
+// writeHTML renders the report as an HTML page
+func writeHTML(w io.Writer, report *Report) error {
+	return htmlReport.Execute(w, report)
+}
+
+// Heatmap is the hour-of-day × resolver view of monitor results, which makes
+// peak-hour throttling by an ISP visible at a glance
+type Heatmap struct {
+	Zone  string
+	Hours []int
+	Rows  []HeatmapRow
+}
+
+// HeatmapRow holds one server's cells, one per entry of Heatmap.Hours
+type HeatmapRow struct {
+	Server string
+	Cells  []*HeatmapCell
+}
+
+// HeatmapCell is one server's average RTT in one hour of the day
+type HeatmapCell struct {
+	Queries int
+	Success int
+	RTT     float64 // average in milliseconds
+	Loss    float64 // percentage of queries not answered
+	Color   htmltemplate.CSS
+
+	rttTotal time.Duration
+}
+
+// Heatmap groups monitor results by hour of day and server; it returns nil
+// for other modes and for monitor data within a single hour
+func (r *Report) Heatmap() *Heatmap {
+	if r.Mode != ModeMonitor || len(r.Results) == 0 {
+		return nil
+	}
+	first, last := r.Results[0].Timestamp, r.Results[0].Timestamp
+	cells := make(map[string]*[24]HeatmapCell)
+	var seen [24]bool
+	for _, result := range r.Results {
+		if result.Timestamp.Before(first) {
+			first = result.Timestamp
+		}
+		if result.Timestamp.After(last) {
+			last = result.Timestamp
+		}
+		key := result.ServerName + " - " + result.ServerAddr
+		if cells[key] == nil {
+			cells[key] = new([24]HeatmapCell)
+		}
+		hour := result.Timestamp.In(timeZone).Hour()
+		seen[hour] = true
+		cell := &cells[key][hour]
+		cell.Queries++
+		if result.Status == "SUCCESS" {
+			cell.Success++
+			cell.rttTotal += result.RTT
+		}
+	}
+
+	heatmap := &Heatmap{Zone: timeZone.String()}
+	for hour, ok := range seen {
+		if ok {
+			heatmap.Hours = append(heatmap.Hours, hour)
+		}
+	}
+	if len(heatmap.Hours) < 2 || last.Sub(first) < time.Hour {
+		return nil
+	}
+
+	fastest, slowest := -1.0, 0.0
+	for _, hours := range cells {
+		for i := range hours {
+			cell := &hours[i]
+			if cell.Queries == 0 {
+				continue
+			}
+			cell.Loss = float64(cell.Queries-cell.Success) / float64(cell.Queries) * 100
+			if cell.Success == 0 {
+				continue
+			}
+			cell.RTT = ms(cell.rttTotal / time.Duration(cell.Success))
+			if fastest < 0 || cell.RTT < fastest {
+				fastest = cell.RTT
+			}
+			slowest = max(slowest, cell.RTT)
+		}
+	}
+
+	servers := make([]string, 0, len(cells))
+	for _, stats := range r.Servers {
+		servers = append(servers, stats.ServerName+" - "+stats.ServerAddr)
+	}
+	if len(servers) != len(cells) {
+		servers = servers[:0]
+		for key := range cells {
+			servers = append(servers, key)
+		}
+		sort.Strings(servers)
+	}
+	for _, key := range servers {
+		hours := cells[key]
+		if hours == nil {
+			continue
+		}
+		name, addr, _ := strings.Cut(key, " - ")
+		row := HeatmapRow{Server: fmt.Sprintf("%s (%s)", name, addr)}
+		for _, hour := range heatmap.Hours {
+			cell := &hours[hour]
+			cell.Color = heatColor(cell, fastest, slowest)
+			row.Cells = append(row.Cells, cell)
+		}
+		heatmap.Rows = append(heatmap.Rows, row)
+	}
+	return heatmap
+}
+
+// heatColor shades a cell from green (fastest) to red (slowest)
+func heatColor(cell *HeatmapCell, fastest, slowest float64) htmltemplate.CSS {
+	if cell.Success == 0 {
+		return "#ddd"
+	}
+	var f float64
+	if slowest > fastest {
+		f = (cell.RTT - fastest) / (slowest - fastest)
+	}
+	return htmltemplate.CSS(fmt.Sprintf("hsl(%.0f, 70%%, 75%%)", 120*(1-f)))
+}
@@ -0,0 +1,504 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2/hpack"
+	"golang.org/x/net/quic"
+)
+
+// HTTP/3 frame and stream types (RFC 9114)
+const (
+	h3FrameData     = 0x00
+	h3FrameHeaders  = 0x01
+	h3FrameSettings = 0x04
+	h3StreamControl = 0x00
+
+	// h3MaxFrame bounds the frames read; DoH responses fit in 64 KiB
+	h3MaxFrame = 1 << 16
+)
+
+// qpackStatic holds the QPACK static table entries (RFC 9204 appendix A)
+// for the fields a DoH response is read by, :status and content-type;
+// references to other entries are parsed and skipped
+var qpackStatic = map[uint64][2]string{
+	24: {":status", "103"}, 25: {":status", "200"}, 26: {":status", "304"},
+	27: {":status", "404"}, 28: {":status", "503"},
+	44: {"content-type", "application/dns-message"}, 45: {"content-type", "application/javascript"},
+	46: {"content-type", "application/json"}, 47: {"content-type", "application/x-www-form-urlencoded"},
+	48: {"content-type", "image/gif"}, 49: {"content-type", "image/jpeg"}, 50: {"content-type", "image/png"},
+	51: {"content-type", "text/css"}, 52: {"content-type", "text/html; charset=utf-8"},
+	53: {"content-type", "text/plain"}, 54: {"content-type", "text/plain;charset=utf-8"},
+	63: {":status", "100"}, 64: {":status", "204"}, 65: {":status", "206"},
+	66: {":status", "302"}, 67: {":status", "400"}, 68: {":status", "403"},
+	69: {":status", "421"}, 70: {":status", "425"}, 71: {":status", "500"},
+}
+
+// h3Transport is a minimal HTTP/3 client for DoH: one QUIC connection per
+// host, one request stream per query, no dynamic QPACK table and no server
+// push. It implements http.RoundTripper, so DoH queries over HTTP/3 share
+// exchangeDoHWith with the other protocol versions.
+type h3Transport struct {
+	mu    sync.Mutex
+	conns map[string]*h3Conn // by host:port
+}
+
+// h3Conn is an open HTTP/3 connection
+type h3Conn struct {
+	endpoint *quic.Endpoint
+	conn     *quic.Conn
+	control  *quic.Stream // must stay open while the connection is used
+}
+
+func newH3Transport() *h3Transport {
+	return &h3Transport{conns: make(map[string]*h3Conn)}
+}
+
+// RoundTrip sends req on a new stream of the host's connection, dialing
+// it first if needed; a failed request closes the connection
+func (t *h3Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("http3: unsupported scheme %q", req.URL.Scheme)
+	}
+	addr := req.URL.Host
+	if req.URL.Port() == "" {
+		addr = net.JoinHostPort(req.URL.Hostname(), "443")
+	}
+	c, err := t.conn(req.Context(), addr)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.roundTrip(req)
+	if err != nil {
+		t.drop(addr, c)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CloseIdleConnections closes every connection; http.Client calls it
+func (t *h3Transport) CloseIdleConnections() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for addr, c := range t.conns {
+		c.close()
+		delete(t.conns, addr)
+	}
+}
+
+// conn returns the host's connection, dialing one when there is none. The
+// dial runs outside the lock so a slow server does not hold up the others;
+// of two racing dials to one host, the first to finish is kept.
+func (t *h3Transport) conn(ctx context.Context, addr string) (*h3Conn, error) {
+	t.mu.Lock()
+	c, ok := t.conns[addr]
+	t.mu.Unlock()
+	if ok {
+		return c, nil
+	}
+
+	c, err := dialH3(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if existing, ok := t.conns[addr]; ok {
+		c.close()
+		return existing, nil
+	}
+	t.conns[addr] = c
+	return c, nil
+}
+
+func (t *h3Transport) drop(addr string, c *h3Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conns[addr] == c {
+		delete(t.conns, addr)
+	}
+	c.close()
+}
+
+// dialH3 opens an HTTP/3 connection to addr within connectTimeout when set.
+// Like dialStream, hostnames pinned with --resolve or looked up through
+// --bootstrap are dialed at those addresses in order.
+func dialH3(ctx context.Context, addr string) (*h3Conn, error) {
+	if connectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, connectTimeout)
+		defer cancel()
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := bootstrapAddrs(ctx, addr)
+	if err != nil {
+		return nil, &connectError{err}
+	}
+	if targets == nil {
+		targets = []string{addr}
+	}
+	var lastErr error
+	for _, target := range targets {
+		c, err := dialH3To(ctx, host, target)
+		if err == nil {
+			return c, nil
+		}
+		lastErr = err
+	}
+	return nil, &connectError{lastErr}
+}
+
+// dialH3To opens a QUIC connection to target negotiating h3 for host, from
+// the configured source address, and sends the SETTINGS frame every HTTP/3
+// endpoint must send first on its control stream
+func dialH3To(ctx context.Context, host string, target string) (*h3Conn, error) {
+	pc, err := listenUDP(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := quic.NewEndpoint(pc, nil)
+	if err != nil {
+		pc.Close()
+		return nil, err
+	}
+	socketsOpened.Add(1)
+	conn, err := endpoint.Dial(ctx, "udp", target, &quic.Config{TLSConfig: &tls.Config{
+		ServerName: host,
+		NextProtos: []string{"h3"},
+		MinVersion: tls.VersionTLS13,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return verifyPins(host, cs.PeerCertificates)
+		},
+	}})
+	if err != nil {
+		endpoint.Close(context.Background())
+		return nil, err
+	}
+	c := &h3Conn{endpoint: endpoint, conn: conn}
+
+	if c.control, err = conn.NewSendOnlyStream(ctx); err != nil {
+		c.close()
+		return nil, err
+	}
+	control := appendVarint(nil, h3StreamControl)
+	control = appendH3Frame(control, h3FrameSettings, nil)
+	c.control.Write(control)
+	if err := c.control.Flush(); err != nil {
+		c.close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *h3Conn) close() {
+	c.conn.Abort(nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	c.endpoint.Close(ctx)
+}
+
+// roundTrip sends one request and reads its response. The body is read in
+// full, as DoH answers are small.
+func (c *h3Conn) roundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	stream, err := c.conn.NewStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream.SetReadContext(ctx)
+	stream.SetWriteContext(ctx)
+
+	fields := [][2]string{
+		{":method", req.Method},
+		{":scheme", "https"},
+		{":authority", req.URL.Host},
+		{":path", req.URL.RequestURI()},
+	}
+	for name, values := range req.Header {
+		for _, value := range values {
+			fields = append(fields, [2]string{strings.ToLower(name), value})
+		}
+	}
+	if len(body) > 0 {
+		fields = append(fields, [2]string{"content-length", strconv.Itoa(len(body))})
+	}
+	request := appendH3Frame(nil, h3FrameHeaders, encodeQPACK(fields))
+	if len(body) > 0 {
+		request = appendH3Frame(request, h3FrameData, body)
+	}
+	if _, err := stream.Write(request); err != nil {
+		stream.Reset(0x100) // H3_NO_ERROR
+		return nil, err
+	}
+	stream.CloseWrite()
+	if readTimeout > 0 {
+		// As ResponseHeaderTimeout does for HTTP/1.1 and HTTP/2
+		readCtx, cancel := context.WithTimeout(ctx, readTimeout)
+		defer cancel()
+		stream.SetReadContext(readCtx)
+	}
+
+	resp := &http.Response{
+		Proto:      "HTTP/3.0",
+		ProtoMajor: 3,
+		Header:     make(http.Header),
+		Request:    req,
+	}
+	var data bytes.Buffer
+	for {
+		ftype, payload, err := readH3Frame(stream)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			stream.CloseRead()
+			return nil, err
+		}
+		switch ftype {
+		case h3FrameHeaders:
+			// Informational (1xx) responses precede the final one;
+			// headers after it are trailers and ignored
+			if resp.StatusCode >= 200 {
+				continue
+			}
+			header := make(http.Header)
+			if err := decodeQPACK(payload, header); err != nil {
+				stream.CloseRead()
+				return nil, err
+			}
+			resp.StatusCode, _ = strconv.Atoi(header.Get(":status"))
+			header.Del(":status")
+			resp.Header = header
+		case h3FrameData:
+			if resp.StatusCode < 200 {
+				stream.CloseRead()
+				return nil, fmt.Errorf("http3: DATA before the response headers")
+			}
+			data.Write(payload)
+		}
+		// Unknown and reserved frame types are ignored (RFC 9114 section 9)
+	}
+	if resp.StatusCode < 200 {
+		return nil, fmt.Errorf("http3: stream ended without a response")
+	}
+	resp.Status = strconv.Itoa(resp.StatusCode) + " " + http.StatusText(resp.StatusCode)
+	resp.ContentLength = int64(data.Len())
+	resp.Body = io.NopCloser(&data)
+	cs := c.conn.ConnectionState()
+	resp.TLS = &cs
+	return resp, nil
+}
+
+// appendVarint appends a QUIC variable-length integer (RFC 9000 section 16)
+func appendVarint(b []byte, v uint64) []byte {
+	switch {
+	case v < 1<<6:
+		return append(b, byte(v))
+	case v < 1<<14:
+		return append(b, 0x40|byte(v>>8), byte(v))
+	case v < 1<<30:
+		return append(b, 0x80|byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+	return append(b, 0xc0|byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func readVarint(r io.ByteReader) (uint64, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	v := uint64(first & 0x3f)
+	for i := 1; i < 1<<(first>>6); i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		v = v<<8 | uint64(b)
+	}
+	return v, nil
+}
+
+func appendH3Frame(b []byte, ftype uint64, payload []byte) []byte {
+	b = appendVarint(b, ftype)
+	b = appendVarint(b, uint64(len(payload)))
+	return append(b, payload...)
+}
+
+// readH3Frame reads one frame; io.EOF means the stream ended between frames
+func readH3Frame(stream *quic.Stream) (uint64, []byte, error) {
+	ftype, err := readVarint(stream)
+	if err != nil {
+		return 0, nil, err
+	}
+	length, err := readVarint(stream)
+	if err != nil {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	if length > h3MaxFrame {
+		return 0, nil, fmt.Errorf("http3: %d byte frame exceeds %d", length, h3MaxFrame)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(stream, payload); err != nil {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	return ftype, payload, nil
+}
+
+// appendPrefixInt appends an HPACK/QPACK integer whose first byte keeps
+// flags in the bits above the n-bit prefix (RFC 7541 section 5.1)
+func appendPrefixInt(b []byte, flags byte, n uint, v uint64) []byte {
+	limit := uint64(1)<<n - 1
+	if v < limit {
+		return append(b, flags|byte(v))
+	}
+	b = append(b, flags|byte(limit))
+	for v -= limit; v >= 0x80; v >>= 7 {
+		b = append(b, byte(v)|0x80)
+	}
+	return append(b, byte(v))
+}
+
+func readPrefixInt(r *bytes.Reader, n uint) (uint64, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	limit := uint64(1)<<n - 1
+	v := uint64(first) & limit
+	if v < limit {
+		return v, nil
+	}
+	for shift := uint(0); shift < 63; shift += 7 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		v += uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("qpack: integer overflow")
+}
+
+// readQPACKString reads a string literal whose Huffman flag is the bit
+// above an n-bit length prefix
+func readQPACKString(r *bytes.Reader, n uint) (string, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	r.UnreadByte()
+	huffman := first&(1<<n) != 0
+	length, err := readPrefixInt(r, n)
+	if err != nil {
+		return "", err
+	}
+	if length > uint64(r.Len()) {
+		return "", fmt.Errorf("qpack: truncated string")
+	}
+	raw := make([]byte, length)
+	r.Read(raw)
+	if huffman {
+		return hpack.HuffmanDecodeToString(raw)
+	}
+	return string(raw), nil
+}
+
+// encodeQPACK encodes a field section with literal names and values only,
+// which needs neither the static nor a dynamic table (RFC 9204 section 4.5.6)
+func encodeQPACK(fields [][2]string) []byte {
+	// Required Insert Count and Delta Base are 0 without a dynamic table
+	b := []byte{0, 0}
+	for _, field := range fields {
+		b = appendPrefixInt(b, 0x20, 3, uint64(len(field[0])))
+		b = append(b, field[0]...)
+		b = appendPrefixInt(b, 0x00, 7, uint64(len(field[1])))
+		b = append(b, field[1]...)
+	}
+	return b
+}
+
+// decodeQPACK decodes a field section into header. The client announces
+// no dynamic table, so a reference to one is an error; static entries
+// outside qpackStatic are skipped.
+func decodeQPACK(section []byte, header http.Header) error {
+	r := bytes.NewReader(section)
+	insertCount, err := readPrefixInt(r, 8)
+	if err != nil {
+		return err
+	}
+	if _, err := readPrefixInt(r, 7); err != nil {
+		return err
+	}
+	if insertCount != 0 {
+		return fmt.Errorf("qpack: dynamic table reference without a dynamic table")
+	}
+
+	for r.Len() > 0 {
+		first, _ := r.ReadByte()
+		r.UnreadByte()
+		var name, value string
+		switch {
+		case first&0x80 != 0: // indexed field line
+			if first&0x40 == 0 {
+				return fmt.Errorf("qpack: dynamic table reference without a dynamic table")
+			}
+			index, err := readPrefixInt(r, 6)
+			if err != nil {
+				return err
+			}
+			entry := qpackStatic[index]
+			name, value = entry[0], entry[1]
+		case first&0x40 != 0: // literal field line with name reference
+			if first&0x10 == 0 {
+				return fmt.Errorf("qpack: dynamic table reference without a dynamic table")
+			}
+			index, err := readPrefixInt(r, 4)
+			if err != nil {
+				return err
+			}
+			if value, err = readQPACKString(r, 7); err != nil {
+				return err
+			}
+			name = qpackStatic[index][0]
+		case first&0x20 != 0: // literal field line with literal name
+			var err error
+			if name, err = readQPACKString(r, 3); err != nil {
+				return err
+			}
+			if value, err = readQPACKString(r, 7); err != nil {
+				return err
+			}
+		default: // post-base references only exist with a dynamic table
+			return fmt.Errorf("qpack: dynamic table reference without a dynamic table")
+		}
+		if name != "" {
+			header.Add(name, value)
+		}
+	}
+	return nil
+}
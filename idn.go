@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/idna"
+)
+
+// toASCII converts an internationalized domain name to its punycode form
+// (bücher.de → xn--bcher-kva.de); ASCII names are returned lower-cased
+func toASCII(domain string) (string, error) {
+	ascii, err := idna.Lookup.ToASCII(strings.TrimSuffix(domain, "."))
+	if err != nil {
+		return "", err
+	}
+	if _, ok := dns.IsDomainName(ascii); !ok || ascii == "" {
+		return "", fmt.Errorf("not a valid domain name")
+	}
+	return ascii, nil
+}
+
+// queryName returns the fully qualified, punycode-encoded name to put on the
+// wire, so domains can be listed and displayed in their Unicode form
+func queryName(domain string) string {
+	if ascii, err := toASCII(domain); err == nil {
+		return dns.Fqdn(ascii)
+	}
+	return dns.Fqdn(domain)
+}
+
+// displayDomain shows an IDN together with its punycode form
+func displayDomain(domain string) string {
+	ascii, err := toASCII(domain)
+	if err != nil || ascii == strings.ToLower(strings.TrimSuffix(domain, ".")) {
+		return domain
+	}
+	return domain + " (" + ascii + ")"
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// runInit implements "dnsbench init": it asks which providers, domains,
+// transport and output to use and writes a settings file that bench,
+// monitor and serve pick up from the working directory
+func runInit(args []string) {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	out := fs.String("o", defaultConfigPath, "settings file to write")
+	force := fs.Bool("force", false, "overwrite an existing settings file without asking")
+	fs.Parse(args)
+
+	catalog, err := loadInstalledCatalog()
+	if err == nil && catalog != nil {
+		useCatalog(catalog)
+	}
+
+	fmt.Printf("%s[*] dnsbench setup%s\n", ColorBlue, ColorReset)
+	fmt.Printf("    Answer each question or press Enter to keep the suggestion in [brackets].\n\n")
+	if _, err := os.Stat(*out); err == nil && !*force && !confirm(fmt.Sprintf("%s already exists. Overwrite it?", *out)) {
+		fmt.Printf("Aborted.\n")
+		return
+	}
+
+	settings := []string{
+		"# dnsbench settings, written by dnsbench init on " + time.Now().Format("2006-01-02"),
+		"# One \"flag = value\" per line; see dnsbench bench -h for every flag.",
+		"# Flags given on the command line override these.",
+		"",
+	}
+	add := func(name, value string) {
+		settings = append(settings, name+" = "+value)
+	}
+
+	fmt.Printf("Public DNS providers (* = benchmarked by default):\n")
+	for _, p := range builtinProviders {
+		marker := " "
+		if slices.Contains(defaultProviders, p.ID) {
+			marker = "*"
+		}
+		fmt.Printf("  %s %-16s %s (%s)\n", marker, p.ID, p.Name, p.Category)
+	}
+	providers := askValid("\nProviders to benchmark (comma-separated IDs, \"all\" or \"none\")", strings.Join(defaultProviders, ","), func(value string) error {
+		if value == "none" {
+			return nil
+		}
+		_, err := resolveSelection(builtinProviders, value, "", "", "", nil)
+		return err
+	})
+
+	var servers []string
+	askValid("Your own DNS servers, e.g. router=192.168.1.1 (comma-separated)", "none", func(value string) error {
+		servers = nil
+		if value == "none" {
+			return nil
+		}
+		for _, server := range splitList(value) {
+			var parsed serverFlag
+			if err := parsed.Set(server); err != nil {
+				return err
+			}
+			servers = append(servers, server)
+		}
+		return nil
+	})
+	switch {
+	case providers == "none" && len(servers) == 0:
+		fmt.Printf("%s    No resolvers chosen; keeping the default providers%s\n", ColorYellow, ColorReset)
+	case providers == "none":
+		// Explicit servers replace the default providers on their own
+	default:
+		add("providers", providers)
+	}
+	for _, server := range servers {
+		add("server", server)
+	}
+
+	domains := askValid("Domains to resolve (comma-separated)", "built-in list", func(value string) error {
+		if value == "built-in list" {
+			return nil
+		}
+		for _, domain := range splitList(value) {
+			if _, err := toASCII(domain); err != nil {
+				return fmt.Errorf("invalid domain %q: %w", domain, err)
+			}
+		}
+		return nil
+	})
+	if domains != "built-in list" {
+		add("domains", strings.Join(splitList(domains), ","))
+	}
+
+	add("transport", askValid("Transport: udp, tcp, dot (DNS over TLS) or doh (DNS over HTTPS)", TransportUDP, func(value string) error {
+		if !validTransport(value) {
+			return errors.New("want udp, tcp, dot or doh")
+		}
+		return nil
+	}))
+
+	output := askValid("Summary format: text, markdown or html", OutputText, func(value string) error {
+		if value != OutputText && value != OutputMarkdown && value != OutputHTML {
+			return errors.New("want text, markdown or html")
+		}
+		return nil
+	})
+	add("output", output)
+
+	// A saved run can be rendered to a file later, without the progress output
+	saveDefault := "none"
+	if output != OutputText {
+		saveDefault = "results.json"
+	}
+	save := ask("Save raw results for the report and compare commands to (file name)", saveDefault)
+	if save != "none" {
+		add("save", save)
+	}
+	if !confirm("Include the website load time test (slower)?") {
+		add("skip-http", "true")
+	}
+
+	if err := os.WriteFile(*out, []byte(strings.Join(settings, "\n")+"\n"), 0o644); err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	fmt.Printf("\n%s[✓] Wrote %s%s\n", ColorGreen, *out, ColorReset)
+	run := "dnsbench"
+	if *out != defaultConfigPath {
+		run += " --config " + *out
+	}
+	fmt.Printf("    Run %s in this directory to benchmark with these settings.\n", run)
+	if output != OutputText && save != "none" {
+		fmt.Printf("    Afterwards, dnsbench report --from %s --output %s -o report.%s writes the summary to a file.\n", save, output, map[string]string{OutputHTML: "html", OutputMarkdown: "md"}[output])
+	}
+}
+
+// ask prints a question with its default and returns the trimmed answer,
+// or the default for an empty line
+func ask(question string, def string) string {
+	fmt.Printf("%s [%s]: ", question, def)
+	answer, _ := stdinLines.ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer != "" {
+		return answer
+	}
+	return def
+}
+
+// askValid repeats a question until valid accepts the answer; it gives up
+// when stdin is closed rather than asking forever
+func askValid(question string, def string, valid func(string) error) string {
+	for {
+		answer := ask(question, def)
+		err := valid(answer)
+		if err == nil {
+			return answer
+		}
+		fmt.Printf("%s    %v%s\n", ColorRed, err, ColorReset)
+		if _, peekErr := stdinLines.Peek(1); peekErr != nil {
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// trustedDoH is the reference resolver for interception checks; DoH cannot
+// be redirected or rewritten on the path without breaking TLS
+const trustedDoH = "https://cloudflare-dns.com/dns-query"
+
+// interceptProbes are addresses that run no DNS server (RFC 5737
+// documentation ranges), so any answer comes from something on the path
+var interceptProbes = []string{"192.0.2.1:53", "198.51.100.1:53"}
+
+// censorProbes are domains commonly blocked by ISPs and national filters,
+// used when --domains is not given
+var censorProbes = []string{
+	"wikipedia.org", "reddit.com", "telegram.org", "torproject.org", "thepiratebay.org",
+	"archive.org", "protonvpn.com", "bbc.com", "vimeo.com", "pornhub.com",
+}
+
+// runInterception looks for transparent DNS proxies by querying addresses
+// that have no resolver, then compares each resolver's answers for commonly
+// censored domains with DoH answers to find blocking and redirection
+func runInterception(config *BenchmarkConfig, domains []string) {
+	fmt.Printf("%s[*] Querying addresses without a DNS server to detect transparent proxies...%s\n\n", ColorBlue, ColorReset)
+	intercepted := false
+	for _, addr := range interceptProbes {
+		ips, rcode, err := lookupA(TransportUDP, addr, "example.com")
+		if err != nil {
+			fmt.Printf("    %-20s %sno answer%s\n", addr, ColorGreen, ColorReset)
+			continue
+		}
+		intercepted = true
+		note := ""
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if egress, err := lookupEgress(ctx, TransportUDP, addr); err == nil {
+			note = ", resolved by " + egress.IP
+		}
+		cancel()
+		answer := strings.Join(ips, ", ")
+		if len(ips) == 0 {
+			answer = rcode
+		}
+		fmt.Printf("    %-20s %sanswered %s%s%s\n", addr, ColorRed, answer, note, ColorReset)
+	}
+	fmt.Printf("\n")
+	if intercepted {
+		fmt.Printf("%s[!] Plain DNS on port 53 is intercepted: queries to any resolver are answered by a proxy on the path (usually the ISP or router)%s\n", ColorRed, ColorReset)
+		fmt.Printf("%s    Results for plain DNS resolvers reflect that proxy; use --transport dot or doh to reach them%s\n\n", ColorCyan, ColorReset)
+	} else {
+		fmt.Printf("%s[✓] No transparent DNS proxy detected%s\n\n", ColorGreen, ColorReset)
+	}
+
+	if len(domains) == 0 {
+		domains = censorProbes
+	}
+	fmt.Printf("%s[*] Comparing answers for %d domains with %s...%s\n\n", ColorBlue, len(domains), trustedDoH, ColorReset)
+
+	trusted := make(map[string][]string)
+	for _, domain := range domains {
+		ips, rcode, err := lookupA(TransportDoH, trustedDoH, domain)
+		if err != nil || len(ips) == 0 {
+			fmt.Printf("%s[!] %s: no trusted answer (%s), skipped%s\n", ColorYellow, domain, lookupFailure(err, rcode), ColorReset)
+			continue
+		}
+		trusted[domain] = ips
+	}
+	if len(trusted) == 0 {
+		fmt.Printf("%s[!] The trusted resolver answered nothing, cannot compare%s\n", ColorRed, ColorReset)
+		return
+	}
+
+	fmt.Printf("%s%-30s | %-25s | %s%s\n", ColorWhite, "Server", "Domain", "Verdict", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼───────────────────────────┼──────────────", ColorReset)
+
+	var tampering []string
+	for _, server := range config.Servers {
+		for _, addr := range server.endpoints(config.Transport) {
+			display := fmt.Sprintf("%s (%s)", server.Name, addr)
+			tampered, compared := 0, 0
+			for _, domain := range domains {
+				want, ok := trusted[domain]
+				if !ok {
+					continue
+				}
+				verdict, interfered := compareCensored(config.Transport, addr, domain, want)
+				if verdict == "" {
+					continue
+				}
+				compared++
+				if interfered {
+					tampered++
+					fmt.Printf("%-30s | %-25s | %s%s%s\n", display, domain, ColorRed, verdict, ColorReset)
+				}
+			}
+			switch {
+			case compared == 0:
+				fmt.Printf("%-30s | %-25s | %sno answers%s\n", display, "-", ColorYellow, ColorReset)
+			case tampered == 0:
+				fmt.Printf("%-30s | %-25s | %sall %d match%s\n", display, "-", ColorGreen, compared, ColorReset)
+			default:
+				tampering = append(tampering, fmt.Sprintf("%s (%d of %d)", display, tampered, compared))
+			}
+		}
+	}
+
+	fmt.Printf("\n")
+	if len(tampering) > 0 {
+		fmt.Printf("%s[!] Likely blocking or interception: %s%s\n", ColorRed, strings.Join(tampering, ", "), ColorReset)
+		fmt.Printf("%s    Filtering resolvers (security/family) block on purpose; for others this points at ISP or national filtering%s\n", ColorCyan, ColorReset)
+	} else {
+		fmt.Printf("%s[✓] No resolver altered the answers%s\n", ColorGreen, ColorReset)
+	}
+}
+
+// compareCensored classifies addr's answer for domain against the trusted
+// one. The verdict is empty when the resolver could not be asked; differing
+// addresses in the same network as the trusted ones are CDN steering.
+func compareCensored(transport string, addr string, domain string, want []string) (string, bool) {
+	got, rcode, err := lookupA(transport, addr, domain)
+	switch {
+	case err != nil:
+		return "", false
+	case rcode != dns.RcodeToString[dns.RcodeSuccess]:
+		return "blocked (" + rcode + ")", true
+	case len(got) == 0:
+		return "blocked (empty answer)", true
+	case slices.ContainsFunc(got, blockedAddress):
+		return "sinkholed to " + strings.Join(got, ", "), true
+	}
+	for _, ip := range got {
+		if slices.Contains(want, ip) {
+			return "match", false
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	gotASN, gotISP, _ := lookupASN(ctx, TransportDoH, trustedDoH, got[0], "")
+	wantASN, _, _ := lookupASN(ctx, TransportDoH, trustedDoH, want[0], "")
+	if gotASN != 0 && gotASN == wantASN {
+		return "match (same network)", false
+	}
+	if gotASN == 0 {
+		return "redirected to " + got[0], true
+	}
+	return fmt.Sprintf("redirected to %s (AS%d %s)", got[0], gotASN, gotISP), true
+}
+
+// blockedAddress reports addresses filters answer with instead of the real
+// one: the null route, loopback and private ranges
+func blockedAddress(raw string) bool {
+	ip := net.ParseIP(raw)
+	return ip != nil && (ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate())
+}
+
+// lookupFailure describes a failed lookup by its error or response code
+func lookupFailure(err error, rcode string) string {
+	if err != nil {
+		return err.Error()
+	}
+	if rcode == "NOERROR" {
+		return "no addresses"
+	}
+	return rcode
+}
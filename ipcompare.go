@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// familyResult is one website loaded over IPv4 only and IPv6 only
+type familyResult struct {
+	domain string
+	v4, v6 time.Duration
+	v4Err  string
+	v6Err  string
+}
+
+// compareAddressFamilies loads every website once over A records only and
+// once over AAAA records only, resolved through the given DNS server, so
+// dual-stack users can see whether their IPv6 path is slower
+func compareAddressFamilies(config *BenchmarkConfig, dnsName string, resolverAddr string) {
+	fmt.Printf("%s[*] IPv4 vs IPv6 load times (via %s):%s\n\n", ColorBlue, dnsName, ColorReset)
+
+	var results []familyResult
+	for _, domain := range config.pages() {
+		result := familyResult{domain: domain}
+		result.v4, result.v4Err = loadOverFamily(config, resolverAddr, "ip4", domain)
+		result.v6, result.v6Err = loadOverFamily(config, resolverAddr, "ip6", domain)
+		results = append(results, result)
+	}
+
+	fmt.Printf("%s%-25s | %-12s | %-12s | %s%s\n", ColorWhite, "Domain", "IPv4", "IPv6", "IPv6 vs IPv4", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "──────────────────────────┼──────────────┼──────────────┼──────────────", ColorReset)
+
+	var dualStack int
+	var v4Total, v6Total time.Duration
+	for _, result := range results {
+		v4 := formatFamilyTime(result.v4, result.v4Err)
+		v6 := formatFamilyTime(result.v6, result.v6Err)
+		diff := ""
+		switch {
+		case result.v4Err == "" && result.v6Err == "":
+			dualStack++
+			v4Total += result.v4
+			v6Total += result.v6
+			delta := result.v6 - result.v4
+			diffColor := ColorGreen
+			if delta > 0 && delta > result.v4/5 {
+				// IPv6 more than 20% slower
+				diffColor = ColorYellow
+			}
+			diff = fmt.Sprintf("%s%+7.0f ms%s", diffColor, float64(delta.Milliseconds()), ColorReset)
+		case result.v4Err == "":
+			diff = ColorRed + "IPv4 only" + ColorReset
+		case result.v6Err == "":
+			diff = ColorYellow + "IPv6 only" + ColorReset
+		}
+		fmt.Printf("%-25s | %s | %s | %s\n", result.domain, v4, v6, diff)
+	}
+	fmt.Printf("\n")
+
+	for _, result := range results {
+		if result.v4Err != "" {
+			fmt.Printf("%s[!] %s over IPv4: %s%s\n", ColorRed, result.domain, result.v4Err, ColorReset)
+		}
+		if result.v6Err != "" {
+			fmt.Printf("%s[!] %s over IPv6: %s%s\n", ColorRed, result.domain, result.v6Err, ColorReset)
+		}
+	}
+
+	if dualStack == 0 {
+		fmt.Printf("%s[!] No website loaded over both IPv4 and IPv6%s\n\n", ColorYellow, ColorReset)
+		return
+	}
+	v4Avg := v4Total / time.Duration(dualStack)
+	v6Avg := v6Total / time.Duration(dualStack)
+	verdict := "faster"
+	if v6Avg > v4Avg {
+		verdict = "slower"
+	}
+	fmt.Printf("%s[✓] Across %d dual-stack sites IPv6 averages %.0f ms vs %.0f ms over IPv4 (%.0f ms %s)%s\n\n",
+		ColorGreen, dualStack, float64(v6Avg.Milliseconds()), float64(v4Avg.Milliseconds()),
+		float64((v6Avg - v4Avg).Abs().Milliseconds()), verdict, ColorReset)
+}
+
+// loadOverFamily requests a website connecting only to addresses of the
+// given family, on a fresh connection so connection setup is included
+func loadOverFamily(config *BenchmarkConfig, resolverAddr string, family string, domain string) (time.Duration, string) {
+	transport := &http.Transport{
+		DisableKeepAlives: true,
+		DialContext:       dialContextFamily(resolverAddr, family),
+	}
+	defer transport.CloseIdleConnections()
+	client := &http.Client{
+		Timeout:   config.HTTPTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > config.HTTPMaxRedirects {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+
+	var elapsed time.Duration
+	var errMsg string
+	for attempt := 0; attempt <= config.HTTPRetries; attempt++ {
+		start := time.Now()
+		resp, err := client.Head(fmt.Sprintf("https://%s", domain))
+		elapsed = time.Since(start)
+		if err == nil {
+			resp.Body.Close()
+			return elapsed, ""
+		}
+		errMsg = err.Error()
+	}
+	return elapsed, errMsg
+}
+
+func formatFamilyTime(elapsed time.Duration, errMsg string) string {
+	if errMsg != "" {
+		return fmt.Sprintf("%s%12s%s", ColorRed, "failed", ColorReset)
+	}
+	return fmt.Sprintf("%9.0f ms", float64(elapsed.Milliseconds()))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// runLabels describe the circumstances of this run (--label), e.g. vpn or
+// office-wifi, and are saved with its results
+var runLabels []string
+
+// labelFlag collects --label values; each may also be a comma-separated list
+type labelFlag struct{}
+
+func (labelFlag) String() string { return strings.Join(runLabels, ",") }
+
+func (labelFlag) Set(value string) error {
+	for _, label := range splitList(value) {
+		if strings.ContainsAny(label, "=/") {
+			return fmt.Errorf("invalid label %q: must not contain = or /", label)
+		}
+		if !slices.Contains(runLabels, label) {
+			runLabels = append(runLabels, label)
+		}
+	}
+	return nil
+}
+
+// withLabel keeps only the results of runs labeled label. In a merged file
+// each result is matched through the source it came from.
+func (f *ResultsFile) withLabel(path string, label string) (*ResultsFile, error) {
+	if label == "" {
+		return f, nil
+	}
+	sourceLabels := make(map[string][]string)
+	for _, source := range f.Sources {
+		sourceLabels[source.Label] = source.Labels
+	}
+
+	filtered := *f
+	filtered.Results = nil
+	for _, result := range f.Results {
+		labels := f.Labels
+		if len(f.Sources) > 0 {
+			labels = sourceLabels[result.Source]
+		}
+		if slices.Contains(labels, label) {
+			filtered.Results = append(filtered.Results, result)
+		}
+	}
+	if len(filtered.Results) == 0 {
+		return nil, fmt.Errorf("no run in %s is labeled %q", path, label)
+	}
+
+	filtered.Sources = nil
+	for _, source := range f.Sources {
+		if slices.Contains(source.Labels, label) {
+			filtered.Sources = append(filtered.Sources, source)
+		}
+	}
+	return &filtered, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// largeQuery is a name known to produce a large response
+type largeQuery struct {
+	Name   string
+	Qtype  uint16
+	DNSSEC bool
+}
+
+func (q largeQuery) String() string {
+	return q.Name + " " + dns.TypeToString[q.Qtype]
+}
+
+// largeResponseQueries are big TXT and DNSKEY sets, typically 1-4 KB
+var largeResponseQueries = []largeQuery{
+	{"microsoft.com", dns.TypeTXT, false},
+	{"google.com", dns.TypeTXT, false},
+	{"isc.org", dns.TypeDNSKEY, true},
+	{"org", dns.TypeDNSKEY, true},
+}
+
+// ednsBufferSizes are the advertised UDP buffer sizes tried for each query
+var ednsBufferSizes = []uint16{512, fragmentationThreshold, 4096}
+
+// largeOutcome is how one large query ended at one buffer size
+type largeOutcome struct {
+	Size      int
+	Truncated bool
+	ViaTCP    bool
+	Err       error
+}
+
+// String renders the outcome as a fixed-width table cell
+func (o largeOutcome) String() string {
+	switch {
+	case o.Err != nil && o.Truncated:
+		return ColorRed + fmt.Sprintf("%-14s", "TC, TCP failed") + ColorReset
+	case o.Err != nil && isTimeout(o.Err):
+		return ColorRed + fmt.Sprintf("%-14s", "timeout") + ColorReset
+	case o.Err != nil:
+		return ColorRed + fmt.Sprintf("%-14s", "failed") + ColorReset
+	case o.ViaTCP:
+		return ColorYellow + fmt.Sprintf("%-14s", fmt.Sprintf("TCP %d B", o.Size)) + ColorReset
+	}
+	return ColorGreen + fmt.Sprintf("%-14s", fmt.Sprintf("UDP %d B", o.Size)) + ColorReset
+}
+
+// runLargeResponse queries large TXT/DNSKEY sets over UDP with several EDNS
+// buffer sizes and follows truncated answers over TCP, showing which
+// resolvers fall back cleanly and which lose oversized (fragmented) responses
+func runLargeResponse(config *BenchmarkConfig) {
+	fmt.Printf("%s[*] Testing large responses with EDNS buffer sizes %v...%s\n", ColorBlue, ednsBufferSizes, ColorReset)
+	fmt.Printf("%s    Truncated answers are retried over TCP, as a stub resolver would%s\n\n", ColorCyan, ColorReset)
+
+	var broken []string
+	for _, server := range config.Servers {
+		addrs := server.endpoints(TransportUDP)
+		if len(addrs) == 0 {
+			continue
+		}
+		addr := addrs[0]
+
+		fmt.Printf("%s[*] %s (%s)%s\n", ColorBlue, server.Name, addr, ColorReset)
+		fmt.Printf("%s%-22s", ColorWhite, "Query")
+		for _, size := range ednsBufferSizes {
+			fmt.Printf(" | %-14s", fmt.Sprintf("EDNS %d", size))
+		}
+		fmt.Printf("%s\n", ColorReset)
+		fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────┼────────────────┼────────────────┼───────────────", ColorReset)
+
+		failures := 0
+		for _, query := range largeResponseQueries {
+			fmt.Printf("%-22s", query)
+			for _, size := range ednsBufferSizes {
+				outcome := queryLarge(addr, query, size)
+				if outcome.Err != nil {
+					failures++
+				}
+				fmt.Printf(" | %s", outcome)
+			}
+			fmt.Printf("\n")
+		}
+		fmt.Printf("\n")
+
+		if failures > 0 {
+			broken = append(broken, server.Name)
+		}
+	}
+
+	if len(broken) == 0 {
+		fmt.Printf("%s[✓] Every resolver delivered large responses, falling back to TCP where needed%s\n", ColorGreen, ColorReset)
+		return
+	}
+	fmt.Printf("%s[!] Large responses failed through: %v%s\n", ColorRed, broken, ColorReset)
+	fmt.Printf("%s    Timeouts at larger buffer sizes usually mean fragmented UDP is being dropped on the path%s\n", ColorYellow, ColorReset)
+}
+
+// queryLarge sends one query over UDP advertising bufSize and retries over
+// TCP when the answer comes back truncated
+func queryLarge(addr string, query largeQuery, bufSize uint16) largeOutcome {
+	m := &dns.Msg{}
+	m.SetQuestion(dns.Fqdn(query.Name), query.Qtype)
+	m.SetEdns0(bufSize, query.DNSSEC)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	r, err := exchange(ctx, m, TransportUDP, addr)
+	if err != nil {
+		return largeOutcome{Err: err}
+	}
+	if !r.Truncated {
+		r.Compress = true
+		return largeOutcome{Size: r.Len()}
+	}
+
+	outcome := largeOutcome{Truncated: true, ViaTCP: true}
+	tcpCtx, tcpCancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer tcpCancel()
+	r, err = exchange(tcpCtx, m, TransportTCP, addr)
+	if err != nil {
+		outcome.Err = err
+		return outcome
+	}
+	r.Compress = true
+	outcome.Size = r.Len()
+	return outcome
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LoadTestConfig holds the QPS ramp used by load mode
+type LoadTestConfig struct {
+	StartQPS     int
+	StepQPS      int
+	MaxQPS       int
+	StepDuration time.Duration
+	MaxLoss      float64 // maximum failure percentage for a step to count as sustained
+}
+
+// LoadStepResult holds the outcome of one offered-load step
+type LoadStepResult struct {
+	OfferedQPS  int
+	AchievedQPS float64
+	Sent        int
+	Succeeded   int
+	LossPercent float64
+	P50         time.Duration
+	P95         time.Duration
+	P99         time.Duration
+}
+
+// loadTickInterval is the granularity at which the open-loop generator sends queries
+const loadTickInterval = 10 * time.Millisecond
+
+func (s *LoadStepResult) sustained(cfg *LoadTestConfig) bool {
+	return s.LossPercent <= cfg.MaxLoss && s.AchievedQPS >= 0.95*float64(s.OfferedQPS)
+}
+
+func runLoadTest(config *BenchmarkConfig) {
+	cfg := config.LoadTest
+	fmt.Printf("%s[*] Starting load test...%s\n", ColorBlue, ColorReset)
+	fmt.Printf("%s    Ramp: %d → %d QPS in steps of %d, %v per step (max loss %.1f%%)%s\n\n",
+		ColorCyan, cfg.StartQPS, cfg.MaxQPS, cfg.StepQPS, cfg.StepDuration, cfg.MaxLoss, ColorReset)
+
+	for _, server := range config.Servers {
+		for _, addr := range server.endpoints(config.Transport) {
+			fmt.Printf("%s[*] Load testing %s (%s)%s\n", ColorBlue, server.Name, addr, ColorReset)
+			fmt.Printf("%s%-12s | %-12s | %-8s | %-12s | %-12s | %-12s%s\n",
+				ColorWhite, "Offered QPS", "Achieved QPS", "Loss", "p50 RTT", "p95 RTT", "p99 RTT", ColorReset)
+			fmt.Printf("%s%s%s\n", ColorYellow, "─────────────┼──────────────┼──────────┼──────────────┼──────────────┼─────────────", ColorReset)
+
+			maxSustained := 0
+			for qps := cfg.StartQPS; qps <= cfg.MaxQPS; qps += cfg.StepQPS {
+				job := queryJob{ServerName: server.Name, ServerAddr: addr, Transport: config.Transport}
+				step := runLoadStep(job, config.Domains, qps, cfg.StepDuration)
+
+				lossColor := ColorGreen
+				if !step.sustained(cfg) {
+					lossColor = ColorRed
+				}
+				fmt.Printf("%-12d | %12.1f | %s%7.2f%%%s | %9.2f ms | %9.2f ms | %9.2f ms\n",
+					step.OfferedQPS, step.AchievedQPS,
+					lossColor, step.LossPercent, ColorReset,
+					ms(step.P50), ms(step.P95), ms(step.P99),
+				)
+
+				// Stop ramping once the resolver can no longer keep up
+				if !step.sustained(cfg) {
+					break
+				}
+				maxSustained = qps
+			}
+
+			if maxSustained > 0 {
+				fmt.Printf("\n%s[✓] Max sustainable QPS: %d%s\n\n", ColorGreen, maxSustained, ColorReset)
+			} else {
+				fmt.Printf("\n%s[!] Could not sustain the starting rate of %d QPS%s\n\n", ColorRed, cfg.StartQPS, ColorReset)
+			}
+		}
+	}
+}
+
+// runLoadStep offers a constant query rate for the given duration (open loop)
+// and waits for all outstanding queries before summarizing
+func runLoadStep(job queryJob, domains []string, qps int, duration time.Duration) *LoadStepResult {
+	var wg sync.WaitGroup
+	var stepMu sync.Mutex
+	var rtts []time.Duration
+	sent, succeeded := 0, 0
+
+	ticker := time.NewTicker(loadTickInterval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	for now := range ticker.C {
+		elapsed := now.Sub(start)
+		if elapsed > duration {
+			break
+		}
+
+		due := int(elapsed.Seconds()*float64(qps)) - sent
+		for i := 0; i < due; i++ {
+			job.Domain = domains[sent%len(domains)]
+			sent++
+			wg.Add(1)
+			go func(job queryJob) {
+				defer wg.Done()
+				result := queryDNS(job)
+				stepMu.Lock()
+				defer stepMu.Unlock()
+				if result.Status == "SUCCESS" {
+					succeeded++
+					rtts = append(rtts, result.RTT)
+				}
+			}(job)
+		}
+	}
+	wg.Wait()
+
+	step := &LoadStepResult{
+		OfferedQPS:  qps,
+		AchievedQPS: float64(succeeded) / duration.Seconds(),
+		Sent:        sent,
+		Succeeded:   succeeded,
+		P50:         percentile(rtts, 50),
+		P95:         percentile(rtts, 95),
+		P99:         percentile(rtts, 99),
+	}
+	if sent > 0 {
+		step.LossPercent = float64(sent-succeeded) / float64(sent) * 100
+	}
+	return step
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// localProbeTimeout keeps detection quick when nothing is listening
+const localProbeTimeout = 500 * time.Millisecond
+
+// localCandidates are addresses where local caching resolvers usually listen:
+// loopback (Unbound, dnsmasq, Pi-hole) and the systemd-resolved stub
+var localCandidates = []string{"127.0.0.1:53", "[::1]:53", "127.0.0.53:53"}
+
+// detectLocalResolvers probes loopback, the resolv.conf nameservers and the
+// default gateway, returning every address that answers DNS queries
+func detectLocalResolvers() []*DNSServer {
+	candidates := slices.Clone(localCandidates)
+	if resolvConf, err := dns.ClientConfigFromFile(resolvConfPath); err == nil {
+		for _, server := range resolvConf.Servers {
+			candidates = append(candidates, net.JoinHostPort(server, "53"))
+		}
+	}
+	if gateway := defaultGateway(); gateway != nil {
+		candidates = append(candidates, net.JoinHostPort(gateway.String(), "53"))
+	}
+	slices.Sort(candidates)
+	candidates = slices.Compact(candidates)
+
+	found := make([]*DNSServer, len(candidates))
+	var wg sync.WaitGroup
+	for i, addr := range candidates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			software, ok := probeLocalResolver(addr)
+			slog.Debug("probed local resolver", "addr", addr, "answers", ok, "software", software)
+			if ok {
+				// The address keeps names unique when several resolvers run
+				// the same software, so their stats are not merged
+				name := "Local (" + addr + ")"
+				if host, _, _ := net.SplitHostPort(addr); software != host {
+					name = "Local (" + software + ", " + addr + ")"
+				}
+				found[i] = &DNSServer{ID: "local", Name: name, Primary: addr}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return slices.DeleteFunc(found, func(server *DNSServer) bool { return server == nil })
+}
+
+// probeLocalResolver checks that addr answers a recursive query and names
+// the resolver software when it can be identified
+func probeLocalResolver(addr string) (string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), localProbeTimeout)
+	defer cancel()
+
+	m := &dns.Msg{}
+	m.SetQuestion("example.com.", dns.TypeA)
+	r, err := exchange(ctx, m, TransportUDP, addr)
+	if err != nil || r.Rcode == dns.RcodeRefused {
+		return "", false
+	}
+
+	if strings.HasPrefix(addr, "127.0.0.53:") {
+		return "systemd-resolved", true
+	}
+	return resolverSoftware(ctx, addr), true
+}
+
+// resolverSoftware names the DNS software at addr, falling back to its host
+func resolverSoftware(ctx context.Context, addr string) string {
+	// dnsmasq, Unbound and BIND report themselves via CHAOS version.bind
+	version := &dns.Msg{}
+	version.SetQuestion("version.bind.", dns.TypeTXT)
+	version.Question[0].Qclass = dns.ClassCHAOS
+	if r, err := exchange(ctx, version, TransportUDP, addr); err == nil {
+		for _, rr := range r.Answer {
+			if txt, ok := rr.(*dns.TXT); ok && len(txt.Txt) > 0 {
+				return txt.Txt[0]
+			}
+		}
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	return host
+}
+
+// defaultGateway reads the IPv4 default route from /proc/net/route (Linux);
+// home routers typically run a caching resolver such as dnsmasq
+func defaultGateway() net.IP {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(raw))
+		if !ip.IsUnspecified() {
+			return ip
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// mdnsAddr is the IPv4 mDNS group (RFC 6762)
+const mdnsAddr = "224.0.0.251:5353"
+
+// localNameTimeout bounds one local lookup; LAN answers take milliseconds,
+// so anything near this is the delay being diagnosed
+const localNameTimeout = 2 * time.Second
+
+// localMethod is one way of resolving a LAN name
+type localMethod struct {
+	Label  string
+	lookup func(name string) (time.Duration, string, error)
+}
+
+// isMDNSName reports whether name belongs to the mDNS .local domain
+func isMDNSName(name string) bool {
+	return strings.HasSuffix(strings.ToLower(strings.TrimSuffix(name, ".")), ".local")
+}
+
+// routerResolver returns the router's DNS server: the default gateway when
+// it answers, otherwise the first resolv.conf nameserver, which DHCP
+// usually points at the router
+func routerResolver() string {
+	if gw := defaultGateway(); gw != nil {
+		addr := net.JoinHostPort(gw.String(), "53")
+		if _, ok := probeLocalResolver(addr); ok {
+			return addr
+		}
+	}
+	if conf, err := dns.ClientConfigFromFile(resolvConfPath); err == nil && len(conf.Servers) > 0 {
+		return net.JoinHostPort(conf.Servers[0], conf.Port)
+	}
+	return ""
+}
+
+// lookupMDNS sends a one-shot mDNS query for the A record of name and waits
+// for the first responder. Sent from an ephemeral port, it is a legacy
+// unicast query (RFC 6762 section 6.7) that responders answer directly.
+func lookupMDNS(name string) (time.Duration, string, error) {
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer conn.Close()
+	group, err := net.ResolveUDPAddr("udp4", mdnsAddr)
+	if err != nil {
+		return 0, "", err
+	}
+
+	m := &dns.Msg{}
+	m.SetQuestion(dns.Fqdn(name), dns.TypeA)
+	m.RecursionDesired = false
+	packed, err := m.Pack()
+	if err != nil {
+		return 0, "", err
+	}
+	start := time.Now()
+	if _, err := conn.WriteToUDP(packed, group); err != nil {
+		return 0, "", err
+	}
+	conn.SetReadDeadline(start.Add(localNameTimeout))
+
+	buf := make([]byte, dns.MaxMsgSize)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return time.Since(start), "", fmt.Errorf("no mDNS responder answered")
+		}
+		r := &dns.Msg{}
+		if r.Unpack(buf[:n]) != nil || !r.Response {
+			continue
+		}
+		for _, rr := range r.Answer {
+			if a, ok := rr.(*dns.A); ok && strings.EqualFold(a.Hdr.Name, m.Question[0].Name) {
+				return time.Since(start), a.A.String(), nil
+			}
+		}
+	}
+}
+
+// lookupRouter resolves name through the router's DNS server
+func lookupRouter(addr string) func(name string) (time.Duration, string, error) {
+	return func(name string) (time.Duration, string, error) {
+		m := &dns.Msg{}
+		m.SetQuestion(dns.Fqdn(name), dns.TypeA)
+		ctx, cancel := context.WithTimeout(context.Background(), localNameTimeout)
+		defer cancel()
+		start := time.Now()
+		r, err := exchange(ctx, m, TransportUDP, addr)
+		elapsed := time.Since(start)
+		if err != nil {
+			return elapsed, "", err
+		}
+		for _, rr := range r.Answer {
+			if a, ok := rr.(*dns.A); ok {
+				return elapsed, a.A.String(), nil
+			}
+		}
+		return elapsed, "", fmt.Errorf("%s", dns.RcodeToString[r.Rcode])
+	}
+}
+
+// lookupSystemName resolves name the way applications do, including search
+// domains, nss-mdns or Bonjour when the binary is built with cgo
+func lookupSystemName(name string) (time.Duration, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), systemTimeout)
+	defer cancel()
+	start := time.Now()
+	addrs, err := systemResolver.LookupHost(ctx, name)
+	elapsed := time.Since(start)
+	if err != nil {
+		return elapsed, "", err
+	}
+	return elapsed, addrs[0], nil
+}
+
+// testLocalNames benchmarks LAN name resolution: .local names over mDNS,
+// every name through the router's DNS server, and every name through the
+// system resolver, which shows whether the OS takes a slow path (e.g.
+// sending .local names to unicast DNS first and waiting for a timeout)
+func testLocalNames(config *BenchmarkConfig) {
+	router := routerResolver()
+	fmt.Printf("%s╔════════════════════════════════════════════════════════════╗%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s║              LOCAL NAME RESOLUTION (LAN/mDNS)              ║%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s╚════════════════════════════════════════════════════════════╝%s\n\n", ColorCyan, ColorReset)
+	if router == "" {
+		fmt.Printf("%s[!] No router DNS server found; only mDNS and the system resolver are tested%s\n\n", ColorYellow, ColorReset)
+	}
+
+	fmt.Printf("%s%-26s | %-26s | %-10s | %-10s | %-10s | %-7s | %s%s\n",
+		ColorWhite, "Name", "Method", "Average", "Median", "P95", "Failed", "Answer", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────┼────────────────────────────┼────────────┼────────────┼────────────┼─────────┼────────────────", ColorReset)
+
+	for _, name := range config.LocalNames {
+		var methods []localMethod
+		if isMDNSName(name) {
+			methods = append(methods, localMethod{"mDNS (" + mdnsAddr + ")", lookupMDNS})
+		}
+		if router != "" {
+			methods = append(methods, localMethod{"Router (" + router + ")", lookupRouter(router)})
+		}
+		methods = append(methods, localMethod{"System", lookupSystemName})
+
+		for _, method := range methods {
+			var rtts []time.Duration
+			var failed int
+			var answer string
+			var lastErr error
+			var lastFail time.Duration
+			for i := 0; i < config.QueryNum; i++ {
+				rtt, addr, err := method.lookup(name)
+				if err != nil {
+					failed++
+					lastErr, lastFail = err, rtt
+					continue
+				}
+				rtts = append(rtts, rtt)
+				answer = addr
+			}
+			if len(rtts) == 0 {
+				// How long a failure takes matters as much: apps wait it out
+				fmt.Printf("%-26s | %-26s | %s%v (after %.1f ms)%s\n", name, method.Label, ColorRed, lastErr, ms(lastFail), ColorReset)
+				continue
+			}
+			var total time.Duration
+			for _, rtt := range rtts {
+				total += rtt
+			}
+			avg := total / time.Duration(len(rtts))
+			// A LAN answer should take a few ms; hundreds point at a timeout
+			// being waited out somewhere on the path
+			color := ColorGreen
+			switch {
+			case avg > 500*time.Millisecond:
+				color = ColorRed
+			case avg > 50*time.Millisecond:
+				color = ColorYellow
+			}
+			failedColor := ColorGreen
+			if failed > 0 {
+				failedColor = ColorRed
+			}
+			fmt.Printf("%-26s | %-26s | %s%7.1f ms%s | %7.1f ms | %7.1f ms | %s%3d/%-3d%s | %s\n",
+				name, method.Label, color, ms(avg), ColorReset, ms(percentile(rtts, 50)), ms(percentile(rtts, 95)),
+				failedColor, failed, config.QueryNum, ColorReset, answer)
+		}
+	}
+	fmt.Printf("\n%s    LAN lookups should take a few ms; a slow System row next to fast mDNS or Router rows means the OS%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s    resolves the long way, e.g. asking unicast DNS for .local names or trying search domains first.%s\n\n", ColorCyan, ColorReset)
+}
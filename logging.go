@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Log formats accepted by --log-format
+const (
+	LogConsole = "console"
+	LogText    = "text"
+	LogJSON    = "json"
+)
+
+// logFormat is the selected --log-format; console renders events for
+// people, text and json emit structured records on stderr for collectors
+var logFormat = LogConsole
+
+// configureLogging installs the slog handler for --log-level and --log-format
+func configureLogging(level string, format string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("unknown log level %q (want debug, info, warn or error)", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: replaceTimeAttr}
+	switch format {
+	case LogConsole:
+		slog.SetDefault(slog.New(newConsoleHandler(os.Stdout, lvl)))
+	case LogText:
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, opts)))
+	case LogJSON:
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, opts)))
+	default:
+		return fmt.Errorf("unknown log format %q (want %s, %s or %s)", format, LogConsole, LogText, LogJSON)
+	}
+	logFormat = format
+	return nil
+}
+
+// logQuery emits one finished query: answered queries at info level,
+// failures at warn, so --log-level warn keeps only the problems. The
+// console renders them as the live benchmark log.
+func logQuery(result *BenchmarkResult) {
+	level := slog.LevelInfo
+	if result.Status != "SUCCESS" {
+		level = slog.LevelWarn
+	}
+	ctx := context.Background()
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+	if logFormat == LogConsole {
+		logResult(result)
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("server", result.ServerName),
+		slog.String("addr", result.ServerAddr),
+		slog.String("transport", result.Transport),
+		slog.String("domain", result.Domain),
+		slog.String("qtype", result.qtype()),
+		slog.Int("iteration", result.Iteration),
+		slog.Float64("rtt_ms", ms(result.RTT)),
+		slog.String("status", result.Status),
+	}
+	if result.Rcode != "" {
+		attrs = append(attrs, slog.String("rcode", result.Rcode))
+	}
+	if result.Error != "" {
+		attrs = append(attrs, slog.String("error", result.Error))
+	}
+	slog.LogAttrs(ctx, level, "query", attrs...)
+}
+
+// consoleHandler renders records in the tool's own style: "[*]" for info,
+// a yellow "[!]" for warnings, a red one for errors and an indented line
+// for debug. Attributes follow in parentheses and an "err" attribute after
+// a colon, e.g. "[!] Failed to save checkpoint: disk full".
+type consoleHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex
+	level slog.Leveler
+	attrs []slog.Attr
+}
+
+func newConsoleHandler(w io.Writer, level slog.Leveler) *consoleHandler {
+	return &consoleHandler{w: w, mu: &sync.Mutex{}, level: level}
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	prefix, color := "    ", ColorCyan
+	switch {
+	case r.Level >= slog.LevelError:
+		prefix, color = "[!] ", ColorRed
+	case r.Level >= slog.LevelWarn:
+		prefix, color = "[!] ", ColorYellow
+	case r.Level >= slog.LevelInfo:
+		prefix, color = "[*] ", ColorBlue
+	}
+
+	var details []string
+	var errText string
+	add := func(a slog.Attr) bool {
+		if a.Key == "err" {
+			errText = a.Value.String()
+		} else {
+			details = append(details, a.Key+"="+a.Value.String())
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		add(a)
+	}
+	r.Attrs(add)
+
+	line := prefix + r.Message
+	if len(details) > 0 {
+		line += " (" + strings.Join(details, ", ") + ")"
+	}
+	if errText != "" {
+		line += ": " + errText
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := fmt.Fprintf(h.w, "%s%s%s\n", color, line, ColorReset)
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &clone
+}
+
+// WithGroup is a no-op: console lines have no room for nesting
+func (h *consoleHandler) WithGroup(string) slog.Handler {
+	return h
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+// Limits of --low-bandwidth, for links where the benchmark itself would
+// congest the connection (LTE hotspots, metered satellite)
+const (
+	lowBandwidthQueries     = 2
+	lowBandwidthDomains     = 6
+	lowBandwidthConcurrency = 2
+)
+
+// applyLowBandwidth trims the run for a slow or metered link: fewer
+// queries and domains, one query in flight (at most two when --concurrency
+// is given) and no website test. Explicitly set flags are respected within
+// those limits.
+func applyLowBandwidth(fs *flag.FlagSet, config *BenchmarkConfig) {
+	config.QueryNum = lowBandwidthQueries
+	if !flagSet(fs, "domains") && !flagSet(fs, "domain-category") && !flagSet(fs, "domain-pack") && len(config.Domains) > lowBandwidthDomains {
+		config.Domains = config.Domains[:lowBandwidthDomains]
+	}
+	if flagSet(fs, "concurrency") {
+		config.Concurrency = min(config.Concurrency, lowBandwidthConcurrency)
+	} else {
+		config.Concurrency = 1
+	}
+	if !config.HTTPOnly {
+		config.SkipHTTP = true
+	}
+}
+
+// printLowBandwidth lists the reduced volume in the configuration
+func printLowBandwidth(config *BenchmarkConfig) {
+	var endpoints int
+	for _, server := range config.Servers {
+		endpoints += len(server.endpoints(config.Transport))
+	}
+	queries := endpoints * len(config.Domains) * len(config.QTypes) * config.QueryNum
+	fmt.Printf("    Low bandwidth: about %d queries, %d in flight", queries, config.Concurrency)
+	if config.SkipHTTP {
+		fmt.Printf(", website test skipped")
+	}
+	fmt.Printf("\n")
+}
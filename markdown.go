@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Summary output formats accepted by --output
+const (
+	OutputText     = "text"
+	OutputMarkdown = "markdown"
+)
+
+// markdownEscape keeps resolver names from breaking table cells
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// writeMarkdown renders the server and domain summaries as GitHub-flavored
+// Markdown tables, ready to paste into issues, wikis or READMEs
+func writeMarkdown(w io.Writer, report *Report) error {
+	// Rendered in memory so a failed write is one error, not a cut-off table
+	var b bytes.Buffer
+	writeMarkdownTables(&b, report)
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writeMarkdownTables renders the Markdown summary
+func writeMarkdownTables(w io.Writer, report *Report) {
+	fmt.Fprintf(w, "## DNS Benchmark Results\n\n")
+	fmt.Fprintf(w, "Transport: %s · %s", strings.ToUpper(report.Transport), report.GeneratedAt.In(timeZone).Format("2006-01-02 15:04 MST"))
+	if report.Metadata != nil && report.Metadata.PublicIP != "" {
+		fmt.Fprintf(w, " · measured from %s", markdownEscape(report.Metadata.String()))
+	}
+	if len(report.Labels) > 0 {
+		fmt.Fprintf(w, " · labels: %s", markdownEscape(strings.Join(report.Labels, ", ")))
+	}
+	if report.Metadata != nil && report.Metadata.Environment != nil {
+		fmt.Fprintf(w, " · %s", markdownEscape(report.Metadata.Environment.String()))
+	}
+	fmt.Fprintf(w, "\n\n")
+	for _, source := range report.Sources {
+		fmt.Fprintf(w, "- **%s**: %s, %s", markdownEscape(source.Label), strings.ToUpper(source.Transport), source.SavedAt.In(timeZone).Format("2006-01-02 15:04 MST"))
+		if source.Metadata != nil && source.Metadata.PublicIP != "" {
+			fmt.Fprintf(w, " · measured from %s", markdownEscape(source.Metadata.String()))
+		}
+		if len(source.Labels) > 0 {
+			fmt.Fprintf(w, " · labels: %s", markdownEscape(strings.Join(source.Labels, ", ")))
+		}
+		if source.Metadata != nil && source.Metadata.Environment != nil {
+			fmt.Fprintf(w, " · %s", markdownEscape(source.Metadata.Environment.String()))
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	if len(report.Sources) > 0 {
+		fmt.Fprintf(w, "\n")
+	}
+
+	fmt.Fprintf(w, "### Servers\n\n")
+	fmt.Fprintf(w, "| # | Server | Address | Min RTT | Avg RTT (95%% CI) | Max RTT | Success Rate |\n")
+	fmt.Fprintf(w, "|--:|--------|---------|--------:|-----------------:|--------:|-------------:|\n")
+	for i, stats := range report.Servers {
+		fmt.Fprintf(w, "| %d | %s | `%s` | %.2f ms | %.2f ± %.2f ms | %.2f ms | %.1f%% |\n",
+			i+1, markdownEscape(stats.ServerName), stats.ServerAddr,
+			ms(stats.MinRTT), ms(stats.AvgRTT), ms(stats.CI95), ms(stats.MaxRTT), stats.SuccessRate())
+	}
+
+	fmt.Fprintf(w, "\n")
+	writeMarkdownApdex(w, report.Servers)
+
+	fmt.Fprintf(w, "### Domains\n\n")
+	fmt.Fprintf(w, "| Domain | Avg RTT | Success Rate |\n")
+	fmt.Fprintf(w, "|--------|--------:|-------------:|\n")
+	for _, stats := range report.Domains {
+		fmt.Fprintf(w, "| %s | %.2f ms | %.1f%% |\n", markdownEscape(displayDomain(stats.Domain)), ms(stats.AvgRTT), stats.SuccessRate())
+	}
+	fmt.Fprintf(w, "\n")
+	writeMarkdownTypes(w, report.Results)
+}
+
+// writeMarkdownTypes adds the per-type table to Markdown output when more
+// than one record type was queried
+func writeMarkdownTypes(w io.Writer, results []*BenchmarkResult) {
+	agg := aggregate(results)
+	byServer, qtypes := agg.typeStats()
+	if len(qtypes) < 2 {
+		return
+	}
+
+	fmt.Fprintf(w, "### Record Types\n\n")
+	fmt.Fprintf(w, "| Server | %s |\n", strings.Join(qtypes, " | "))
+	fmt.Fprintf(w, "|--------|%s\n", strings.Repeat("----:|", len(qtypes)))
+	for _, stats := range agg.serverStats() {
+		fmt.Fprintf(w, "| %s |", markdownEscape(stats.ServerName+" ("+stats.ServerAddr+")"))
+		for _, qtype := range qtypes {
+			if t := byServer[stats.ServerName+" - "+stats.ServerAddr][qtype]; t != nil && t.Answered > 0 {
+				fmt.Fprintf(w, " %.2f ms |", ms(t.AvgRTT))
+			} else {
+				fmt.Fprintf(w, " - |")
+			}
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	fmt.Fprintf(w, "\n")
+}
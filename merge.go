@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ResultsSource describes one run combined into a merged results file
+type ResultsSource struct {
+	Label     string       `json:"label"`
+	SavedAt   time.Time    `json:"saved_at"`
+	Mode      string       `json:"mode"`
+	Transport string       `json:"transport"`
+	Metadata  *RunMetadata `json:"metadata,omitempty"`
+	Labels    []string     `json:"labels,omitempty"`
+	Results   int          `json:"results"`
+}
+
+// runMerge implements "dnsbench merge a.json b.json -o merged.json": it
+// combines saved runs, e.g. from different machines or times, into one file
+// for the report, export and compare commands. Every result keeps the label
+// of the run it came from.
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: dnsbench merge [label=]a.json [label=]b.json... -o merged.json\n")
+		fs.PrintDefaults()
+	}
+	out := fs.String("o", "", "merged results file to write (required)")
+	split := fs.Bool("split", false, "prefix server names with the source label so each source's resolvers are summarized separately")
+	inputs := parseInterspersed(fs, args)
+	if *out == "" || len(inputs) < 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	merged, err := mergeResults(inputs, *split)
+	if err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	if err := writeResultsFile(*out, merged); err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	for _, source := range merged.Sources {
+		fmt.Printf("    %-20s %6d results, %s, saved %s\n",
+			source.Label, source.Results, strings.ToUpper(source.Transport), source.SavedAt.Format("2006-01-02 15:04"))
+	}
+	fmt.Printf("%s[✓] Merged %d results from %d runs into %s%s\n", ColorGreen, len(merged.Results), len(merged.Sources), *out, ColorReset)
+}
+
+// mergeResults loads the "[label=]path" inputs and combines them. Runs that
+// were merged before keep their own sources and labels.
+func mergeResults(inputs []string, split bool) (*ResultsFile, error) {
+	merged := &ResultsFile{Version: resultsFileVersion, SavedAt: time.Now()}
+	labels := make(map[string]bool)
+	addSource := func(source *ResultsSource) error {
+		if labels[source.Label] {
+			return fmt.Errorf("duplicate source label %q (name inputs as label=path)", source.Label)
+		}
+		labels[source.Label] = true
+		merged.Sources = append(merged.Sources, source)
+		return nil
+	}
+
+	for _, input := range inputs {
+		label, path, found := strings.Cut(input, "=")
+		if !found {
+			path = input
+		}
+		file, err := loadResults(path)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			// Runs saved with --label are named after it, others after the file
+			label = strings.Join(file.Labels, "+")
+			if label == "" {
+				label = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			}
+		}
+
+		if len(file.Sources) > 0 {
+			for _, source := range file.Sources {
+				if err := addSource(source); err != nil {
+					return nil, err
+				}
+			}
+		} else {
+			if err := addSource(&ResultsSource{
+				Label:     label,
+				SavedAt:   file.SavedAt,
+				Mode:      file.Mode,
+				Transport: file.Transport,
+				Metadata:  file.Metadata,
+				Labels:    file.Labels,
+				Results:   len(file.Results),
+			}); err != nil {
+				return nil, err
+			}
+			for _, result := range file.Results {
+				result.Source = label
+			}
+		}
+
+		for _, result := range file.Results {
+			if split && !strings.HasPrefix(result.ServerName, result.Source+"/") {
+				result.ServerName = result.Source + "/" + result.ServerName
+			}
+			merged.Results = append(merged.Results, result)
+		}
+	}
+
+	// Shared settings carry over; differing ones are marked as mixed
+	merged.Mode, merged.Transport = merged.Sources[0].Mode, merged.Sources[0].Transport
+	merged.Metadata = merged.Sources[0].Metadata
+	for _, source := range merged.Sources[1:] {
+		if source.Mode != merged.Mode {
+			merged.Mode = "mixed"
+		}
+		if source.Transport != merged.Transport {
+			merged.Transport = "mixed"
+		}
+		if source.Metadata == nil || merged.Metadata == nil || source.Metadata.PublicIP != merged.Metadata.PublicIP {
+			// Measured from different places: see the sources instead
+			merged.Metadata = nil
+		}
+	}
+	return merged, nil
+}
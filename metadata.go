@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// cloudflareTraceURL reports the client's public IP and nearest Cloudflare PoP
+const cloudflareTraceURL = "https://1.1.1.1/cdn-cgi/trace"
+
+// RunMetadata describes where a benchmark was measured from, so results
+// shared between users can be interpreted ("measured from AS7713, ID")
+type RunMetadata struct {
+	PublicIP    string          `json:"public_ip,omitempty"`
+	ASN         int             `json:"asn,omitempty"`
+	ISP         string          `json:"isp,omitempty"`
+	Country     string          `json:"country,omitempty"`
+	Colo        string          `json:"colo,omitempty"`
+	Environment *RunEnvironment `json:"environment,omitempty"`
+	CapturedAt  time.Time       `json:"captured_at"`
+}
+
+// runMetadata is captured once at start; nil when detection is disabled
+var runMetadata *RunMetadata
+
+// String renders the metadata for the configuration listing
+func (md *RunMetadata) String() string {
+	if md.PublicIP == "" {
+		return "unknown"
+	}
+	parts := []string{md.PublicIP}
+	if md.ASN > 0 {
+		parts = append(parts, fmt.Sprintf("AS%d", md.ASN))
+	}
+	if md.ISP != "" {
+		parts = append(parts, md.ISP)
+	}
+	if md.Country != "" {
+		parts = append(parts, md.Country)
+	}
+	if md.Colo != "" {
+		parts = append(parts, "Cloudflare PoP "+md.Colo)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// captureMetadata detects the public IP via the Cloudflare trace (falling
+// back to whoami.cloudflare) and its ASN and ISP via Team Cymru's DNS
+// service, queried through the given resolver
+func captureMetadata(transport string, resolver string) *RunMetadata {
+	md := &RunMetadata{CapturedAt: time.Now()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if trace, err := fetchCloudflareTrace(ctx); err == nil {
+		md.PublicIP = trace["ip"]
+		md.Country = trace["loc"]
+		md.Colo = trace["colo"]
+	} else {
+		slog.Debug("cloudflare trace failed", "err", err)
+		if proxyURL == nil {
+			// Plain UDP would bypass the proxy and report the wrong vantage point
+			if ip, err := whoamiCloudflare(ctx); err == nil {
+				md.PublicIP = ip
+			} else {
+				slog.Debug("whoami.cloudflare lookup failed", "err", err)
+			}
+		}
+	}
+	if md.PublicIP == "" || resolver == "" {
+		return md
+	}
+
+	md.ASN, md.ISP, md.Country = lookupASN(ctx, transport, resolver, md.PublicIP, md.Country)
+	slog.Debug("captured run metadata", "public_ip", md.PublicIP, "asn", md.ASN, "via", resolver)
+	return md
+}
+
+// lookupASN maps an IP to its origin ASN, AS description and registry
+// country using Team Cymru's DNS service; country is kept when already known
+func lookupASN(ctx context.Context, transport string, resolver string, addr string, country string) (int, string, string) {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return 0, "", country
+	}
+	originZone := "origin.asn.cymru.com."
+	if ip.To4() == nil {
+		originZone = "origin6.asn.cymru.com."
+	}
+	reverse, err := dns.ReverseAddr(ip.String())
+	if err != nil {
+		return 0, "", country
+	}
+	reverse = strings.TrimSuffix(strings.TrimSuffix(reverse, "in-addr.arpa."), "ip6.arpa.")
+
+	// "7713 | 36.64.0.0/11 | ID | apnic | 2009-01-06"
+	origin, err := lookupTXT(ctx, transport, resolver, reverse+originZone)
+	if err != nil {
+		return 0, "", country
+	}
+	fields := strings.Split(origin, "|")
+	origins := strings.Fields(fields[0])
+	if len(origins) == 0 {
+		return 0, "", country
+	}
+	asn, err := strconv.Atoi(origins[0])
+	if err != nil {
+		return 0, "", country
+	}
+	if country == "" && len(fields) > 2 {
+		country = strings.TrimSpace(fields[2])
+	}
+
+	// "7713 | ID | apnic | 2001-01-01 | TELKOMNET-AS-AP PT Telekomunikasi Indonesia, ID"
+	var isp string
+	if description, err := lookupTXT(ctx, transport, resolver, fmt.Sprintf("AS%d.asn.cymru.com.", asn)); err == nil {
+		if fields := strings.Split(description, "|"); len(fields) >= 5 {
+			isp = strings.TrimSpace(fields[4])
+		}
+	}
+	return asn, isp, country
+}
+
+// fetchCloudflareTrace returns the key=value pairs of the Cloudflare trace,
+// dialed through the proxy and source address like every other query
+func fetchCloudflareTrace(ctx context.Context) (map[string]string, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+				return dialStream(ctx, addr)
+			},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cloudflareTraceURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("trace: %s", resp.Status)
+	}
+
+	trace := make(map[string]string)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			trace[key] = value
+		}
+	}
+	return trace, scanner.Err()
+}
+
+// whoamiCloudflare asks 1.1.1.1 for the querying address (CHAOS TXT whoami.cloudflare)
+func whoamiCloudflare(ctx context.Context) (string, error) {
+	m := &dns.Msg{}
+	m.SetQuestion("whoami.cloudflare.", dns.TypeTXT)
+	m.Question[0].Qclass = dns.ClassCHAOS
+	r, err := exchange(ctx, m, TransportUDP, "1.1.1.1:53")
+	if err != nil {
+		return "", err
+	}
+	for _, rr := range r.Answer {
+		if txt, ok := rr.(*dns.TXT); ok && len(txt.Txt) > 0 {
+			return txt.Txt[0], nil
+		}
+	}
+	return "", fmt.Errorf("whoami.cloudflare: no answer")
+}
+
+// lookupTXT returns the first TXT string for name
+func lookupTXT(ctx context.Context, transport string, addr string, name string) (string, error) {
+	m := &dns.Msg{}
+	m.SetQuestion(name, dns.TypeTXT)
+	r, err := exchange(ctx, m, transport, addr)
+	if err != nil {
+		return "", err
+	}
+	for _, rr := range r.Answer {
+		if txt, ok := rr.(*dns.TXT); ok && len(txt.Txt) > 0 {
+			return strings.Join(txt.Txt, ""), nil
+		}
+	}
+	return "", fmt.Errorf("%s: no TXT record", name)
+}
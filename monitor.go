@@ -0,0 +1,518 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// MonitorConfig controls continuous monitoring
+type MonitorConfig struct {
+	Interval time.Duration // time between rounds
+	Duration time.Duration // total run time, 0 = until interrupted
+	Listen   string        // HTTP API address in serve mode, empty otherwise
+	Save     string        // results file kept up to date, empty to keep no results
+}
+
+// monitorSaveEvery is how often --save rewrites the results file
+const monitorSaveEvery = time.Hour
+
+// hourBucket aggregates the results of one resolver in one hour of the day
+type hourBucket struct {
+	Total   int
+	Success int
+	RTT     time.Duration // sum of successful RTTs
+}
+
+func (b *hourBucket) avg() time.Duration {
+	if b.Success == 0 {
+		return 0
+	}
+	return b.RTT / time.Duration(b.Success)
+}
+
+// rollingWindows are the sliding windows reported next to lifetime totals,
+// so a recent degradation is not drowned out by hours of good history
+var rollingWindows = []time.Duration{5 * time.Minute, time.Hour}
+
+// monitorSample is one result kept for the rolling windows
+type monitorSample struct {
+	At      time.Time
+	RTT     time.Duration
+	Success bool
+}
+
+// seriesRetention is how far back the per-round time series of the serve
+// API reach
+const seriesRetention = 7 * 24 * time.Hour
+
+// seriesPoint is one resolver's results in one round
+type seriesPoint struct {
+	At time.Time
+	hourBucket
+}
+
+// monitorStats aggregates monitoring results per resolver without keeping
+// every result, so sessions can run for days; only samples inside the
+// longest rolling window and one point per round are retained
+type monitorStats struct {
+	mu       sync.Mutex
+	order    []string
+	hourly   map[string]*[24]hourBucket
+	lifetime map[string]*hourBucket
+	samples  map[string][]monitorSample
+	series   map[string][]seriesPoint
+	sla      map[string]*slaTally
+}
+
+func newMonitorStats() *monitorStats {
+	return &monitorStats{
+		hourly:   make(map[string]*[24]hourBucket),
+		lifetime: make(map[string]*hourBucket),
+		samples:  make(map[string][]monitorSample),
+		series:   make(map[string][]seriesPoint),
+		sla:      make(map[string]*slaTally),
+	}
+}
+
+// add records one result in the bucket of the hour it was sent
+func (s *monitorStats) add(result *BenchmarkResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buckets, ok := s.hourly[result.ServerName]
+	if !ok {
+		buckets = &[24]hourBucket{}
+		s.hourly[result.ServerName] = buckets
+		s.lifetime[result.ServerName] = &hourBucket{}
+		s.sla[result.ServerName] = &slaTally{}
+		s.order = append(s.order, result.ServerName)
+	}
+	success := result.Status == "SUCCESS"
+	for _, bucket := range []*hourBucket{&buckets[result.Timestamp.Hour()], s.lifetime[result.ServerName]} {
+		bucket.Total++
+		if success {
+			bucket.Success++
+			bucket.RTT += result.RTT
+		}
+	}
+	s.sla[result.ServerName].add(result)
+
+	// Drop samples that fell out of the longest window
+	samples := append(s.samples[result.ServerName], monitorSample{result.Timestamp, result.RTT, success})
+	cutoff := result.Timestamp.Add(-rollingWindows[len(rollingWindows)-1])
+	for len(samples) > 0 && samples[0].At.Before(cutoff) {
+		samples = samples[1:]
+	}
+	s.samples[result.ServerName] = samples
+}
+
+// roundBuckets totals a round's results per resolver
+func roundBuckets(roundResults []*BenchmarkResult) map[string]*hourBucket {
+	round := make(map[string]*hourBucket)
+	for _, result := range roundResults {
+		bucket, ok := round[result.ServerName]
+		if !ok {
+			bucket = &hourBucket{}
+			round[result.ServerName] = bucket
+		}
+		bucket.Total++
+		if result.Status == "SUCCESS" {
+			bucket.Success++
+			bucket.RTT += result.RTT
+		}
+	}
+	return round
+}
+
+// addRound records one point per resolver for a round started at, dropping
+// points older than seriesRetention
+func (s *monitorStats) addRound(at time.Time, roundResults []*BenchmarkResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := at.Add(-seriesRetention)
+	for name, bucket := range roundBuckets(roundResults) {
+		series := append(s.series[name], seriesPoint{at, *bucket})
+		for len(series) > 0 && series[0].At.Before(cutoff) {
+			series = series[1:]
+		}
+		s.series[name] = series
+	}
+}
+
+// seriesRange returns a resolver's round points between from and to,
+// merged into buckets of step when step is longer than the rounds
+func (s *monitorStats) seriesRange(name string, from, to time.Time, step time.Duration) []seriesPoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var points []seriesPoint
+	for _, point := range s.series[name] {
+		if point.At.Before(from) || point.At.After(to) {
+			continue
+		}
+		if step > 0 {
+			at := point.At.Truncate(step)
+			if n := len(points); n > 0 && points[n-1].At.Equal(at) {
+				last := &points[n-1]
+				last.Total += point.Total
+				last.Success += point.Success
+				last.RTT += point.RTT
+				continue
+			}
+			point.At = at
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// window aggregates a resolver's samples sent within d before now
+func (s *monitorStats) window(name string, d time.Duration, now time.Time) hourBucket {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var bucket hourBucket
+	cutoff := now.Add(-d)
+	for _, sample := range s.samples[name] {
+		if sample.At.Before(cutoff) {
+			continue
+		}
+		bucket.Total++
+		if sample.Success {
+			bucket.Success++
+			bucket.RTT += sample.RTT
+		}
+	}
+	return bucket
+}
+
+// runMonitor queries every server once per domain each interval until the
+// duration elapses or the user interrupts, then prints time-of-day statistics.
+// SIGHUP reloads the settings: the report so far is printed and the process
+// restarts itself with the same command line.
+func runMonitor(config *BenchmarkConfig) {
+	cfg := config.Monitor
+	ctx, stop := signal.NotifyContext(serviceCtx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	fmt.Printf("%s[*] Monitoring every %v", ColorBlue, cfg.Interval)
+	if cfg.Duration > 0 {
+		fmt.Printf(" for %v", cfg.Duration)
+	}
+	fmt.Printf(" (Ctrl+C to stop and print the report)...%s\n\n", ColorReset)
+
+	stats := newMonitorStats()
+	started := time.Now()
+	var rounds atomic.Int64
+	if cfg.Listen != "" {
+		srv := &monitorServer{stats: stats, started: started, rounds: func() int { return int(rounds.Load()) }}
+		if err := startMonitorServer(cfg.Listen, srv); err != nil {
+			slog.Error("Cannot serve the API", "err", err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s[*] Serving status at http://%s/api/status, Grafana JSON datasource at http://%s/grafana%s\n\n", ColorBlue, cfg.Listen, cfg.Listen, ColorReset)
+	}
+
+	saver := newMonitorSaver(config)
+	alerts := newAlerter()
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	reload := make(chan os.Signal, 1)
+	notifyReload(reload)
+
+	for round := 1; ; round++ {
+		roundStarted := time.Now()
+		roundResults := runMonitorRound(config)
+		for _, result := range roundResults {
+			stats.add(result)
+		}
+		stats.addRound(roundStarted, roundResults)
+		saver.add(roundResults)
+		rounds.Store(int64(round))
+		printMonitorRound(round, roundResults, stats)
+		alerts.round(roundStarted, roundResults)
+		for _, export := range []func([]*BenchmarkResult) error{exportOTLP, exportStatsD, exportGraphite, exportMQTT} {
+			if err := export(roundResults); err != nil {
+				slog.Warn("Export failed", "err", err)
+			}
+		}
+
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				printTimeOfDay(stats)
+				printSLA(stats, started, round)
+				saver.save()
+				return
+			case <-reload:
+				reloadMonitor(stats, saver, started, round)
+			case <-ticker.C:
+				break wait
+			}
+		}
+	}
+}
+
+// reloadMonitor restarts monitoring with the current settings file and
+// flags. They are checked first, so a broken settings file leaves the
+// running monitor alone instead of stopping the service.
+func reloadMonitor(stats *monitorStats, saver *monitorSaver, started time.Time, rounds int) {
+	if err := checkArgs(os.Args[1:]); err != nil {
+		slog.Error("Reload failed, keeping the running settings", "err", err)
+		return
+	}
+	slog.Info("Reloading settings, report so far follows")
+	printTimeOfDay(stats)
+	printSLA(stats, started, rounds)
+	saver.save()
+	if err := reexec(); err != nil {
+		slog.Error("Reload failed", "err", err)
+	}
+}
+
+// monitorSaver keeps the raw results for --save, which the monitor does not
+// otherwise retain, and writes them every monitorSaveEvery
+type monitorSaver struct {
+	config  *BenchmarkConfig
+	results []*BenchmarkResult
+	saved   time.Time
+}
+
+// newMonitorSaver starts from the results of an existing monitor file, so
+// restarts and reloads of a service add to the same history; it returns
+// nil without --save
+func newMonitorSaver(config *BenchmarkConfig) *monitorSaver {
+	path := config.Monitor.Save
+	if path == "" {
+		return nil
+	}
+	saver := &monitorSaver{config: config, saved: time.Now()}
+	if file, err := loadResults(path); err == nil && file.Mode == ModeMonitor {
+		saver.results = file.Results
+		fmt.Printf("%s[*] Continuing %d results saved in %s%s\n\n", ColorBlue, len(file.Results), path, ColorReset)
+	} else if err == nil {
+		slog.Warn("Not a monitor results file, it will be overwritten", "path", path)
+	}
+	return saver
+}
+
+// add keeps a round's results, saving when the last save is an interval old
+func (s *monitorSaver) add(roundResults []*BenchmarkResult) {
+	if s == nil {
+		return
+	}
+	s.results = append(s.results, roundResults...)
+	if time.Since(s.saved) >= monitorSaveEvery {
+		s.save()
+	}
+}
+
+// save writes the kept results
+func (s *monitorSaver) save() {
+	if s == nil {
+		return
+	}
+	s.saved = time.Now()
+	if err := saveResults(s.config.Monitor.Save, s.config, s.results); err != nil {
+		slog.Error("Failed to save results", "path", s.config.Monitor.Save, "err", err)
+		return
+	}
+	slog.Debug("saved monitor results", "path", s.config.Monitor.Save, "results", len(s.results))
+}
+
+// runMonitorRound sends one query per domain to every endpoint
+func runMonitorRound(config *BenchmarkConfig) []*BenchmarkResult {
+	var jobs []queryJob
+	for _, server := range config.Servers {
+		for _, addr := range server.endpoints(config.Transport) {
+			for _, domain := range config.Domains {
+				jobs = append(jobs, queryJob{ServerName: server.Name, ServerAddr: addr, Transport: config.Transport, Domain: domain})
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	var roundMu sync.Mutex
+	var roundResults []*BenchmarkResult
+	jobChan := make(chan queryJob)
+	for w := 0; w < config.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobChan {
+				result := queryDNS(job)
+				roundMu.Lock()
+				roundResults = append(roundResults, result)
+				roundMu.Unlock()
+			}
+		}()
+	}
+	for _, job := range jobs {
+		jobChan <- job
+	}
+	close(jobChan)
+	wg.Wait()
+	return roundResults
+}
+
+// printMonitorRound prints each resolver's round average next to its
+// rolling windows and lifetime totals; a window noticeably slower than the
+// lifetime average is flagged
+func printMonitorRound(round int, roundResults []*BenchmarkResult, stats *monitorStats) {
+	current := roundBuckets(roundResults)
+
+	stats.mu.Lock()
+	names := append([]string(nil), stats.order...)
+	stats.mu.Unlock()
+	sort.Strings(names)
+	now := time.Now()
+
+	fmt.Printf("%s[%s] Round %d%s\n", ColorCyan, consoleTime(now, "15:04:05"), round, ColorReset)
+	fmt.Printf("%s    %-28s | %-16s", ColorWhite, "Resolver", "Round")
+	for _, d := range rollingWindows {
+		fmt.Printf(" | %-16s", "Last "+shortDuration(d))
+	}
+	fmt.Printf(" | %-16s%s\n", "Lifetime", ColorReset)
+
+	for _, name := range names {
+		bucket, ok := current[name]
+		if !ok {
+			continue
+		}
+		stats.mu.Lock()
+		lifetime := *stats.lifetime[name]
+		stats.mu.Unlock()
+
+		fmt.Printf("    %-28s | %s", name, windowCell(*bucket, lifetime))
+		for _, d := range rollingWindows {
+			fmt.Printf(" | %s", windowCell(stats.window(name, d, now), lifetime))
+		}
+		fmt.Printf(" | %s\n", windowCell(lifetime, lifetime))
+	}
+}
+
+// windowCell renders average RTT and loss, highlighting a window that is
+// over 50% slower than the lifetime average or losing queries
+func windowCell(bucket hourBucket, lifetime hourBucket) string {
+	if bucket.Total == 0 {
+		return fmt.Sprintf("%-16s", "-")
+	}
+	loss := float64(bucket.Total-bucket.Success) / float64(bucket.Total) * 100
+	cell := fmt.Sprintf("%8.2f ms %3.0f%%", ms(bucket.avg()), loss)
+	switch {
+	case loss > 0:
+		return ColorRed + cell + ColorReset
+	case lifetime.avg() > 0 && bucket.avg() > lifetime.avg()*3/2:
+		return ColorYellow + cell + ColorReset
+	}
+	return cell
+}
+
+// shortDuration formats a window length as 5m or 1h
+func shortDuration(d time.Duration) string {
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+// sparkBlocks render relative values from lowest to highest
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// printTimeOfDay prints average RTT per resolver for each hour of the day
+// that has data, plus a 24-hour sparkline per resolver
+func printTimeOfDay(stats *monitorStats) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	fmt.Printf("\n%s╔════════════════════════════════════════════════════════════╗%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s║                TIME-OF-DAY REPORT (avg RTT)                ║%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s╚════════════════════════════════════════════════════════════╝%s\n\n", ColorCyan, ColorReset)
+
+	if len(stats.order) == 0 {
+		fmt.Printf("%s[!] No results recorded%s\n", ColorYellow, ColorReset)
+		return
+	}
+
+	fmt.Printf("%s%-5s", ColorWhite, "Hour")
+	for _, name := range stats.order {
+		fmt.Printf(" | %-14.14s", name)
+	}
+	fmt.Printf("%s\n", ColorReset)
+
+	for hour := 0; hour < 24; hour++ {
+		hasData := false
+		for _, name := range stats.order {
+			if stats.hourly[name][hour].Total > 0 {
+				hasData = true
+			}
+		}
+		if !hasData {
+			continue
+		}
+
+		fmt.Printf("%02d:00", hour)
+		for _, name := range stats.order {
+			bucket := stats.hourly[name][hour]
+			switch {
+			case bucket.Total == 0:
+				fmt.Printf(" | %14s", "-")
+			case bucket.Success < bucket.Total:
+				loss := float64(bucket.Total-bucket.Success) / float64(bucket.Total) * 100
+				fmt.Printf(" | %s%8.2f ms %2.0f%%%s", ColorRed, ms(bucket.avg()), loss, ColorReset)
+			default:
+				fmt.Printf(" | %11.2f ms", ms(bucket.avg()))
+			}
+		}
+		fmt.Printf("\n")
+	}
+
+	fmt.Printf("\n%s[*] 24-hour profile (00 → 23, taller = slower):%s\n\n", ColorBlue, ColorReset)
+	for _, name := range stats.order {
+		fmt.Printf("    %-28s %s\n", name, sparkline(stats.hourly[name]))
+	}
+	fmt.Printf("\n")
+}
+
+// sparkline renders hourly averages relative to the resolver's own range
+func sparkline(buckets *[24]hourBucket) string {
+	var low, high time.Duration
+	for _, bucket := range buckets {
+		if avg := bucket.avg(); avg > 0 {
+			if low == 0 || avg < low {
+				low = avg
+			}
+			high = max(high, avg)
+		}
+	}
+
+	line := make([]rune, 24)
+	for hour, bucket := range buckets {
+		avg := bucket.avg()
+		switch {
+		case avg == 0:
+			line[hour] = '·'
+		case high == low:
+			line[hour] = sparkBlocks[0]
+		default:
+			level := int(float64(avg-low) / float64(high-low) * float64(len(sparkBlocks)-1))
+			line[hour] = sparkBlocks[level]
+		}
+	}
+	return string(line)
+}
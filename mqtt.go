@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// mqttTimeout bounds the whole publish session with the broker
+const mqttTimeout = 10 * time.Second
+
+var (
+	// mqttBroker is the broker URL (mqtt://[user:pass@]host[:1883]); nil when disabled
+	mqttBroker *url.URL
+	// mqttTopic is the topic prefix; each resolver publishes to <prefix>/<resolver>/state
+	mqttTopic string
+)
+
+// configureMQTT validates the broker URL and topic prefix
+func configureMQTT(broker string, topic string) error {
+	if broker == "" {
+		return nil
+	}
+	if !strings.Contains(broker, "://") {
+		broker = "mqtt://" + broker
+	}
+	u, err := url.Parse(broker)
+	if err != nil || u.Scheme != "mqtt" || u.Hostname() == "" {
+		return fmt.Errorf("invalid --mqtt %q (want mqtt://[user:pass@]host[:1883])", broker)
+	}
+	if _, hasPassword := u.User.Password(); hasPassword && u.User.Username() == "" {
+		// MQTT 3.1.1 section 3.1.2.9 only allows a password with a user name
+		return fmt.Errorf("invalid --mqtt %q: a password needs a user name", u.Redacted())
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "1883")
+	}
+	topic = strings.Trim(topic, "/")
+	if topic == "" || strings.ContainsAny(topic, "+#") {
+		return fmt.Errorf("invalid --mqtt-topic %q: must be non-empty without wildcards", topic)
+	}
+	mqttBroker = u
+	mqttTopic = topic
+	return nil
+}
+
+// ResolverState is the retained per-resolver payload, shaped for Home
+// Assistant MQTT sensors (value_template: "{{ value_json.avg_rtt_ms }}")
+type ResolverState struct {
+	Resolver    string    `json:"resolver"`
+	Queries     int       `json:"queries"`
+	SuccessRate float64   `json:"success_rate"`
+	AvgRTT      float64   `json:"avg_rtt_ms"`
+	P95RTT      float64   `json:"p95_rtt_ms"`
+	Healthy     bool      `json:"healthy"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// resolverStates aggregates results per resolver, primary and secondary together
+func resolverStates(results []*BenchmarkResult, now time.Time) []ResolverState {
+	var states []ResolverState
+	for _, provider := range aggregate(results).providerStats() {
+		state := ResolverState{
+			Resolver:    provider.Name,
+			Queries:     provider.TotalQueries,
+			SuccessRate: provider.SuccessRate(),
+			Healthy:     provider.SuccessQueries > 0,
+			Timestamp:   now,
+		}
+		if provider.SuccessQueries > 0 {
+			state.AvgRTT = ms(provider.AvgRTT)
+			state.P95RTT = ms(percentile(provider.rtts, 95))
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// mqttString encodes a length-prefixed UTF-8 string
+func mqttString(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.BigEndian, uint16(len(s)))
+	buf.WriteString(s)
+}
+
+// mqttPacket writes a control packet with its variable-length remaining length
+func mqttPacket(w io.Writer, header byte, body []byte) error {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if n == 0 {
+			break
+		}
+	}
+	_, err := w.Write(append(packet, body...))
+	return err
+}
+
+// exportMQTT publishes one retained state message per resolver using MQTT
+// 3.1.1 at QoS 0: CONNECT, wait for CONNACK, PUBLISH each state, DISCONNECT
+func exportMQTT(results []*BenchmarkResult) error {
+	if mqttBroker == nil || len(results) == 0 {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", mqttBroker.Host, mqttTimeout)
+	if err != nil {
+		return fmt.Errorf("mqtt: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(mqttTimeout))
+
+	var connect bytes.Buffer
+	mqttString(&connect, "MQTT")
+	connect.WriteByte(4) // protocol level 3.1.1
+	flags := byte(0x02)  // clean session
+	user := mqttBroker.User.Username()
+	password, hasPassword := mqttBroker.User.Password()
+	if user != "" {
+		flags |= 0x80
+	}
+	if hasPassword {
+		flags |= 0x40
+	}
+	connect.WriteByte(flags)
+	binary.Write(&connect, binary.BigEndian, uint16(60)) // keep alive
+	mqttString(&connect, fmt.Sprintf("dnsbench-%d", time.Now().UnixNano()%1e9))
+	if user != "" {
+		mqttString(&connect, user)
+	}
+	if hasPassword {
+		mqttString(&connect, password)
+	}
+	if err := mqttPacket(conn, 0x10, connect.Bytes()); err != nil {
+		return fmt.Errorf("mqtt: %w", err)
+	}
+
+	connack := make([]byte, 4)
+	if _, err := io.ReadFull(conn, connack); err != nil {
+		return fmt.Errorf("mqtt: reading CONNACK: %w", err)
+	}
+	if connack[0] != 0x20 {
+		return fmt.Errorf("mqtt: unexpected packet 0x%02x instead of CONNACK", connack[0])
+	}
+	if connack[3] != 0 {
+		return fmt.Errorf("mqtt: broker refused connection (return code %d)", connack[3])
+	}
+
+	w := bufio.NewWriter(conn)
+	for _, state := range resolverStates(results, time.Now()) {
+		payload, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		var publish bytes.Buffer
+		mqttString(&publish, mqttTopic+"/"+graphiteNode(state.Resolver)+"/state")
+		publish.Write(payload)
+		// PUBLISH, QoS 0, retained so dashboards show the last state on connect
+		if err := mqttPacket(w, 0x31, publish.Bytes()); err != nil {
+			return fmt.Errorf("mqtt: %w", err)
+		}
+	}
+	if err := mqttPacket(w, 0xE0, nil); err != nil {
+		return fmt.Errorf("mqtt: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("mqtt: %w", err)
+	}
+	return nil
+}
+
+// reportMQTT exports results and prints the outcome
+func reportMQTT(results []*BenchmarkResult) {
+	if mqttBroker == nil {
+		return
+	}
+	if err := exportMQTT(results); err != nil {
+		slog.Warn("Export failed", "err", err)
+		return
+	}
+	fmt.Printf("%s[✓] Published resolver states to %s under %s/+/state%s\n", ColorGreen, mqttBroker.Redacted(), mqttTopic, ColorReset)
+}
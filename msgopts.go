@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+var (
+	// queryRD, queryCD, queryAD and queryDOBit are the bits benchmark queries
+	// carry; RD=1 and the rest unset is what stub resolvers send
+	queryRD      = true
+	queryCD      bool
+	queryAD      bool
+	queryDOBit   bool
+	queryOptions []dns.EDNS0 // --edns-opt, added to every benchmark query
+)
+
+// ednsOptionCodes are the option names --edns-opt accepts besides numbers
+var ednsOptionCodes = map[string]uint16{
+	"nsid":      dns.EDNS0NSID,
+	"ecs":       dns.EDNS0SUBNET,
+	"expire":    dns.EDNS0EXPIRE,
+	"cookie":    dns.EDNS0COOKIE,
+	"keepalive": dns.EDNS0TCPKEEPALIVE,
+	"padding":   dns.EDNS0PADDING,
+}
+
+// ednsOptFlag collects EDNS options given as CODE[:HEX] like dig's +ednsopt,
+// where CODE is a number or one of ednsOptionCodes
+type ednsOptFlag struct{}
+
+func (ednsOptFlag) String() string { return "" }
+
+func (ednsOptFlag) Set(value string) error {
+	name, data, _ := strings.Cut(value, ":")
+	code, ok := ednsOptionCodes[strings.ToLower(name)]
+	if !ok {
+		n, err := strconv.ParseUint(name, 10, 16)
+		if err != nil {
+			return fmt.Errorf("invalid EDNS option %q (want CODE[:HEX], e.g. nsid or 65001:beef)", value)
+		}
+		code = uint16(n)
+	}
+	raw, err := hex.DecodeString(data)
+	if err != nil {
+		return fmt.Errorf("invalid EDNS option %q: value is not hex", value)
+	}
+	queryOptions = append(queryOptions, &dns.EDNS0_LOCAL{Code: code, Data: raw})
+	return nil
+}
+
+// applyQueryFlags sets the header bits and EDNS options chosen with --rd,
+// --cd, --ad, --do and --edns-opt on an outgoing query
+func applyQueryFlags(m *dns.Msg) {
+	m.RecursionDesired, m.CheckingDisabled, m.AuthenticatedData = queryRD, queryCD, queryAD
+	if !queryDOBit && len(queryOptions) == 0 {
+		return
+	}
+	opt := m.IsEdns0()
+	if opt == nil {
+		m.SetEdns0(dns.DefaultMsgSize, false)
+		opt = m.IsEdns0()
+	}
+	if queryDOBit {
+		opt.SetDo()
+	}
+	opt.Option = append(opt.Option, queryOptions...)
+}
+
+// queryFlagsString describes non-default query flags for the configuration
+// listing, or returns "" when queries go out as a stub resolver sends them
+func queryFlagsString() string {
+	if queryRD && !queryCD && !queryAD && !queryDOBit && len(queryOptions) == 0 {
+		return ""
+	}
+	bit := func(set bool) int {
+		if set {
+			return 1
+		}
+		return 0
+	}
+	s := fmt.Sprintf("RD=%d CD=%d AD=%d DO=%d", bit(queryRD), bit(queryCD), bit(queryAD), bit(queryDOBit))
+	var options []string
+	for _, option := range queryOptions {
+		local := option.(*dns.EDNS0_LOCAL)
+		options = append(options, strconv.Itoa(int(local.Code)))
+		if len(local.Data) > 0 {
+			options[len(options)-1] += ":" + hex.EncodeToString(local.Data)
+		}
+	}
+	if len(options) > 0 {
+		s += ", EDNS options " + strings.Join(options, ", ")
+	}
+	return s
+}
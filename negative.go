@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// negativeRepeatInterval spaces repeated queries so cached TTLs visibly decrease
+const negativeRepeatInterval = 1100 * time.Millisecond
+
+// negativeAnswer is one NXDOMAIN response and the negative TTL it carried
+type negativeAnswer struct {
+	RTT time.Duration
+	TTL uint32
+	NX  bool
+}
+
+// queryNegative asks for name and returns the negative TTL from the SOA in
+// the authority section (RFC 2308: min of SOA TTL and SOA minimum)
+func queryNegative(transport string, addr string, name string) (*negativeAnswer, error) {
+	m := &dns.Msg{}
+	m.SetQuestion(queryName(name), dns.TypeA)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	start := time.Now()
+	r, err := exchange(ctx, m, transport, addr)
+	answer := &negativeAnswer{RTT: time.Since(start)}
+	if err != nil {
+		return nil, err
+	}
+	answer.NX = r.Rcode == dns.RcodeNameError
+	for _, rr := range r.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			answer.TTL = min(soa.Hdr.Ttl, soa.Minttl)
+		}
+	}
+	return answer, nil
+}
+
+// runNegativeCache repeats an NXDOMAIN query per resolver and reports
+// whether later answers come from the negative cache: faster than the cold
+// lookup and with a negative TTL that counts down
+func runNegativeCache(config *BenchmarkConfig) {
+	repeats := max(config.QueryNum, 2)
+	fmt.Printf("%s[*] Measuring negative caching (%d repeats of a fresh NXDOMAIN per server)...%s\n\n", ColorBlue, repeats, ColorReset)
+
+	fmt.Printf("%s%-30s | %-12s | %-12s | %-8s | %-9s | %-10s | %-9s%s\n",
+		ColorWhite, "Server", "Cold RTT", "Cached p50", "Speedup", "Neg. TTL", "Cache Hits", "Effective", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼──────────────┼──────────────┼──────────┼───────────┼────────────┼──────────", ColorReset)
+
+	// Parent zone with a real SOA, so the negative TTL is meaningful
+	zone := "com"
+	if len(config.Domains) > 0 {
+		zone = config.Domains[0]
+	}
+
+	for _, server := range config.Servers {
+		for _, addr := range server.endpoints(config.Transport) {
+			display := fmt.Sprintf("%s (%s)", server.Name, addr)
+			name := randomLabel() + "." + zone
+
+			cold, err := queryNegative(config.Transport, addr, name)
+			if err != nil {
+				fmt.Printf("%-30s | %s%s%s\n", display, ColorRed, err, ColorReset)
+				continue
+			}
+			if !cold.NX {
+				fmt.Printf("%-30s | %s%s%s\n", display, ColorYellow, "no NXDOMAIN (rewritten? see --mode wildcard)", ColorReset)
+				continue
+			}
+
+			var cached []time.Duration
+			hits := 0
+			for i := 0; i < repeats; i++ {
+				time.Sleep(negativeRepeatInterval)
+				answer, err := queryNegative(config.Transport, addr, name)
+				if err != nil {
+					continue
+				}
+				cached = append(cached, answer.RTT)
+				// A counting-down TTL means the answer came from the cache
+				if answer.NX && answer.TTL < cold.TTL {
+					hits++
+				}
+			}
+
+			p50 := percentile(cached, 50)
+			speedup := 0.0
+			if p50 > 0 {
+				speedup = float64(cold.RTT) / float64(p50)
+			}
+			effective := ColorRed + "no" + ColorReset
+			if hits*2 >= repeats || speedup >= 2 {
+				effective = ColorGreen + "yes" + ColorReset
+			}
+
+			fmt.Printf("%-30s | %8.2f ms | %8.2f ms | %7.1fx | %8ds | %6d/%-3d | %s\n",
+				display, ms(cold.RTT), ms(p50), speedup, cold.TTL, hits, repeats, effective)
+		}
+	}
+
+	fmt.Printf("\n%s    Cache hits are repeats whose negative TTL had counted down; anycast resolvers%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s    with many independent caches may still miss, which shows up as a low hit count%s\n", ColorCyan, ColorReset)
+}
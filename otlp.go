@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// otlpTimeout bounds each export so a dead collector never stalls a run
+const otlpTimeout = 10 * time.Second
+
+// otlpBounds are the explicit histogram bucket bounds for query RTT, in ms
+var otlpBounds = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000}
+
+var (
+	// otlpEndpoint is the OTLP/HTTP collector base URL; nil when export is disabled
+	otlpEndpoint *url.URL
+	// otlpSpans also exports one span per query
+	otlpSpans bool
+)
+
+// configureOTLP validates the collector URL (e.g. http://localhost:4318)
+func configureOTLP(endpoint string, spans bool) error {
+	if endpoint == "" {
+		if spans {
+			return fmt.Errorf("--otlp-spans requires --otlp-endpoint")
+		}
+		return nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid --otlp-endpoint %q (want http://host:4318)", endpoint)
+	}
+	otlpEndpoint = u
+	otlpSpans = spans
+	return nil
+}
+
+// OTLP/JSON encoding (opentelemetry-proto); 64-bit integers are strings
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpHistogramPoint struct {
+	Attributes        []otlpAttribute `json:"attributes"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	Count             string          `json:"count"`
+	Sum               float64         `json:"sum"`
+	Min               float64         `json:"min"`
+	Max               float64         `json:"max"`
+	BucketCounts      []string        `json:"bucketCounts"`
+	ExplicitBounds    []float64       `json:"explicitBounds"`
+}
+
+type otlpNumberPoint struct {
+	Attributes        []otlpAttribute `json:"attributes"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	AsInt             string          `json:"asInt"`
+}
+
+type otlpHistogram struct {
+	DataPoints             []otlpHistogramPoint `json:"dataPoints"`
+	AggregationTemporality int                  `json:"aggregationTemporality"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpNumberPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+type otlpMetric struct {
+	Name      string         `json:"name"`
+	Unit      string         `json:"unit"`
+	Histogram *otlpHistogram `json:"histogram,omitempty"`
+	Sum       *otlpSum       `json:"sum,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes"`
+	Status            struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+// OTLP enum values
+const (
+	otlpTemporalityDelta = 1
+	otlpSpanKindClient   = 3
+	otlpStatusOK         = 1
+	otlpStatusError      = 2
+)
+
+func otlpString(key string, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: &value}}
+}
+
+func otlpInt(key string, value int) otlpAttribute {
+	s := strconv.Itoa(value)
+	return otlpAttribute{Key: key, Value: otlpValue{IntValue: &s}}
+}
+
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// otlpResourceAttributes identify the exporter and where it measured from
+func otlpResourceAttributes() otlpResource {
+	attrs := []otlpAttribute{otlpString("service.name", "dnsbench")}
+	if runMetadata != nil && runMetadata.PublicIP != "" {
+		attrs = append(attrs, otlpString("client.address", runMetadata.PublicIP))
+		if runMetadata.ASN > 0 {
+			attrs = append(attrs, otlpInt("client.asn", runMetadata.ASN))
+		}
+		if runMetadata.Country != "" {
+			attrs = append(attrs, otlpString("client.country", runMetadata.Country))
+		}
+	}
+	return otlpResource{Attributes: attrs}
+}
+
+// exportOTLP sends the RTT histogram and query counts of results as delta
+// metrics, plus a span per query when enabled; every export covers only the
+// results passed in, so monitor rounds can be exported one at a time
+func exportOTLP(results []*BenchmarkResult) error {
+	if otlpEndpoint == nil || len(results) == 0 {
+		return nil
+	}
+
+	start, end := results[0].Timestamp, results[0].Timestamp
+	for _, result := range results {
+		if result.Timestamp.Before(start) {
+			start = result.Timestamp
+		}
+		if done := result.Timestamp.Add(result.RTT); done.After(end) {
+			end = done
+		}
+	}
+
+	if err := postOTLP("/v1/metrics", map[string]any{
+		"resourceMetrics": []map[string]any{{
+			"resource": otlpResourceAttributes(),
+			"scopeMetrics": []map[string]any{{
+				"scope":   otlpScope{Name: "dnsbench"},
+				"metrics": otlpMetrics(results, start, end),
+			}},
+		}},
+	}); err != nil {
+		return err
+	}
+
+	if !otlpSpans {
+		return nil
+	}
+	return postOTLP("/v1/traces", map[string]any{
+		"resourceSpans": []map[string]any{{
+			"resource": otlpResourceAttributes(),
+			"scopeSpans": []map[string]any{{
+				"scope": otlpScope{Name: "dnsbench"},
+				"spans": otlpQuerySpans(results),
+			}},
+		}},
+	})
+}
+
+// otlpMetrics aggregates results per server endpoint: dns.query.duration
+// (successful queries) and dns.query.count (by status)
+func otlpMetrics(results []*BenchmarkResult, start time.Time, end time.Time) []otlpMetric {
+	type series struct {
+		attrs []otlpAttribute
+		rtts  []float64
+	}
+	histograms := make(map[string]*series)
+	type counter struct {
+		attrs []otlpAttribute
+		n     int
+	}
+	counts := make(map[string]*counter)
+
+	for _, result := range results {
+		endpoint := []otlpAttribute{
+			otlpString("dns.server.name", result.ServerName),
+			otlpString("server.address", result.ServerAddr),
+			otlpString("network.transport", result.Transport),
+		}
+
+		countKey := result.ServerName + "|" + result.ServerAddr + "|" + result.Status
+		c, ok := counts[countKey]
+		if !ok {
+			c = &counter{attrs: append(slices.Clone(endpoint), otlpString("dns.status", result.Status))}
+			counts[countKey] = c
+		}
+		c.n++
+
+		if result.Status != "SUCCESS" {
+			continue
+		}
+		key := result.ServerName + "|" + result.ServerAddr
+		s, ok := histograms[key]
+		if !ok {
+			s = &series{attrs: endpoint}
+			histograms[key] = s
+		}
+		s.rtts = append(s.rtts, ms(result.RTT))
+	}
+
+	duration := otlpMetric{Name: "dns.query.duration", Unit: "ms"}
+	duration.Histogram = &otlpHistogram{AggregationTemporality: otlpTemporalityDelta}
+	for _, s := range histograms {
+		buckets := make([]int, len(otlpBounds)+1)
+		point := otlpHistogramPoint{
+			Attributes:        s.attrs,
+			StartTimeUnixNano: otlpTime(start),
+			TimeUnixNano:      otlpTime(end),
+			Count:             strconv.Itoa(len(s.rtts)),
+			Min:               s.rtts[0],
+			Max:               s.rtts[0],
+			ExplicitBounds:    otlpBounds,
+		}
+		for _, rtt := range s.rtts {
+			point.Sum += rtt
+			point.Min = min(point.Min, rtt)
+			point.Max = max(point.Max, rtt)
+			buckets[sort.SearchFloat64s(otlpBounds, rtt)]++
+		}
+		for _, count := range buckets {
+			point.BucketCounts = append(point.BucketCounts, strconv.Itoa(count))
+		}
+		duration.Histogram.DataPoints = append(duration.Histogram.DataPoints, point)
+	}
+
+	count := otlpMetric{Name: "dns.query.count", Unit: "{query}"}
+	count.Sum = &otlpSum{AggregationTemporality: otlpTemporalityDelta, IsMonotonic: true}
+	for _, c := range counts {
+		count.Sum.DataPoints = append(count.Sum.DataPoints, otlpNumberPoint{
+			Attributes:        c.attrs,
+			StartTimeUnixNano: otlpTime(start),
+			TimeUnixNano:      otlpTime(end),
+			AsInt:             strconv.Itoa(c.n),
+		})
+	}
+
+	return []otlpMetric{duration, count}
+}
+
+// otlpQuerySpans turns every result into a client span of one trace
+func otlpQuerySpans(results []*BenchmarkResult) []otlpSpan {
+	traceID := otlpID(16)
+	spans := make([]otlpSpan, 0, len(results))
+	for _, result := range results {
+		span := otlpSpan{
+			TraceID:           traceID,
+			SpanID:            otlpID(8),
+			Name:              "dns.query " + result.Domain,
+			Kind:              otlpSpanKindClient,
+			StartTimeUnixNano: otlpTime(result.Timestamp),
+			EndTimeUnixNano:   otlpTime(result.Timestamp.Add(result.RTT)),
+			Attributes: []otlpAttribute{
+				otlpString("dns.server.name", result.ServerName),
+				otlpString("server.address", result.ServerAddr),
+				otlpString("network.transport", result.Transport),
+				otlpString("dns.question.name", result.Domain),
+				otlpString("dns.status", result.Status),
+			},
+		}
+		if result.Rcode != "" {
+			span.Attributes = append(span.Attributes, otlpString("dns.response.rcode", result.Rcode))
+		}
+		span.Status.Code = otlpStatusOK
+		if result.Status != "SUCCESS" {
+			span.Status.Code = otlpStatusError
+			span.Status.Message = result.Error
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// otlpID returns n random bytes hex-encoded, as OTLP/JSON expects for IDs
+func otlpID(n int) string {
+	id := make([]byte, n)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// postOTLP sends one export request; the collector is local infrastructure,
+// so it is reached directly rather than through --proxy
+func postOTLP(path string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), otlpTimeout)
+	defer cancel()
+
+	target := otlpEndpoint.JoinPath(path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("otlp export: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("otlp export to %s: %s", target.Redacted(), resp.Status)
+	}
+	return nil
+}
+
+// reportOTLP exports results and prints the outcome
+func reportOTLP(results []*BenchmarkResult) {
+	if otlpEndpoint == nil {
+		return
+	}
+	if err := exportOTLP(results); err != nil {
+		slog.Warn("Export failed", "err", err)
+		return
+	}
+	what := "metrics"
+	if otlpSpans {
+		what = "metrics and spans"
+	}
+	fmt.Printf("%s[✓] Exported %s for %d queries to %s%s\n", ColorGreen, what, len(results), strings.TrimSuffix(otlpEndpoint.Redacted(), "/"), ColorReset)
+}
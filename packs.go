@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// domainPacks are curated domain lists resembling a kind of usage
+// (--domain-pack); each pack's domains are tagged with its name as their
+// category
+var domainPacks = map[string][]string{
+	"indonesia": {
+		"tokopedia.com", "shopee.co.id", "detik.com", "kompas.com", "bukalapak.com",
+		"gojek.com", "traveloka.com", "klikbca.com", "tribunnews.com", "vidio.com",
+	},
+	"india": {
+		"flipkart.com", "hotstar.com", "paytm.com", "ndtv.com", "zomato.com",
+		"swiggy.com", "irctc.co.in", "timesofindia.indiatimes.com", "hdfcbank.com", "jiocinema.com",
+	},
+	"europe": {
+		"bbc.co.uk", "spiegel.de", "lemonde.fr", "zalando.com", "booking.com",
+		"bol.com", "allegro.pl", "elpais.com", "corriere.it", "otto.de",
+	},
+	"latam": {
+		"mercadolibre.com", "globo.com", "uol.com.br", "clarin.com", "americanas.com.br",
+		"nubank.com.br", "elcomercio.pe", "eltiempo.com", "infobae.com", "rappi.com",
+	},
+	"gaming": {
+		"steampowered.com", "steamcommunity.com", "epicgames.com", "riotgames.com", "battle.net",
+		"ea.com", "playstation.com", "xbox.com", "discord.com", "twitch.tv",
+	},
+	"streaming": {
+		"netflix.com", "youtube.com", "primevideo.com", "disneyplus.com", "spotify.com",
+		"twitch.tv", "hbomax.com", "nflxvideo.net", "googlevideo.com", "scdn.co",
+	},
+	"social": {
+		"facebook.com", "instagram.com", "whatsapp.net", "tiktok.com", "x.com",
+		"reddit.com", "linkedin.com", "snapchat.com", "telegram.org", "pinterest.com",
+	},
+	"work": {
+		"office.com", "outlook.office365.com", "teams.microsoft.com", "zoom.us", "slack.com",
+		"atlassian.net", "github.com", "docs.google.com", "salesforce.com", "dropbox.com",
+	},
+}
+
+// packNames returns the available domain packs, sorted
+func packNames() []string {
+	names := make([]string, 0, len(domainPacks))
+	for name := range domainPacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyDomainPacks adds the domains of comma-separated packs to domains and
+// tags them with the pack name, unless --domain-category already tagged them
+func applyDomainPacks(list string, domains []string) ([]string, error) {
+	for _, name := range splitList(list) {
+		pack, ok := domainPacks[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown domain pack %q (want %s)", name, strings.Join(packNames(), ", "))
+		}
+		for _, domain := range pack {
+			if !slices.Contains(domains, domain) {
+				domains = append(domains, domain)
+			}
+			if _, tagged := domainCategories[domain]; !tagged {
+				domainCategories[domain] = strings.ToLower(name)
+			}
+		}
+	}
+	return domains, nil
+}
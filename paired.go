@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// PairedStats compares one resolver across two runs query by query: each
+// query of the first run is matched with the same domain, type, iteration
+// and run of the second, so differences in the domain mix or in caching
+// between the runs cancel out
+type PairedStats struct {
+	Server string
+	Before time.Duration // average RTT of the paired queries
+	After  time.Duration
+	Delta  time.Duration // mean of after minus before
+	CI95   time.Duration // half-width of the paired 95% confidence interval
+	Pairs  int
+	Missed int // queries answered in one run only
+}
+
+// Significant reports whether the mean delta differs from zero at 95%
+// confidence (paired t-test)
+func (p *PairedStats) Significant() bool {
+	return p.Pairs >= 2 && math.Abs(float64(p.Delta)) > float64(p.CI95)
+}
+
+// summarizePaired pairs the answered queries of two runs per endpoint
+func summarizePaired(a []*BenchmarkResult, b []*BenchmarkResult) []*PairedStats {
+	type queryKey struct {
+		server    string
+		domain    string
+		qtype     string
+		iteration int
+		run       int
+	}
+	keyOf := func(r *BenchmarkResult) queryKey {
+		return queryKey{r.ServerName + " (" + r.ServerAddr + ")", r.Domain, r.qtype(), r.Iteration, r.Run}
+	}
+	before := make(map[queryKey]*BenchmarkResult, len(a))
+	for _, result := range a {
+		before[keyOf(result)] = result
+	}
+
+	byServer := make(map[string]*PairedStats)
+	diffs := make(map[string][]time.Duration)
+	totals := make(map[string][2]time.Duration)
+	for _, cur := range b {
+		key := keyOf(cur)
+		old, ok := before[key]
+		if !ok {
+			continue
+		}
+		stats := byServer[key.server]
+		if stats == nil {
+			stats = &PairedStats{Server: key.server}
+			byServer[key.server] = stats
+		}
+		if (old.Status == "SUCCESS") != (cur.Status == "SUCCESS") {
+			stats.Missed++
+			continue
+		}
+		if old.Status != "SUCCESS" {
+			continue
+		}
+		stats.Pairs++
+		diffs[key.server] = append(diffs[key.server], cur.RTT-old.RTT)
+		total := totals[key.server]
+		totals[key.server] = [2]time.Duration{total[0] + old.RTT, total[1] + cur.RTT}
+	}
+
+	paired := make([]*PairedStats, 0, len(byServer))
+	for server, stats := range byServer {
+		if stats.Pairs > 0 {
+			n := time.Duration(stats.Pairs)
+			stats.Before = totals[server][0] / n
+			stats.After = totals[server][1] / n
+			stats.Delta = averageDuration(diffs[server])
+			stats.CI95 = confidence95(diffs[server])
+		}
+		paired = append(paired, stats)
+	}
+	sort.Slice(paired, func(i, j int) bool {
+		if (paired[i].Pairs == 0) != (paired[j].Pairs == 0) {
+			return paired[j].Pairs == 0
+		}
+		if paired[i].Before != paired[j].Before {
+			return paired[i].Before < paired[j].Before
+		}
+		return paired[i].Server < paired[j].Server
+	})
+	return paired
+}
+
+// printPairedComparison shows the per-resolver paired deltas of two runs,
+// e.g. with and without a VPN; a * marks deltas significant at 95%
+// confidence
+func printPairedComparison(a *ResultsFile, b *ResultsFile) {
+	paired := summarizePaired(a.Results, b.Results)
+	if len(paired) == 0 {
+		fmt.Printf("\n%s[!] No queries could be paired: the runs share no server, domain and iteration%s\n", ColorYellow, ColorReset)
+		return
+	}
+
+	fmt.Printf("\n%s[*] Paired comparison (same server, domain, type and iteration in both runs):%s\n\n", ColorBlue, ColorReset)
+	fmt.Printf("%s%-30s | %-6s | %-12s | %-12s | %-11s | %-12s | %-9s | %s%s\n",
+		ColorWhite, "Server", "Pairs", "Avg Before", "Avg After", "Delta", "95% CI", "Change", "Answered once", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼────────┼──────────────┼──────────────┼─────────────┼──────────────┼───────────┼──────────────", ColorReset)
+
+	for _, stats := range paired {
+		if stats.Pairs == 0 {
+			fmt.Printf("%-30s | %6d | %12s | %12s | %11s | %12s | %9s | %d\n",
+				stats.Server, 0, "-", "-", "-", "-", "-", stats.Missed)
+			continue
+		}
+		color, marker := ColorWhite, " "
+		if stats.Significant() {
+			marker = "*"
+			color = ColorGreen
+			if stats.Delta > 0 {
+				color = ColorRed
+			}
+		}
+		var change float64
+		if stats.Before > 0 {
+			change = float64(stats.Delta) / float64(stats.Before) * 100
+		}
+		missedColor := ""
+		if stats.Missed > 0 {
+			missedColor = ColorYellow
+		}
+		fmt.Printf("%-30s | %6d | %9.2f ms | %9.2f ms | %s%+8.2f ms%s | ± %6.2f ms | %s%+7.1f%%%s%s | %s%d%s\n",
+			stats.Server, stats.Pairs, ms(stats.Before), ms(stats.After),
+			color, ms(stats.Delta), ColorReset, ms(stats.CI95),
+			color, change, marker, ColorReset, missedColor, stats.Missed, ColorReset)
+	}
+	fmt.Printf("\n%s    * the delta is significant at 95%% confidence (paired t-test); Answered once counts queries only one run answered%s\n", ColorCyan, ColorReset)
+}
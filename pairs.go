@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// PairStats is the latency a client racing a provider's two addresses would
+// see: each attempt asks both and takes the first answer (Happy Eyeballs
+// style), so a lost or slow packet to one address costs nothing
+type PairStats struct {
+	ServerName string
+	Addrs      [2]string
+	Attempts   int
+	Answered   int
+	RTTs       []time.Duration // of answered attempts, the faster of the two
+	BestSingle time.Duration   // average RTT of the faster address alone
+	Rescued    int             // attempts only one of the two answered
+}
+
+// pairQueryKey identifies one attempt at a provider: its primary and
+// secondary queries share the domain, type, iteration and run
+type pairQueryKey struct {
+	domain    string
+	qtype     string
+	iteration int
+	run       int
+}
+
+// pairAnswer is one address's part of an attempt
+type pairAnswer struct {
+	answered bool
+	rtt      time.Duration
+}
+
+// addPairQuery files a result under its provider's attempt
+func (a *aggregator) addPairQuery(result *BenchmarkResult) {
+	if a.pairs[result.ServerName] == nil {
+		a.pairs[result.ServerName] = make(map[pairQueryKey][]pairAnswer)
+	}
+	key := pairQueryKey{result.Domain, result.qtype(), result.Iteration, result.Run}
+	a.pairs[result.ServerName][key] = append(a.pairs[result.ServerName][key], pairAnswer{result.Status == "SUCCESS", result.RTT})
+}
+
+// pairStats matches the primary and secondary queries of each attempt for
+// providers queried at exactly two addresses
+func (a *aggregator) pairStats() []*PairStats {
+	singles := make(map[string]time.Duration)
+	for _, stats := range a.serverStats() {
+		if stats.SuccessQueries > 0 {
+			singles[stats.ServerName+"|"+stats.ServerAddr] = stats.AvgRTT
+		}
+	}
+	addrs := make(map[string][]string)
+	for _, provider := range a.providerStats() {
+		addrs[provider.Name] = provider.Addrs
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var pairs []*PairStats
+	for name, pairAddrs := range addrs {
+		if len(pairAddrs) != 2 {
+			continue
+		}
+		pair := &PairStats{ServerName: name, Addrs: [2]string{pairAddrs[0], pairAddrs[1]}}
+		for _, addr := range pairAddrs {
+			if avg, ok := singles[name+"|"+addr]; ok && (pair.BestSingle == 0 || avg < pair.BestSingle) {
+				pair.BestSingle = avg
+			}
+		}
+		for _, attempt := range a.pairs[name] {
+			if len(attempt) != 2 {
+				continue // one side was skipped or not yet run
+			}
+			pair.Attempts++
+			var fastest time.Duration
+			answered := 0
+			for _, answer := range attempt {
+				if !answer.answered {
+					continue
+				}
+				answered++
+				if answered == 1 || answer.rtt < fastest {
+					fastest = answer.rtt
+				}
+			}
+			if answered == 0 {
+				continue
+			}
+			pair.Answered++
+			pair.RTTs = append(pair.RTTs, fastest)
+			if answered == 1 {
+				pair.Rescued++
+			}
+		}
+		if pair.Attempts > 0 {
+			pairs = append(pairs, pair)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Answered == 0 || pairs[j].Answered == 0 {
+			return pairs[i].Answered > pairs[j].Answered
+		}
+		return pairs[i].AvgRTT() < pairs[j].AvgRTT()
+	})
+	return pairs
+}
+
+// AvgRTT returns the average effective pair latency
+func (p *PairStats) AvgRTT() time.Duration {
+	if len(p.RTTs) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, rtt := range p.RTTs {
+		total += rtt
+	}
+	return total / time.Duration(len(p.RTTs))
+}
+
+// SuccessRate returns the percentage of attempts either address answered
+func (p *PairStats) SuccessRate() float64 {
+	if p.Attempts == 0 {
+		return 0
+	}
+	return float64(p.Answered) / float64(p.Attempts) * 100
+}
+
+// printPairLatency shows the effective pair latency per provider next to its
+// faster address alone; providers with a single address are left out
+func printPairLatency(agg *aggregator) {
+	pairs := agg.pairStats()
+	if len(pairs) == 0 {
+		return
+	}
+
+	fmt.Printf("\n%s[*] Effective Pair Latency (primary and secondary raced, first answer wins):%s\n\n", ColorBlue, ColorReset)
+	fmt.Printf("%s%-30s | %-12s | %-12s | %-12s | %-12s | %-10s%s\n",
+		ColorWhite, "Provider", "Pair Avg", "Pair p95", "Best Single", "Gain", "Success", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼──────────────┼──────────────┼──────────────┼──────────────┼───────────", ColorReset)
+
+	for _, pair := range pairs {
+		successColor := ColorGreen
+		if pair.SuccessRate() < 100 {
+			successColor = ColorRed
+		}
+		if pair.Answered == 0 {
+			fmt.Printf("%-30s | %12s | %12s | %12s | %12s | %s%6.1f%%%s\n",
+				pair.ServerName, "-", "-", "-", "-", successColor, pair.SuccessRate(), ColorReset)
+			continue
+		}
+		gain := "-"
+		if pair.BestSingle > 0 {
+			gain = fmt.Sprintf("%8.2f ms", ms(pair.BestSingle-pair.AvgRTT()))
+		}
+		fmt.Printf("%-30s | %s%8.2f ms%s | %8.2f ms | %8.2f ms | %12s | %s%6.1f%%%s",
+			pair.ServerName,
+			ColorGreen, ms(pair.AvgRTT()), ColorReset,
+			ms(percentile(pair.RTTs, 95)),
+			ms(pair.BestSingle),
+			gain,
+			successColor, pair.SuccessRate(), ColorReset,
+		)
+		if pair.Rescued > 0 {
+			fmt.Printf("  %s(%d answered by one address only)%s", ColorCyan, pair.Rescued, ColorReset)
+		}
+		fmt.Printf("\n")
+	}
+	fmt.Printf("\n%s    Each attempt takes the faster of the two answers; Gain is how much sooner than querying the faster address alone%s\n", ColorCyan, ColorReset)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// pipelineTimeout bounds each batch of queries on one connection
+const pipelineTimeout = 10 * time.Second
+
+// pipelineResult compares one connection used one query at a time with
+// one that has all queries in flight at once
+type pipelineResult struct {
+	Sequential []time.Duration
+	SeqTotal   time.Duration
+	Pipelined  []time.Duration
+	PipeTotal  time.Duration
+	OutOfOrder int // responses that overtook an earlier query
+	Err        error
+}
+
+// runPipelining sends the same batch of queries over a single TCP or DoT
+// connection twice: one at a time, then all back to back (RFC 7766
+// pipelining), and compares per-query latency and total time. Resolvers that
+// process pipelined queries serially or answer only the first one stand out.
+func runPipelining(config *BenchmarkConfig) {
+	var queries []string
+	for i := 0; i < config.QueryNum; i++ {
+		queries = append(queries, config.Domains...)
+	}
+	fmt.Printf("%s[*] Comparing %d queries per %s connection one at a time and pipelined...%s\n\n",
+		ColorBlue, len(queries), strings.ToUpper(config.Transport), ColorReset)
+
+	fmt.Printf("%s%-30s | %-12s | %-12s | %-12s | %-12s | %-8s | %-12s%s\n",
+		ColorWhite, "Server", "Serial Avg", "Pipe Avg", "Serial Total", "Pipe Total", "Speedup", "Out of Order", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼──────────────┼──────────────┼──────────────┼──────────────┼──────────┼─────────────", ColorReset)
+
+	for _, server := range config.Servers {
+		for _, addr := range server.endpoints(config.Transport) {
+			display := fmt.Sprintf("%s (%s)", server.Name, addr)
+			result := measurePipelining(config.Transport, addr, queries)
+			if result.Err != nil {
+				fmt.Printf("%-30s | %s%v%s\n", display, ColorRed, result.Err, ColorReset)
+				continue
+			}
+
+			speedup := float64(result.SeqTotal) / float64(result.PipeTotal)
+			speedupColor := ColorGreen
+			if speedup < 1.5 {
+				// Pipelined queries are apparently handled one after another
+				speedupColor = ColorYellow
+			}
+			fmt.Printf("%-30s | %9.2f ms | %9.2f ms | %9.2f ms | %9.2f ms | %s%7.1fx%s | %12d\n",
+				display, ms(avgDuration(result.Sequential)), ms(avgDuration(result.Pipelined)),
+				ms(result.SeqTotal), ms(result.PipeTotal), speedupColor, speedup, ColorReset, result.OutOfOrder)
+		}
+	}
+	fmt.Printf("\n%s    Out of order counts answers that overtook earlier queries, which RFC 7766 allows and clients must handle%s\n\n", ColorCyan, ColorReset)
+}
+
+// measurePipelining runs the serial and the pipelined batch, each on its own
+// connection so the handshake is excluded from both
+func measurePipelining(transport string, addr string, names []string) *pipelineResult {
+	result := &pipelineResult{}
+
+	co, err := dialPipeline(transport, addr)
+	if err != nil {
+		return &pipelineResult{Err: err}
+	}
+	start := time.Now()
+	for _, name := range names {
+		m := &dns.Msg{}
+		m.SetQuestion(queryName(name), dns.TypeA)
+		sent := time.Now()
+		if err := co.WriteMsg(m); err != nil {
+			co.Close()
+			return &pipelineResult{Err: fmt.Errorf("serial: %w", err)}
+		}
+		if _, err := co.ReadMsg(); err != nil {
+			co.Close()
+			return &pipelineResult{Err: fmt.Errorf("serial: %w", err)}
+		}
+		result.Sequential = append(result.Sequential, time.Since(sent))
+	}
+	result.SeqTotal = time.Since(start)
+	co.Close()
+
+	co, err = dialPipeline(transport, addr)
+	if err != nil {
+		return &pipelineResult{Err: err}
+	}
+	defer co.Close()
+
+	sent := make(map[uint16]time.Time, len(names))
+	order := make(map[uint16]int, len(names))
+	start = time.Now()
+	for i, name := range names {
+		m := &dns.Msg{}
+		m.SetQuestion(queryName(name), dns.TypeA)
+		for _, taken := sent[m.Id]; taken; _, taken = sent[m.Id] {
+			m.Id = dns.Id()
+		}
+		sent[m.Id] = time.Now()
+		order[m.Id] = i
+		if err := co.WriteMsg(m); err != nil {
+			return &pipelineResult{Err: fmt.Errorf("pipelined: %w", err)}
+		}
+	}
+	highest := -1
+	for received := 0; received < len(names); received++ {
+		r, err := co.ReadMsg()
+		if err != nil {
+			return &pipelineResult{Err: fmt.Errorf("pipelined: %d of %d answered: %w", received, len(names), err)}
+		}
+		at, ok := sent[r.Id]
+		if !ok {
+			return &pipelineResult{Err: fmt.Errorf("pipelined: unexpected response ID %d", r.Id)}
+		}
+		delete(sent, r.Id)
+		result.Pipelined = append(result.Pipelined, time.Since(at))
+		if order[r.Id] < highest {
+			result.OutOfOrder++
+		}
+		highest = max(highest, order[r.Id])
+	}
+	result.PipeTotal = time.Since(start)
+	return result
+}
+
+// dialPipeline opens a TCP or DoT connection with a deadline for one batch
+func dialPipeline(transport string, addr string) (*dns.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pipelineTimeout)
+	defer cancel()
+	co, err := dialPooled(ctx, transport, addr)
+	if err != nil {
+		return nil, err
+	}
+	co.SetDeadline(time.Now().Add(pipelineTimeout))
+	return co, nil
+}
+
+func avgDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations))
+}
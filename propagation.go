@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// propagationAnswer is one resolver's current view of a record
+type propagationAnswer struct {
+	Server  *DNSServer
+	Addr    string
+	RTT     time.Duration
+	Status  string
+	TTL     uint32
+	Answers []string // sorted record data
+	Err     error
+}
+
+// key identifies the answer set for grouping identical views
+func (a *propagationAnswer) key() string {
+	if a.Err != nil {
+		return "error"
+	}
+	return a.Status + " " + strings.Join(a.Answers, ", ")
+}
+
+// runPropagation implements "dnsbench propagation NAME [TYPE]": every
+// selected resolver is asked once and the answers and remaining TTLs are
+// shown side by side, grouped by answer, to verify that a change has spread
+func runPropagation(args []string) {
+	fs := flag.NewFlagSet("propagation", flag.ExitOnError)
+	sel := addSelectionFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: dnsbench propagation NAME [TYPE] [flags]\n")
+		fs.PrintDefaults()
+	}
+
+	positional := parseInterspersed(fs, args)
+	if len(positional) == 0 || len(positional) > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	name := positional[0]
+	qtype := "A"
+	if len(positional) == 2 {
+		qtype = positional[1]
+	}
+	rrtype, ok := dns.StringToType[strings.ToUpper(qtype)]
+	if !ok {
+		fmt.Printf("%s[!] Unknown record type %q%s\n", ColorRed, qtype, ColorReset)
+		os.Exit(2)
+	}
+	fqdn, err := toASCII(name)
+	if err != nil {
+		fmt.Printf("%s[!] Invalid name %q: %v%s\n", ColorRed, name, err, ColorReset)
+		os.Exit(2)
+	}
+	selected := sel.resolve(fs)
+
+	answers := askResolvers(selected, *sel.transport, dns.Fqdn(fqdn), rrtype, *sel.timeout)
+	printPropagation(displayDomain(name), dns.TypeToString[rrtype], answers)
+}
+
+// selectionFlags are the resolver selection flags of the propagation and
+// watch commands, which default to the whole catalog
+type selectionFlags struct {
+	providers *string
+	category  *string
+	region    *string
+	exclude   *string
+	servers   serverFlag
+	transport *string
+	timeout   *time.Duration
+}
+
+func addSelectionFlags(fs *flag.FlagSet) *selectionFlags {
+	sel := &selectionFlags{}
+	sel.providers = fs.String("providers", "all", "comma-separated provider IDs to ask (\"all\" for the whole catalog)")
+	sel.category = fs.String("category", "", "comma-separated provider categories to ask: unfiltered, security, family")
+	sel.region = fs.String("region", "", "add regionally relevant providers: id, eu or us")
+	sel.exclude = fs.String("exclude", "", "comma-separated provider IDs to leave out")
+	fs.Var(&sel.servers, "server", "additional resolver: addr, name=addr or name=primary,secondary (repeatable)")
+	sel.transport = fs.String("transport", TransportUDP, "DNS transport: udp, tcp, dot or doh")
+	sel.timeout = fs.Duration("timeout", 3*time.Second, "per-query timeout")
+	return sel
+}
+
+// resolve returns the selected resolvers after parsing, exiting on errors;
+// --server alone replaces the catalog like it does for bench
+func (sel *selectionFlags) resolve(fs *flag.FlagSet) []*DNSServer {
+	if !validTransport(*sel.transport) {
+		fmt.Printf("%s[!] Unknown transport %q%s\n", ColorRed, *sel.transport, ColorReset)
+		os.Exit(2)
+	}
+	if catalog, err := loadInstalledCatalog(); err == nil && catalog != nil {
+		useCatalog(catalog)
+	}
+	providers := *sel.providers
+	if len(sel.servers) > 0 && !flagSet(fs, "providers") {
+		providers = ""
+	}
+	selected, err := resolveSelection(builtinProviders, providers, *sel.category, *sel.region, *sel.exclude, sel.servers)
+	if err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(2)
+	}
+	return selected
+}
+
+// askResolvers queries the first endpoint of every resolver concurrently
+func askResolvers(servers []*DNSServer, transport string, fqdn string, rrtype uint16, timeout time.Duration) []*propagationAnswer {
+	var answers []*propagationAnswer
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		addrs := server.endpoints(transport)
+		if len(addrs) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			answer := queryPropagation(transport, addrs[0], fqdn, rrtype, timeout)
+			answer.Server = server
+			mu.Lock()
+			answers = append(answers, answer)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return answers
+}
+
+// queryPropagation asks one resolver for the record
+func queryPropagation(transport string, addr string, fqdn string, rrtype uint16, timeout time.Duration) *propagationAnswer {
+	answer := &propagationAnswer{Addr: addr}
+	m := &dns.Msg{}
+	m.SetQuestion(fqdn, rrtype)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	start := time.Now()
+	r, err := exchange(ctx, m, transport, addr)
+	answer.RTT = time.Since(start)
+	if err != nil {
+		answer.Err = err
+		return answer
+	}
+
+	answer.Status = dns.RcodeToString[r.Rcode]
+	for _, rr := range r.Answer {
+		if rr.Header().Rrtype != rrtype {
+			continue
+		}
+		if answer.TTL == 0 || rr.Header().Ttl < answer.TTL {
+			answer.TTL = rr.Header().Ttl
+		}
+		answer.Answers = append(answer.Answers, strings.TrimPrefix(rr.String(), rr.Header().String()))
+	}
+	slices.Sort(answer.Answers)
+	return answer
+}
+
+// printPropagation lists the answers grouped by answer set, the most common
+// (consensus) group first, so stale resolvers stand out
+func printPropagation(name string, qtype string, answers []*propagationAnswer) {
+	fmt.Printf("\n%s[*] %s %s across %d resolvers:%s\n\n", ColorBlue, name, qtype, len(answers), ColorReset)
+	if len(answers) == 0 {
+		fmt.Printf("%s[!] No resolvers selected for this transport%s\n", ColorYellow, ColorReset)
+		return
+	}
+
+	groups := make(map[string][]*propagationAnswer)
+	for _, answer := range answers {
+		groups[answer.key()] = append(groups[answer.key()], answer)
+	}
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(groups[keys[i]]) != len(groups[keys[j]]) {
+			return len(groups[keys[i]]) > len(groups[keys[j]])
+		}
+		return keys[i] < keys[j]
+	})
+
+	fmt.Printf("%s%-30s | %-10s | %-8s | %-8s | %s%s\n", ColorWhite, "Resolver", "RTT", "Status", "TTL", "Answer", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼────────────┼──────────┼──────────┼────────────────────────", ColorReset)
+	for i, key := range keys {
+		group := groups[key]
+		sort.Slice(group, func(a, b int) bool { return group[a].Server.Name < group[b].Server.Name })
+		color := ColorGreen
+		if i > 0 {
+			color = ColorYellow
+		}
+		for _, answer := range group {
+			display := fmt.Sprintf("%s (%s)", answer.Server.Name, answer.Addr)
+			if answer.Err != nil {
+				fmt.Printf("%-30s | %7.2f ms | %s%-8s%s | %8s | %s\n", display, ms(answer.RTT), ColorRed, "ERROR", ColorReset, "-", answer.Err)
+				continue
+			}
+			if len(answer.Answers) == 0 {
+				fmt.Printf("%-30s | %7.2f ms | %-8s | %8s | %s(no records)%s\n", display, ms(answer.RTT), answer.Status, "-", color, ColorReset)
+				continue
+			}
+			fmt.Printf("%-30s | %7.2f ms | %-8s | %7ds | %s%s%s\n", display, ms(answer.RTT), answer.Status, answer.TTL, color, strings.Join(answer.Answers, ", "), ColorReset)
+		}
+	}
+
+	if len(keys) == 1 {
+		fmt.Printf("\n%s[✓] All resolvers return the same answer%s\n", ColorGreen, ColorReset)
+		return
+	}
+	fmt.Printf("\n%s[!] %d different answers; %d of %d resolvers agree with the most common one%s\n",
+		ColorYellow, len(keys), len(groups[keys[0]]), len(answers), ColorReset)
+	fmt.Printf("%s    Stale resolvers pick up the change once their TTL runs out%s\n", ColorCyan, ColorReset)
+}
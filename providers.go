@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Provider categories
+const (
+	CategoryUnfiltered = "unfiltered"
+	CategorySecurity   = "security"
+	CategoryFamily     = "family"
+)
+
+// builtinProviders is the embedded catalog of public resolvers.
+// Fields: ID, name, category, primary, secondary, DoT host, DoH URL.
+var builtinProviders = []*DNSServer{
+	{"google", "Google DNS", CategoryUnfiltered, "8.8.8.8:53", "8.8.4.4:53", "dns.google", "https://dns.google/dns-query"},
+	{"cloudflare", "Cloudflare", CategoryUnfiltered, "1.1.1.1:53", "1.0.0.1:53", "one.one.one.one", "https://cloudflare-dns.com/dns-query"},
+	{"cloudflare-security", "Cloudflare Security", CategorySecurity, "1.1.1.2:53", "1.0.0.2:53", "security.cloudflare-dns.com", "https://security.cloudflare-dns.com/dns-query"},
+	{"cloudflare-family", "Cloudflare Family", CategoryFamily, "1.1.1.3:53", "1.0.0.3:53", "family.cloudflare-dns.com", "https://family.cloudflare-dns.com/dns-query"},
+	{"quad9", "Quad9", CategorySecurity, "9.9.9.9:53", "149.112.112.112:53", "dns.quad9.net", "https://dns.quad9.net/dns-query"},
+	{"quad9-unfiltered", "Quad9 Unfiltered", CategoryUnfiltered, "9.9.9.10:53", "149.112.112.10:53", "dns10.quad9.net", "https://dns10.quad9.net/dns-query"},
+	{"opendns", "OpenDNS", CategorySecurity, "208.67.222.222:53", "208.67.220.220:53", "dns.opendns.com", "https://doh.opendns.com/dns-query"},
+	{"opendns-family", "OpenDNS FamilyShield", CategoryFamily, "208.67.222.123:53", "208.67.220.123:53", "", "https://doh.familyshield.opendns.com/dns-query"},
+	{"nextdns", "NextDNS", CategoryUnfiltered, "45.90.28.0:53", "45.90.30.0:53", "dns.nextdns.io", "https://dns.nextdns.io/dns-query"},
+	// {"dnswatch", "dns.watch", CategoryUnfiltered, "84.200.69.80:53", "84.200.70.40:53", "", ""},
+	{"tiar", "tiar.app", CategorySecurity, "174.138.21.128:53", "188.166.206.224:53", "dot.tiar.app", "https://doh.tiar.app/dns-query"},
+	{"adguard", "AdGuard", CategorySecurity, "94.140.14.14:53", "94.140.15.15:53", "dns.adguard-dns.com", "https://dns.adguard-dns.com/dns-query"},
+	{"adguard-unfiltered", "AdGuard Unfiltered", CategoryUnfiltered, "94.140.14.140:53", "94.140.14.141:53", "unfiltered.adguard-dns.com", "https://unfiltered.adguard-dns.com/dns-query"},
+	{"adguard-family", "AdGuard Family", CategoryFamily, "94.140.14.15:53", "94.140.15.16:53", "family.adguard-dns.com", "https://family.adguard-dns.com/dns-query"},
+	{"cleanbrowsing-security", "CleanBrowsing Security", CategorySecurity, "185.228.168.9:53", "185.228.169.9:53", "security-filter-dns.cleanbrowsing.org", "https://doh.cleanbrowsing.org/doh/security-filter/"},
+	{"cleanbrowsing-family", "CleanBrowsing Family", CategoryFamily, "185.228.168.168:53", "185.228.169.168:53", "family-filter-dns.cleanbrowsing.org", "https://doh.cleanbrowsing.org/doh/family-filter/"},
+	{"comodo", "Comodo Secure DNS", CategorySecurity, "8.26.56.26:53", "8.20.247.20:53", "", ""},
+	{"level3", "Level3", CategoryUnfiltered, "4.2.2.1:53", "4.2.2.2:53", "", ""},
+	// Mullvad only serves encrypted DNS
+	{"mullvad", "Mullvad", CategoryUnfiltered, "", "", "dns.mullvad.net", "https://dns.mullvad.net/dns-query"},
+	{"mullvad-adblock", "Mullvad Adblock", CategorySecurity, "", "", "adblock.dns.mullvad.net", "https://adblock.dns.mullvad.net/dns-query"},
+	{"dnssb", "DNS.SB", CategoryUnfiltered, "185.222.222.222:53", "45.11.45.11:53", "dot.sb", "https://doh.dns.sb/dns-query"},
+	{"controld", "Control D", CategoryUnfiltered, "76.76.2.0:53", "76.76.10.0:53", "p0.freedns.controld.com", "https://freedns.controld.com/p0"},
+	{"controld-malware", "Control D Malware", CategorySecurity, "76.76.2.1:53", "76.76.10.1:53", "p1.freedns.controld.com", "https://freedns.controld.com/p1"},
+	{"controld-family", "Control D Family", CategoryFamily, "76.76.2.4:53", "76.76.10.4:53", "family.freedns.controld.com", "https://freedns.controld.com/family"},
+
+	// Regional resolvers, only benchmarked through --region or --providers
+	{"nawala", "DNS Nawala", CategorySecurity, "180.131.144.144:53", "180.131.145.145:53", "", ""},
+	{"biznet", "Biznet", CategoryUnfiltered, "203.142.82.222:53", "203.142.84.222:53", "", ""},
+	{"dns4eu", "DNS4EU Unfiltered", CategoryUnfiltered, "86.54.11.100:53", "86.54.11.200:53", "unfiltered.joindns4.eu", "https://unfiltered.joindns4.eu/dns-query"},
+	{"dns4eu-protective", "DNS4EU Protective", CategorySecurity, "86.54.11.1:53", "86.54.11.201:53", "protective.joindns4.eu", "https://protective.joindns4.eu/dns-query"},
+	{"fdn", "FDN", CategoryUnfiltered, "80.67.169.12:53", "80.67.169.40:53", "ns0.fdn.fr", "https://ns0.fdn.fr/dns-query"},
+	{"comcast", "Comcast Xfinity", CategoryUnfiltered, "75.75.75.75:53", "75.75.76.76:53", "", ""},
+	{"ultradns", "UltraDNS Public", CategoryUnfiltered, "64.6.64.6:53", "64.6.65.6:53", "", ""},
+}
+
+// regionPresets lists the regionally relevant providers --region adds to the
+// selection: national providers and large local ISPs
+var regionPresets = map[string][]string{
+	"id": {"nawala", "biznet"},
+	"eu": {"dns4eu", "dns4eu-protective", "fdn", "mullvad", "dnssb"},
+	"us": {"comcast", "ultradns", "level3", "comodo"},
+}
+
+// defaultProviders are benchmarked when no provider selection is given,
+// keeping the default run short; the full catalog is opt-in
+var defaultProviders = []string{"google", "cloudflare", "quad9", "opendns", "nextdns", "tiar"}
+
+// selectProviders filters the catalog by provider ID. An empty include list
+// selects everything; exclude is applied afterwards. IDs are case-insensitive.
+func selectProviders(catalog []*DNSServer, include []string, exclude []string) ([]*DNSServer, error) {
+	known := make(map[string]bool)
+	for _, server := range catalog {
+		known[server.ID] = true
+	}
+	for _, id := range slices.Concat(include, exclude) {
+		if !known[id] {
+			return nil, fmt.Errorf("unknown provider %q (available: %s)", id, strings.Join(providerIDs(catalog), ", "))
+		}
+	}
+
+	var selected []*DNSServer
+	for _, server := range catalog {
+		if len(include) > 0 && !slices.Contains(include, server.ID) {
+			continue
+		}
+		if slices.Contains(exclude, server.ID) {
+			continue
+		}
+		selected = append(selected, server)
+	}
+	return selected, nil
+}
+
+// filterCategories keeps providers in any of the given categories
+func filterCategories(catalog []*DNSServer, categories []string) ([]*DNSServer, error) {
+	for _, category := range categories {
+		switch category {
+		case CategoryUnfiltered, CategorySecurity, CategoryFamily:
+		default:
+			return nil, fmt.Errorf("unknown category %q (want %s, %s or %s)", category, CategoryUnfiltered, CategorySecurity, CategoryFamily)
+		}
+	}
+
+	var selected []*DNSServer
+	for _, server := range catalog {
+		if slices.Contains(categories, server.Category) {
+			selected = append(selected, server)
+		}
+	}
+	return selected, nil
+}
+
+// regionProviders returns the provider IDs of a region preset
+func regionProviders(region string) ([]string, error) {
+	ids, ok := regionPresets[region]
+	if !ok {
+		regions := slices.Sorted(maps.Keys(regionPresets))
+		return nil, fmt.Errorf("unknown region %q (available: %s)", region, strings.Join(regions, ", "))
+	}
+	return ids, nil
+}
+
+func providerIDs(catalog []*DNSServer) []string {
+	var ids []string
+	for _, server := range catalog {
+		ids = append(ids, server.ID)
+	}
+	return ids
+}
+
+// splitList splits a comma-separated flag value into lower-cased, trimmed items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// resolveSelection applies --providers, --category, --region and --exclude
+// to the catalog; custom --server entries are added to that selection, or
+// replace the catalog when no selection is given
+func resolveSelection(catalog []*DNSServer, providers string, category string, region string, exclude string, servers []*DNSServer) ([]*DNSServer, error) {
+	include := splitList(providers)
+	switch {
+	case slices.Contains(include, "all"):
+		include = nil
+	case len(include) == 0 && (category == "" || region != ""):
+		include = slices.Clone(defaultProviders)
+	}
+	if region != "" && include != nil {
+		ids, err := regionProviders(strings.ToLower(region))
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			if !slices.Contains(include, id) {
+				include = append(include, id)
+			}
+		}
+	}
+	selected, err := selectProviders(catalog, include, splitList(exclude))
+	if err == nil && category != "" {
+		selected, err = filterCategories(selected, splitList(category))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(servers) > 0 {
+		if providers != "" || category != "" || region != "" {
+			return append(selected, servers...), nil
+		}
+		return servers, nil
+	}
+	return selected, nil
+}
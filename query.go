@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// runQuery implements "dnsbench query NAME [TYPE] [@SERVER]": a one-shot,
+// dig-like lookup through the same transports the benchmark uses. SERVER may
+// be an IP[:port], tls://host[:port] or a DoH URL; it defaults to the first
+// resolv.conf nameserver.
+func runQuery(args []string) {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	qtype := fs.String("type", "A", "record type, e.g. A, AAAA, MX, TXT, NS, SOA")
+	transport := fs.String("transport", TransportUDP, "transport for plain server addresses: udp or tcp (tls:// and https:// servers imply dot and doh)")
+	dnssec := fs.Bool("dnssec", false, "set the DO bit to request DNSSEC records")
+	fs.BoolVar(&queryRD, "rd", true, "set the RD (recursion desired) bit")
+	fs.BoolVar(&queryCD, "cd", false, "set the CD (checking disabled) bit to skip DNSSEC validation")
+	fs.BoolVar(&queryAD, "ad", false, "set the AD bit to ask for the authenticated-data bit in the answer")
+	fs.Var(ednsOptFlag{}, "edns-opt", "add an EDNS option as CODE[:HEX], e.g. nsid or 65001:beef (repeatable)")
+	timeout := fs.Duration("timeout", 3*time.Second, "query timeout")
+	proxyFlag := fs.String("proxy", "", "route tcp, dot and doh queries through a proxy")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: dnsbench query NAME [TYPE] [@SERVER] [flags]\n")
+		fs.PrintDefaults()
+	}
+
+	var name, server string
+	for _, arg := range parseInterspersed(fs, args) {
+		switch {
+		case strings.HasPrefix(arg, "@"):
+			server = strings.TrimPrefix(arg, "@")
+		case name != "" && dns.StringToType[strings.ToUpper(arg)] != 0:
+			*qtype = arg
+		case name == "":
+			name = arg
+		default:
+			fmt.Printf("%s[!] Unexpected argument %q%s\n", ColorRed, arg, ColorReset)
+			os.Exit(2)
+		}
+	}
+	if name == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	rrtype, ok := dns.StringToType[strings.ToUpper(*qtype)]
+	if !ok {
+		fmt.Printf("%s[!] Unknown record type %q%s\n", ColorRed, *qtype, ColorReset)
+		os.Exit(2)
+	}
+	fqdn, err := toASCII(name)
+	if err != nil {
+		fmt.Printf("%s[!] Invalid name %q: %v%s\n", ColorRed, name, err, ColorReset)
+		os.Exit(2)
+	}
+	if err := configureProxy(*proxyFlag); err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(2)
+	}
+
+	addr, proto, err := queryServer(server, *transport)
+	if err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(2)
+	}
+	if proxyURL != nil && proto == TransportUDP {
+		proto = TransportTCP
+	}
+
+	m := &dns.Msg{}
+	m.SetQuestion(dns.Fqdn(fqdn), rrtype)
+	m.SetEdns0(dns.DefaultMsgSize, *dnssec)
+	applyQueryFlags(m)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	start := time.Now()
+	r, err := exchange(ctx, m, proto, addr)
+	rtt := time.Since(start)
+	if err != nil {
+		fmt.Printf("%s;; %s query to %s failed after %.2f ms: %v%s\n", ColorRed, strings.ToUpper(proto), addr, ms(rtt), err, ColorReset)
+		os.Exit(1)
+	}
+
+	fmt.Println(r.String())
+	fmt.Printf(";; Query time: %.2f ms\n", ms(rtt))
+	fmt.Printf(";; SERVER: %s (%s)\n", addr, strings.ToUpper(proto))
+	fmt.Printf(";; WHEN: %s\n", start.Format(time.RFC1123))
+	fmt.Printf(";; MSG SIZE  rcvd: %d\n", r.Len())
+}
+
+// queryServer maps a dig-style server argument to an address and transport
+func queryServer(server string, transport string) (string, string, error) {
+	switch {
+	case strings.HasPrefix(server, "https://"):
+		return server, TransportDoH, nil
+	case strings.HasPrefix(server, "tls://"):
+		host, err := hostPort(strings.TrimPrefix(server, "tls://"), defaultDoTPort)
+		return host, TransportDoT, err
+	}
+	if transport != TransportUDP && transport != TransportTCP {
+		return "", "", fmt.Errorf("--transport %q needs a tls:// or https:// server", transport)
+	}
+
+	if server == "" {
+		conf, err := dns.ClientConfigFromFile(resolvConfPath)
+		if err != nil || len(conf.Servers) == 0 {
+			return "", "", fmt.Errorf("no @server given and no nameserver in %s", resolvConfPath)
+		}
+		server = conf.Servers[0]
+	}
+	server, err := hostPort(server, defaultDNSPort)
+	return server, transport, err
+}
+
+// parseInterspersed parses flags that may appear before or after positional
+// arguments, like dig, and returns the positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		if fs.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+// breakdownRcodes are the response codes tracked separately in the failure breakdown
+var breakdownRcodes = []string{"SERVFAIL", "REFUSED", "NXDOMAIN", "FORMERR"}
+
+// printFailureBreakdown shows per-server failure counts by cause, since a
+// resolver returning REFUSED is a very different problem from timeouts
+func printFailureBreakdown(statsList []*ServerStats) {
+	fmt.Printf("\n%s[*] Failure Breakdown:%s\n\n", ColorBlue, ColorReset)
+
+	var failing []*ServerStats
+	for _, stats := range statsList {
+		if stats.SuccessQueries < stats.TotalQueries {
+			failing = append(failing, stats)
+		}
+	}
+	if len(failing) == 0 {
+		fmt.Printf("%s[✓] No failed queries%s\n", ColorGreen, ColorReset)
+		return
+	}
+
+	fmt.Printf("%s%-30s | %-8s | %-8s | %-8s | %-8s | %-8s | %-8s | %-10s | %-8s%s\n",
+		ColorWhite, "Server", "SERVFAIL", "REFUSED", "NXDOMAIN", "FORMERR", "TIMEOUT", "CONNECT", "NO_RECORDS", "OTHER", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼──────────┼──────────┼──────────┼──────────┼──────────┼──────────┼────────────┼─────────", ColorReset)
+
+	for _, stats := range failing {
+		// Everything not broken out explicitly (other rcodes, transport errors)
+		other := stats.TotalQueries - stats.SuccessQueries - stats.Timeouts - stats.ConnectFailures - stats.NoRecords
+		for _, rcode := range breakdownRcodes {
+			other -= stats.RcodeCounts[rcode]
+		}
+
+		serverDisplay := fmt.Sprintf("%s (%s)", stats.ServerName, stats.ServerAddr)
+		fmt.Printf("%-30s", serverDisplay)
+		for _, rcode := range breakdownRcodes {
+			fmt.Printf(" | %s", failureCount(stats.RcodeCounts[rcode], 8))
+		}
+		fmt.Printf(" | %s | %s | %s | %s\n",
+			failureCount(stats.Timeouts, 8),
+			failureCount(stats.ConnectFailures, 8),
+			failureCount(stats.NoRecords, 10),
+			failureCount(other, 8),
+		)
+	}
+	if slices.ContainsFunc(failing, func(stats *ServerStats) bool { return stats.ConnectFailures > 0 }) {
+		fmt.Printf("\n%s    CONNECT counts queries never sent because the connection or TLS handshake failed (unreachable, not slow)%s\n", ColorCyan, ColorReset)
+	}
+}
+
+// failureCount renders a right-aligned count, highlighted when non-zero
+func failureCount(count int, width int) string {
+	if count == 0 {
+		return fmt.Sprintf("%*d", width, count)
+	}
+	return fmt.Sprintf("%s%*d%s", ColorRed, width, count, ColorReset)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// rebindProbe is a public name whose answer is the embedded IP (nip.io)
+type rebindProbe struct {
+	Label string
+	Name  string
+}
+
+// rebindControl must resolve to a public address; if it does not, the
+// rebind zone is unreachable through that resolver and results are unknown
+var rebindControl = rebindProbe{"Public", "8.8.8.8.nip.io"}
+
+// rebindProbes cover the RFC 1918 ranges and loopback
+var rebindProbes = []rebindProbe{
+	{"10/8", "10.0.0.1.nip.io"},
+	{"172.16/12", "172.16.0.1.nip.io"},
+	{"192.168/16", "192.168.1.1.nip.io"},
+	{"127/8", "127.0.0.1.nip.io"},
+}
+
+// privateAnswer reports whether any answer is a private, loopback or
+// link-local address a rebinding attack could point a browser at
+func privateAnswer(ips []string) bool {
+	for _, raw := range ips {
+		ip := net.ParseIP(raw)
+		if ip != nil && (ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast()) {
+			return true
+		}
+	}
+	return false
+}
+
+// runRebinding checks which resolvers strip private addresses from public
+// names, the DNS rebinding protection offered by AdGuard, NextDNS and
+// home routers
+func runRebinding(config *BenchmarkConfig) {
+	fmt.Printf("%s[*] Checking DNS rebinding protection (public names resolving to private IPs)...%s\n\n", ColorBlue, ColorReset)
+
+	fmt.Printf("%s%-30s", ColorWhite, "Server")
+	for _, probe := range rebindProbes {
+		fmt.Printf(" | %-10s", probe.Label)
+	}
+	fmt.Printf(" | %-10s%s\n", "Protection", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼────────────┼────────────┼────────────┼────────────┼───────────", ColorReset)
+
+	var protected, unprotected []string
+	for _, server := range config.Servers {
+		for _, addr := range server.endpoints(config.Transport) {
+			display := fmt.Sprintf("%s (%s)", server.Name, addr)
+			fmt.Printf("%-30s", display)
+
+			ips, _, err := lookupA(config.Transport, addr, rebindControl.Name)
+			if err != nil || len(ips) == 0 {
+				for range rebindProbes {
+					fmt.Printf(" | %-10s", "-")
+				}
+				fmt.Printf(" | %sunknown (%s unreachable)%s\n", ColorYellow, rebindControl.Name, ColorReset)
+				continue
+			}
+
+			filtered := 0
+			for _, probe := range rebindProbes {
+				ips, rcode, err := lookupA(config.Transport, addr, probe.Name)
+				switch {
+				case err != nil:
+					fmt.Printf(" | %s%-10s%s", ColorYellow, "error", ColorReset)
+				case privateAnswer(ips):
+					fmt.Printf(" | %s%-10s%s", ColorRed, "passed", ColorReset)
+				default:
+					filtered++
+					if len(ips) > 0 {
+						// Answered with a public or null address instead
+						rcode = "rewritten"
+					}
+					fmt.Printf(" | %s%-10s%s", ColorGreen, rcode, ColorReset)
+				}
+			}
+
+			switch filtered {
+			case len(rebindProbes):
+				protected = append(protected, display)
+				fmt.Printf(" | %syes%s\n", ColorGreen, ColorReset)
+			case 0:
+				unprotected = append(unprotected, display)
+				fmt.Printf(" | %sno%s\n", ColorRed, ColorReset)
+			default:
+				fmt.Printf(" | %spartial%s\n", ColorYellow, ColorReset)
+			}
+		}
+	}
+
+	fmt.Printf("\n")
+	if len(protected) > 0 {
+		fmt.Printf("%s[✓] Filter private answers: %s%s\n", ColorGreen, strings.Join(protected, ", "), ColorReset)
+	}
+	if len(unprotected) > 0 {
+		fmt.Printf("%s[!] Return private answers for public names: %s%s\n", ColorYellow, strings.Join(unprotected, ", "), ColorReset)
+		fmt.Printf("%s    Most public resolvers do this by design; enable rebinding protection on your router or filter%s\n", ColorCyan, ColorReset)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyReload delivers SIGHUP, the conventional request to reload settings
+func notifyReload(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGHUP)
+}
+
+// reexec replaces the process with a fresh start of the same command line,
+// keeping its PID for the service manager
+func reexec() error {
+	exe, err := executablePath()
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(exe, os.Args, os.Environ())
+}
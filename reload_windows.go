@@ -0,0 +1,14 @@
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// notifyReload does nothing: Windows has no SIGHUP, services are restarted
+func notifyReload(c chan<- os.Signal) {}
+
+// reexec is not possible on Windows
+func reexec() error {
+	return errors.New("reloading is not supported on Windows; restart the service")
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// replayMaxGap caps the idle time between two replayed queries, so a log
+// spanning a night does not take a night to replay; bursts keep their timing
+const replayMaxGap = time.Second
+
+// ReplayConfig selects the query log replayed in replay mode
+type ReplayConfig struct {
+	Path    string
+	Speed   float64 // playback speed, 0 = back to back
+	Limit   int     // most recent queries to replay, 0 = all
+	Queries []replayQuery
+	Format  string // log format the queries were read from
+}
+
+// replayQuery is one client query read from a resolver's query log
+type replayQuery struct {
+	At     time.Time
+	Domain string
+	QType  string
+}
+
+// localSuffixes are names that only a LAN resolver can answer; forwarding
+// them to a public resolver measures nothing useful
+var localSuffixes = []string{".local", ".lan", ".home", ".home.arpa", ".internal", ".localdomain"}
+
+// loadQueryLog reads the client queries from a dnsmasq or Pi-hole log
+// (log-queries) or an Unbound log (log-queries: yes), keeping the most recent
+// limit queries. Replies, forwards and cache lines are skipped, as are LAN-only
+// names and reverse lookups of private addresses.
+func loadQueryLog(path string, limit int) ([]replayQuery, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	var queries []replayQuery
+	counts := make(map[string]int)
+	now := time.Now()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var q replayQuery
+		var ok bool
+		format := "dnsmasq/Pi-hole"
+		if strings.Contains(line, "unbound[") {
+			format = "Unbound"
+			q, ok = parseUnboundLine(line, now)
+		} else {
+			q, ok = parseDnsmasqLine(line, now)
+		}
+		if !ok || !replayable(q.Domain) {
+			continue
+		}
+		counts[format]++
+		queries = append(queries, q)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	if len(queries) == 0 {
+		return nil, "", fmt.Errorf("%s: no queries found (want a dnsmasq, Pi-hole or Unbound log with query logging enabled)", path)
+	}
+	if limit > 0 && len(queries) > limit {
+		queries = queries[len(queries)-limit:]
+	}
+
+	format := "dnsmasq/Pi-hole"
+	if counts["Unbound"] > counts[format] {
+		format = "Unbound"
+	}
+	return queries, format, nil
+}
+
+// parseDnsmasqLine parses "Oct 15 12:00:01 dnsmasq[812]: query[A] example.com
+// from 192.168.1.10", with or without the extra log-queries=extra fields
+func parseDnsmasqLine(line string, now time.Time) (replayQuery, bool) {
+	_, rest, found := strings.Cut(line, "]: ")
+	if !found {
+		return replayQuery{}, false
+	}
+	fields := strings.Fields(rest)
+	for i, field := range fields {
+		if !strings.HasPrefix(field, "query[") || i+1 >= len(fields) {
+			continue
+		}
+		qtype := strings.TrimSuffix(strings.TrimPrefix(field, "query["), "]")
+		if n, isNum := strings.CutPrefix(qtype, "type="); isNum {
+			code, err := strconv.Atoi(n)
+			if err != nil {
+				return replayQuery{}, false
+			}
+			qtype = dns.TypeToString[uint16(code)]
+		}
+		if _, known := dns.StringToType[qtype]; !known {
+			return replayQuery{}, false
+		}
+		at, ok := syslogTime(line, now)
+		if !ok {
+			return replayQuery{}, false
+		}
+		return replayQuery{At: at, Domain: fields[i+1], QType: qtype}, true
+	}
+	return replayQuery{}, false
+}
+
+// parseUnboundLine parses "[1697371200] unbound[1234:0] info: 192.168.1.2
+// example.com. A IN" and its syslog variant; reply lines carry the rcode
+// and timing after the class and are skipped
+func parseUnboundLine(line string, now time.Time) (replayQuery, bool) {
+	_, rest, found := strings.Cut(line, " info: ")
+	if !found {
+		return replayQuery{}, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) != 4 || fields[3] != "IN" || net.ParseIP(fields[0]) == nil {
+		return replayQuery{}, false
+	}
+	if _, known := dns.StringToType[fields[2]]; !known {
+		return replayQuery{}, false
+	}
+
+	var at time.Time
+	if epoch, ok := strings.CutPrefix(line, "["); ok {
+		end := strings.IndexByte(epoch, ']')
+		if end < 0 {
+			return replayQuery{}, false
+		}
+		secs, err := strconv.ParseInt(epoch[:end], 10, 64)
+		if err != nil {
+			return replayQuery{}, false
+		}
+		at = time.Unix(secs, 0)
+	} else {
+		var ok bool
+		if at, ok = syslogTime(line, now); !ok {
+			return replayQuery{}, false
+		}
+	}
+	return replayQuery{At: at, Domain: strings.TrimSuffix(fields[1], "."), QType: fields[2]}, true
+}
+
+// syslogTime reads the "Oct 15 12:00:01" prefix of a syslog line, which has
+// no year: the year is the one that puts the time in the past. An RFC 3339
+// prefix, as written by journalctl -o short-iso, is accepted too.
+func syslogTime(line string, now time.Time) (time.Time, bool) {
+	if first, _, found := strings.Cut(line, " "); found {
+		if at, err := time.Parse("2006-01-02T15:04:05-0700", first); err == nil {
+			return at, true
+		}
+		if at, err := time.Parse(time.RFC3339, first); err == nil {
+			return at, true
+		}
+	}
+	if len(line) < len(time.Stamp) {
+		return time.Time{}, false
+	}
+	at, err := time.ParseInLocation(time.Stamp, line[:len(time.Stamp)], now.Location())
+	if err != nil {
+		return time.Time{}, false
+	}
+	at = at.AddDate(now.Year(), 0, 0)
+	if at.After(now.Add(24 * time.Hour)) {
+		at = at.AddDate(-1, 0, 0)
+	}
+	return at, true
+}
+
+// replayable reports whether a logged name is one a public resolver could
+// answer: not a single label, a LAN suffix or a private reverse zone
+func replayable(domain string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if !strings.Contains(domain, ".") {
+		return false
+	}
+	for _, suffix := range localSuffixes {
+		if strings.HasSuffix(domain, suffix) {
+			return false
+		}
+	}
+	if strings.HasSuffix(domain, ".in-addr.arpa") {
+		labels := strings.Split(strings.TrimSuffix(domain, ".in-addr.arpa"), ".")
+		if len(labels) == 4 {
+			ip := net.ParseIP(labels[3] + "." + labels[2] + "." + labels[1] + "." + labels[0])
+			if ip != nil && (ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast()) {
+				return false
+			}
+		}
+	}
+	if strings.HasSuffix(domain, ".ip6.arpa") && privateReverse6(domain) {
+		return false
+	}
+	return true
+}
+
+// privateReverse6 reports whether an ip6.arpa name lies in fc00::/7 or
+// fe80::/10, the unique local and link-local ranges
+func privateReverse6(domain string) bool {
+	nibbles := strings.Split(strings.TrimSuffix(domain, ".ip6.arpa"), ".")
+	if len(nibbles) < 3 {
+		return false
+	}
+	top := nibbles[len(nibbles)-1] + nibbles[len(nibbles)-2] + nibbles[len(nibbles)-3]
+	return strings.HasPrefix(top, "fc") || strings.HasPrefix(top, "fd") ||
+		top == "fe8" || top == "fe9" || top == "fea" || top == "feb"
+}
+
+// replayDomains lists the distinct names of a query log and the record
+// types used, for the configuration and report
+func replayDomains(queries []replayQuery) ([]string, []string) {
+	seenDomain, seenType := make(map[string]bool), make(map[string]bool)
+	var domains, qtypes []string
+	for _, q := range queries {
+		if !seenDomain[q.Domain] {
+			seenDomain[q.Domain] = true
+			domains = append(domains, q.Domain)
+		}
+		if !seenType[q.QType] {
+			seenType[q.QType] = true
+			qtypes = append(qtypes, q.QType)
+		}
+	}
+	return domains, qtypes
+}
+
+// printReplayMix summarizes the replayed traffic: span, record types and
+// the most queried names
+func printReplayMix(replay *ReplayConfig) {
+	queries := replay.Queries
+	span := queries[len(queries)-1].At.Sub(queries[0].At)
+	domains, _ := replayDomains(queries)
+	fmt.Printf("    Query log: %s (%s), %d queries over %v, %d distinct names\n",
+		replay.Path, replay.Format, len(queries), span.Round(time.Second), len(domains))
+
+	types := make(map[string]int)
+	names := make(map[string]int)
+	for _, q := range queries {
+		types[q.QType]++
+		names[q.Domain]++
+	}
+	var mix []string
+	for _, qtype := range sortedByCount(types) {
+		mix = append(mix, fmt.Sprintf("%s %.0f%%", qtype, float64(types[qtype])/float64(len(queries))*100))
+	}
+	fmt.Printf("    Types: %s\n", strings.Join(mix, ", "))
+	top := sortedByCount(names)
+	if len(top) > 5 {
+		top = top[:5]
+	}
+	for i, name := range top {
+		top[i] = fmt.Sprintf("%s (%d)", name, names[name])
+	}
+	fmt.Printf("    Most queried: %s\n", strings.Join(top, ", "))
+	if replay.Speed > 0 {
+		fmt.Printf("    Playback: %gx speed, idle gaps capped at %v\n", replay.Speed, replayMaxGap)
+	} else {
+		fmt.Printf("    Playback: back to back\n")
+	}
+}
+
+// sortedByCount returns the keys of counts, most frequent first
+func sortedByCount(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// runReplay sends the logged queries to every endpoint in their original
+// order and rhythm: each query goes to all endpoints at once, at its logged
+// offset divided by the playback speed, so every resolver sees the same mix
+// of repeats (cache hits) and first lookups. Results feed the regular
+// summary, report and export.
+func runReplay(ctx context.Context, config *BenchmarkConfig) {
+	replay := config.Replay
+	var endpoints []queryJob
+	for _, server := range config.Servers {
+		for _, addr := range server.endpoints(config.Transport) {
+			endpoints = append(endpoints, queryJob{ServerName: server.Name, ServerAddr: addr, Transport: config.Transport})
+		}
+	}
+	queryCount := len(replay.Queries) * len(endpoints)
+	fmt.Printf("%s[*] Replaying the query log...%s\n", ColorBlue, ColorReset)
+	fmt.Printf("%s    Total queries: %d (%d logged × %d endpoints)%s\n\n", ColorCyan, queryCount, len(replay.Queries), len(endpoints), ColorReset)
+
+	logChan = make(chan *BenchmarkResult, queryCount)
+	logDone := make(chan struct{})
+	go func() {
+		defer close(logDone)
+		for result := range logChan {
+			logQuery(result)
+		}
+	}()
+
+	sem := make(chan struct{}, max(config.Concurrency, len(endpoints)))
+	var wg sync.WaitGroup
+	start := time.Now()
+	var offset time.Duration
+	var sent int
+replay:
+	for i, q := range replay.Queries {
+		if i > 0 && replay.Speed > 0 {
+			gap := min(max(q.At.Sub(replay.Queries[i-1].At), 0), replayMaxGap)
+			offset += time.Duration(float64(gap) / replay.Speed)
+			select {
+			case <-time.After(time.Until(start.Add(offset))):
+			case <-ctx.Done():
+				break replay
+			}
+		}
+		for _, endpoint := range endpoints {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				break replay
+			}
+			job := endpoint
+			job.Domain, job.QType, job.Iteration = q.Domain, q.QType, i
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				n := inFlight.Add(1)
+				result := queryDNS(job)
+				inFlight.Add(-1)
+				result.InFlight = int(n)
+				recordResult(result)
+				logChan <- result
+			}()
+			sent++
+		}
+	}
+	wg.Wait()
+	close(logChan)
+	<-logDone
+
+	if sent < queryCount {
+		fmt.Printf("\n")
+		slog.Warn(fmt.Sprintf("Deadline of %v reached", config.MaxDuration), "completed", sent, "queries", queryCount)
+		fmt.Printf("\n")
+		return
+	}
+	fmt.Printf("\n%s[✓] Replayed %d queries in %v%s\n\n", ColorGreen, queryCount, time.Since(start).Round(time.Millisecond), ColorReset)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DomainStats holds aggregated statistics for one domain across all servers
+type DomainStats struct {
+	Domain         string
+	AvgRTT         time.Duration
+	TotalQueries   int
+	SuccessQueries int
+
+	rttTotal time.Duration
+}
+
+// SuccessRate returns the percentage of successful queries
+func (s *ServerStats) SuccessRate() float64 {
+	if s.TotalQueries == 0 {
+		return 0
+	}
+	return float64(s.SuccessQueries) / float64(s.TotalQueries) * 100
+}
+
+// SuccessRate returns the percentage of successful queries
+func (s DomainStats) SuccessRate() float64 {
+	if s.TotalQueries == 0 {
+		return 0
+	}
+	return float64(s.SuccessQueries) / float64(s.TotalQueries) * 100
+}
+
+// summarizeServers aggregates results per server address, sorted by average RTT
+func summarizeServers(results []*BenchmarkResult) []*ServerStats {
+	return aggregate(results).serverStats()
+}
+
+// printSignificance names the servers whose average RTT cannot be told apart
+// from the fastest one's at 95% confidence, so noise-level differences are
+// not mistaken for a reason to switch resolvers
+func printSignificance(statsList []*ServerStats) {
+	var fastest *ServerStats
+	for _, stats := range statsList {
+		if stats.SuccessQueries > 0 {
+			fastest = stats
+			break
+		}
+	}
+	if fastest == nil {
+		return
+	}
+
+	var tied []string
+	others := 0
+	for _, stats := range statsList {
+		if stats == fastest || stats.SuccessQueries == 0 {
+			continue
+		}
+		others++
+		if !significantlyDifferent(fastest.rtts, stats.rtts) {
+			tied = append(tied, fmt.Sprintf("%s (%s, +%.2f ms)", stats.ServerName, stats.ServerAddr, ms(stats.AvgRTT-fastest.AvgRTT)))
+		}
+	}
+	if others == 0 {
+		return
+	}
+
+	fastestDisplay := fmt.Sprintf("%s (%s)", fastest.ServerName, fastest.ServerAddr)
+	fmt.Printf("\n")
+	if len(tied) == 0 {
+		fmt.Printf("%s[✓] %s is significantly faster than every other server (Welch's t-test, 95%% confidence)%s\n", ColorGreen, fastestDisplay, ColorReset)
+		return
+	}
+	fmt.Printf("%s[*] Statistically tied with %s (Welch's t-test, 95%% confidence):%s\n", ColorBlue, fastestDisplay, ColorReset)
+	for _, server := range tied {
+		fmt.Printf("    %s\n", server)
+	}
+	fmt.Printf("%s    These differences are within measurement noise; more domains (--domains) narrow the intervals%s\n", ColorCyan, ColorReset)
+}
+
+// summarizeDomains aggregates results per domain, sorted by average RTT
+func summarizeDomains(results []*BenchmarkResult) []DomainStats {
+	return aggregate(results).domainStats()
+}
+
+// Report is the data passed to --format-template templates
+type Report struct {
+	GeneratedAt time.Time
+	Mode        string
+	Transport   string
+	Metadata    *RunMetadata
+	Labels      []string         // --label of the run
+	Sources     []*ResultsSource // runs combined by merge, if any
+	Servers     []*ServerStats
+	Domains     []DomainStats
+	Results     []*BenchmarkResult
+}
+
+// newReport summarizes results for rendering
+func newReport(config *BenchmarkConfig, results []*BenchmarkResult) *Report {
+	agg := aggregate(results)
+	return &Report{
+		GeneratedAt: time.Now(),
+		Mode:        config.Mode,
+		Transport:   config.Transport,
+		Metadata:    runMetadata,
+		Labels:      runLabels,
+		Servers:     agg.serverStats(),
+		Domains:     agg.domainStats(),
+		Results:     results,
+	}
+}
+
+// templateFuncs are available to --format-template templates in addition to
+// the text/template builtins
+var templateFuncs = template.FuncMap{
+	"ms":     ms,
+	"upper":  strings.ToUpper,
+	"lower":  strings.ToLower,
+	"join":   strings.Join,
+	"repeat": strings.Repeat,
+	"pad": func(width int, s string) string {
+		return fmt.Sprintf("%-*s", width, s)
+	},
+}
+
+// parseFormatTemplate loads a user template up front, so syntax errors are
+// reported before the benchmark runs
+func parseFormatTemplate(path string) (*template.Template, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New(path).Funcs(templateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// renderFormatTemplate executes the template to path, or stdout when empty
+func renderFormatTemplate(tmpl *template.Template, report *Report, path string) error {
+	var w io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return tmpl.Execute(w, report)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// resultsFileVersion is bumped when the saved results format changes incompatibly
+const resultsFileVersion = 1
+
+// ResultsFile is a saved benchmark run, read back by the export and compare commands
+type ResultsFile struct {
+	Version   int                `json:"version"`
+	SavedAt   time.Time          `json:"saved_at"`
+	Mode      string             `json:"mode"`
+	Transport string             `json:"transport"`
+	Metadata  *RunMetadata       `json:"metadata,omitempty"`
+	Labels    []string           `json:"labels,omitempty"`  // --label of the run
+	Sources   []*ResultsSource   `json:"sources,omitempty"` // runs combined by merge
+	Results   []*BenchmarkResult `json:"results"`
+}
+
+// saveResults atomically writes the raw results of a run
+func saveResults(path string, config *BenchmarkConfig, results []*BenchmarkResult) error {
+	return writeResultsFile(path, &ResultsFile{
+		Version:   resultsFileVersion,
+		SavedAt:   now(),
+		Mode:      config.Mode,
+		Transport: config.Transport,
+		Metadata:  runMetadata,
+		Labels:    runLabels,
+		Results:   results,
+	})
+}
+
+// writeResultsFile atomically writes a results file
+func writeResultsFile(path string, file *ResultsFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadResults reads a file written by saveResults
+func loadResults(path string) (*ResultsFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file ResultsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid results file %s: %w", path, err)
+	}
+	if file.Version != resultsFileVersion {
+		return nil, fmt.Errorf("results file %s has unsupported version %d", path, file.Version)
+	}
+	return &file, nil
+}
+
+// environment returns where the run was measured; for a merged file, only
+// when it holds a single source
+func (f *ResultsFile) environment() *RunEnvironment {
+	if f.Metadata != nil {
+		return f.Metadata.Environment
+	}
+	if len(f.Sources) == 1 && f.Sources[0].Metadata != nil {
+		return f.Sources[0].Metadata.Environment
+	}
+	return nil
+}
+
+// report summarizes a saved run for rendering
+func (f *ResultsFile) report() *Report {
+	agg := aggregate(f.Results)
+	return &Report{
+		GeneratedAt: f.SavedAt,
+		Mode:        f.Mode,
+		Transport:   f.Transport,
+		Metadata:    f.Metadata,
+		Labels:      f.Labels,
+		Sources:     f.Sources,
+		Servers:     agg.serverStats(),
+		Domains:     agg.domainStats(),
+		Results:     f.Results,
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// queryRetries is how many times a query that timed out or failed to reach
+// the resolver is sent again (--retries), as stub resolvers do
+var queryRetries int
+
+// attemptTimeout is the worst-case wait of one query including its retries
+func attemptTimeout() time.Duration {
+	return time.Duration(queryRetries+1) * queryTimeout
+}
+
+// printRetries compares resolver speed with what a client perceives when it
+// retries: the first-attempt columns cover queries answered without a retry,
+// time to answer covers every answered query including the timeouts waited
+// out before a retry got through
+func printRetries(statsList []*ServerStats, results []*BenchmarkResult) {
+	if queryRetries == 0 {
+		return
+	}
+	type retryStats struct {
+		total, firstAnswered, retried, rescued int
+		first, toAnswer                        []time.Duration
+	}
+	byServer := make(map[string]*retryStats)
+	for _, result := range results {
+		if result.Attempts == 0 {
+			continue // saved before --retries or merged from such a run
+		}
+		key := result.ServerName + "|" + result.ServerAddr
+		stats := byServer[key]
+		if stats == nil {
+			stats = &retryStats{}
+			byServer[key] = stats
+		}
+		stats.total++
+		if result.Attempts > 1 {
+			stats.retried++
+		}
+		if result.Status != "SUCCESS" {
+			continue
+		}
+		stats.toAnswer = append(stats.toAnswer, result.TimeToAnswer)
+		if result.Attempts == 1 {
+			stats.firstAnswered++
+			stats.first = append(stats.first, result.RTT)
+		} else {
+			stats.rescued++
+		}
+	}
+	if len(byServer) == 0 {
+		return
+	}
+
+	fmt.Printf("\n%s[*] Retries (up to %d per query): first attempt vs time to answer%s\n\n", ColorBlue, queryRetries, ColorReset)
+	fmt.Printf("%s%-30s | %-12s | %-10s | %-12s | %-12s | %-10s | %s%s\n",
+		ColorWhite, "Server", "1st Avg RTT", "1st Success", "Answer Avg", "Answer p95", "Success", "Retried", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼──────────────┼─────────────┼──────────────┼──────────────┼────────────┼─────────────", ColorReset)
+
+	for _, server := range statsList {
+		stats, ok := byServer[server.ServerName+"|"+server.ServerAddr]
+		if !ok {
+			continue
+		}
+		firstRate := float64(stats.firstAnswered) / float64(stats.total) * 100
+		answerRate := float64(len(stats.toAnswer)) / float64(stats.total) * 100
+		firstAvg, answerAvg, answerP95 := "-", "-", "-"
+		if len(stats.first) > 0 {
+			firstAvg = fmt.Sprintf("%8.2f ms", ms(averageDuration(stats.first)))
+		}
+		if len(stats.toAnswer) > 0 {
+			answerAvg = fmt.Sprintf("%8.2f ms", ms(averageDuration(stats.toAnswer)))
+			answerP95 = fmt.Sprintf("%8.2f ms", ms(percentile(stats.toAnswer, 95)))
+		}
+		retriedColor := ColorGreen
+		if stats.retried > 0 {
+			retriedColor = ColorYellow
+		}
+		fmt.Printf("%-30s | %12s | %10.1f%% | %12s | %12s | %8.1f%% | %s%d (%d rescued)%s\n",
+			fmt.Sprintf("%s (%s)", server.ServerName, server.ServerAddr),
+			firstAvg, firstRate, answerAvg, answerP95, answerRate,
+			retriedColor, stats.retried, stats.rescued, ColorReset,
+		)
+	}
+	fmt.Printf("\n%s    1st columns measure the resolver (queries answered without a retry); Answer columns what a client waits, timeouts included%s\n", ColorCyan, ColorReset)
+}
+
+// averageDuration returns the mean of durations, 0 for none
+func averageDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	return total / time.Duration(len(durations))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+)
+
+// rootServer is one of the 13 root server letters
+type rootServer struct {
+	Host     string
+	Addr     string
+	Operator string
+}
+
+// rootServers are the IPv4 addresses of the root server letters
+var rootServers = []rootServer{
+	{"a.root-servers.net.", "198.41.0.4:53", "Verisign"},
+	{"b.root-servers.net.", "170.247.170.2:53", "USC-ISI"},
+	{"c.root-servers.net.", "192.33.4.12:53", "Cogent"},
+	{"d.root-servers.net.", "199.7.91.13:53", "UMD"},
+	{"e.root-servers.net.", "192.203.230.10:53", "NASA"},
+	{"f.root-servers.net.", "192.5.5.241:53", "ISC"},
+	{"g.root-servers.net.", "192.112.36.4:53", "DISA"},
+	{"h.root-servers.net.", "198.97.190.53:53", "US Army"},
+	{"i.root-servers.net.", "192.36.148.17:53", "Netnod"},
+	{"j.root-servers.net.", "192.58.128.30:53", "Verisign"},
+	{"k.root-servers.net.", "193.0.14.129:53", "RIPE NCC"},
+	{"l.root-servers.net.", "199.7.83.42:53", "ICANN"},
+	{"m.root-servers.net.", "202.12.27.33:53", "WIDE"},
+}
+
+// rootOperators maps root server hosts to their operator
+var rootOperators = func() map[string]string {
+	operators := make(map[string]string)
+	for _, root := range rootServers {
+		operators[root.Host] = root.Operator
+	}
+	return operators
+}()
+
+// defaultTLDs are measured in roots mode unless --zone names others
+var defaultTLDs = []string{"com", "net", "org"}
+
+// runRoots measures latency to every root server letter and to the name
+// servers of major TLDs, the first hops of every cold recursive lookup
+func runRoots(config *BenchmarkConfig, tlds []string) {
+	if len(tlds) == 0 {
+		tlds = defaultTLDs
+	}
+
+	var servers []authServer
+	for _, root := range rootServers {
+		servers = append(servers, authServer{Zone: ".", Host: root.Host, Addr: root.Addr})
+	}
+	fmt.Printf("%s[*] Measuring the 13 root servers and TLDs: %v%s\n", ColorBlue, tlds, ColorReset)
+
+	// TLD name servers are looked up through the first selected resolver
+	var resolver string
+	for _, server := range config.Servers {
+		if addrs := server.endpoints(config.Transport); len(addrs) > 0 {
+			resolver = addrs[0]
+			break
+		}
+	}
+	for _, tld := range tlds {
+		if resolver == "" {
+			break
+		}
+		found, err := lookupNameServers(config.Transport, resolver, tld)
+		if err != nil {
+			fmt.Printf("%s[!] %s: %v%s\n", ColorYellow, tld, err, ColorReset)
+			continue
+		}
+		servers = append(servers, found...)
+	}
+
+	printAuthoritative(benchmarkAuthServers(servers, nil, config.QueryNum))
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// runPasses runs the complete benchmark config.Runs times, starting a pass
+// every config.RunInterval (or right after the previous one if it ran
+// longer), and tags each result with its pass. It returns the queries the
+// deadline skipped.
+func runPasses(ctx context.Context, config *BenchmarkConfig) []queryJob {
+	if config.Runs <= 1 {
+		return runBenchmark(ctx, config)
+	}
+
+	var skipped []queryJob
+	for pass := 1; pass <= config.Runs; pass++ {
+		started := time.Now()
+		fmt.Printf("%s[*] Run %d of %d%s\n", ColorBlue, pass, config.Runs, ColorReset)
+		first := len(results)
+		skipped = append(skipped, runBenchmark(ctx, config)...)
+		for _, result := range results[first:] {
+			result.Run = pass
+		}
+		if pass == config.Runs || ctx.Err() != nil {
+			break
+		}
+
+		next := started.Add(config.RunInterval)
+		if wait := time.Until(next); wait > 0 {
+			fmt.Printf("%s    Next run at %s%s\n\n", ColorCyan, consoleTime(next, "15:04:05"), ColorReset)
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return skipped
+}
+
+// printRunAggregates shows each resolver's average RTT per pass with the
+// mean and range across passes; a wide range means a pass hit transient
+// congestion, which a single run would have reported as the result
+func printRunAggregates(results []*BenchmarkResult, runs int) {
+	if runs <= 1 {
+		return
+	}
+	byRun := make([][]*BenchmarkResult, runs+1)
+	for _, result := range results {
+		if result.Run >= 1 && result.Run <= runs {
+			byRun[result.Run] = append(byRun[result.Run], result)
+		}
+	}
+	perRun := make([]map[string]*ServerStats, runs+1)
+	for run := 1; run <= runs; run++ {
+		perRun[run] = make(map[string]*ServerStats)
+		for _, stats := range summarizeServers(byRun[run]) {
+			perRun[run][stats.ServerName+"|"+stats.ServerAddr] = stats
+		}
+	}
+
+	fmt.Printf("%s[*] Across %d runs (average RTT per run):%s\n\n", ColorBlue, runs, ColorReset)
+	fmt.Printf("%s%-30s", ColorWhite, "Server")
+	separator := "───────────────────────────────"
+	for run := 1; run <= runs; run++ {
+		fmt.Printf(" | %-10s", fmt.Sprintf("Run %d", run))
+		separator += "┼────────────"
+	}
+	fmt.Printf(" | %-10s | %-10s | %s%s\n", "Mean", "Range", "Success", ColorReset)
+	fmt.Printf("%s%s┼────────────┼────────────┼─────────%s\n", ColorYellow, separator, ColorReset)
+
+	var unstable int
+	for _, overall := range summarizeServers(results) {
+		key := overall.ServerName + "|" + overall.ServerAddr
+		fmt.Printf("%-30s", fmt.Sprintf("%s (%s)", overall.ServerName, overall.ServerAddr))
+		var avgs []float64
+		for run := 1; run <= runs; run++ {
+			stats, ok := perRun[run][key]
+			if !ok || stats.SuccessQueries == 0 {
+				fmt.Printf(" | %10s", "-")
+				continue
+			}
+			avg := ms(stats.AvgRTT)
+			avgs = append(avgs, avg)
+			fmt.Printf(" | %7.2f ms", avg)
+		}
+		if len(avgs) == 0 {
+			fmt.Printf(" | %10s | %10s | %6.1f%%\n", "-", "-", overall.SuccessRate())
+			continue
+		}
+
+		var sum float64
+		low, high := math.Inf(1), math.Inf(-1)
+		for _, avg := range avgs {
+			sum += avg
+			low, high = min(low, avg), max(high, avg)
+		}
+		mean := sum / float64(len(avgs))
+		rangeColor := ColorGreen
+		if high-low > mean/2 {
+			rangeColor = ColorYellow
+			unstable++
+		}
+		fmt.Printf(" | %7.2f ms | %s%7.2f ms%s | %6.1f%%\n", mean, rangeColor, high-low, ColorReset, overall.SuccessRate())
+	}
+
+	fmt.Printf("\n%s    Mean is the average of the per-run averages; Range is the slowest minus the fastest run%s\n", ColorCyan, ColorReset)
+	if unstable > 0 {
+		fmt.Printf("%s    %d resolver(s) varied by more than half their mean between runs; congestion or load was transient%s\n", ColorYellow, unstable, ColorReset)
+	}
+	fmt.Printf("\n")
+}
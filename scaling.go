@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+	"strings"
+	"time"
+)
+
+// concurrencyBucket groups in-flight counts into powers of two (1, 2-3, 4-7, ...)
+func concurrencyBucket(inFlight int) int {
+	if inFlight < 1 {
+		return 0
+	}
+	return bits.Len(uint(inFlight)) - 1
+}
+
+func concurrencyBucketLabel(bucket int) string {
+	lo, hi := 1<<bucket, 1<<(bucket+1)-1
+	if lo == hi {
+		return fmt.Sprintf("%d", lo)
+	}
+	return fmt.Sprintf("%d-%d", lo, hi)
+}
+
+// loadStats totals one server's answered queries in one concurrency bucket
+type loadStats struct {
+	total time.Duration
+	count int
+}
+
+// addLoad accumulates an answered query under its server and the number of
+// queries in flight when it was sent
+func (a *aggregator) addLoad(key string, result *BenchmarkResult) {
+	if result.Status != "SUCCESS" || result.InFlight < 1 {
+		return
+	}
+	if a.load[key] == nil {
+		a.load[key] = make(map[int]*loadStats)
+	}
+	bucket := concurrencyBucket(result.InFlight)
+	if a.load[key][bucket] == nil {
+		a.load[key][bucket] = &loadStats{}
+	}
+	a.load[key][bucket].total += result.RTT
+	a.load[key][bucket].count++
+}
+
+// loadStats returns a snapshot of the per-bucket totals keyed by server
+// ("name (addr)") and concurrency bucket
+func (a *aggregator) loadStats() map[string]map[int]loadStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	snapshot := make(map[string]map[int]loadStats, len(a.load))
+	for key, buckets := range a.load {
+		stats := a.servers[key]
+		name := stats.ServerName + " (" + stats.ServerAddr + ")"
+		snapshot[name] = make(map[int]loadStats, len(buckets))
+		for bucket, load := range buckets {
+			snapshot[name][bucket] = *load
+		}
+	}
+	return snapshot
+}
+
+// printConcurrencyScaling reports how average RTT changes with the number of
+// queries in flight, exposing resolvers that degrade under parallel load
+func printConcurrencyScaling(agg *aggregator) {
+	servers := agg.loadStats()
+	maxBucket := 0
+	for _, buckets := range servers {
+		for bucket := range buckets {
+			maxBucket = max(maxBucket, bucket)
+		}
+	}
+
+	if len(servers) == 0 {
+		return
+	}
+
+	fmt.Printf("%s[*] Latency Under Load (avg RTT by queries in flight):%s\n\n", ColorBlue, ColorReset)
+
+	header := fmt.Sprintf("%-30s", "Server")
+	separator := strings.Repeat("─", 31)
+	for bucket := 0; bucket <= maxBucket; bucket++ {
+		header += fmt.Sprintf(" | %-11s", concurrencyBucketLabel(bucket))
+		separator += "┼" + strings.Repeat("─", 13)
+	}
+	header += fmt.Sprintf(" | %-11s", "Degradation")
+	separator += "┼" + strings.Repeat("─", 12)
+	fmt.Printf("%s%s%s\n", ColorWhite, header, ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, separator, ColorReset)
+
+	var names []string
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		buckets := servers[name]
+		line := fmt.Sprintf("%-30s", name)
+
+		var lowest, highest time.Duration
+		for bucket := 0; bucket <= maxBucket; bucket++ {
+			stat, exists := buckets[bucket]
+			if !exists {
+				line += fmt.Sprintf(" | %11s", "-")
+				continue
+			}
+			avg := stat.total / time.Duration(stat.count)
+			if lowest == 0 {
+				lowest = avg
+			}
+			highest = avg
+			line += fmt.Sprintf(" | %8.2f ms", ms(avg))
+		}
+
+		// Ratio of avg RTT at the highest observed concurrency to the lowest
+		degradation := 1.0
+		if lowest > 0 {
+			degradation = float64(highest) / float64(lowest)
+		}
+		degradationColor := ColorGreen
+		if degradation > 1.5 {
+			degradationColor = ColorYellow
+		}
+		if degradation > 3 {
+			degradationColor = ColorRed
+		}
+		line += fmt.Sprintf(" | %s%10.2fx%s", degradationColor, degradation, ColorReset)
+		fmt.Printf("%s\n", line)
+	}
+	fmt.Printf("\n")
+}
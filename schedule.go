@@ -0,0 +1,105 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Query scheduling strategies
+const (
+	ScheduleBurst       = "burst"
+	ScheduleInterleaved = "interleaved"
+	ScheduleShuffled    = "shuffled"
+)
+
+// checkSchedule rejects an unknown --schedule
+func checkSchedule(schedule string) error {
+	switch schedule {
+	case ScheduleBurst, ScheduleInterleaved, ScheduleShuffled:
+		return nil
+	}
+	return fmt.Errorf("unknown schedule %q (want %s, %s or %s)", schedule, ScheduleBurst, ScheduleInterleaved, ScheduleShuffled)
+}
+
+// scheduleJobs orders the query matrix according to the configured strategy.
+//
+//   - burst:       server by server, every iteration of a domain back-to-back
+//   - interleaved: round-robin across server addresses, one query each per turn
+//   - shuffled:    round-robin with server and query order randomized per turn
+func scheduleJobs(jobs []queryJob, schedule string, rng *rand.Rand) ([]queryJob, error) {
+	if err := checkSchedule(schedule); err != nil {
+		return nil, err
+	}
+	if schedule == ScheduleBurst {
+		return jobs, nil
+	}
+
+	// Queue per server address, iteration-major so domains are spread out too
+	var order []string
+	queues := make(map[string][]queryJob)
+	for _, job := range jobs {
+		if _, exists := queues[job.ServerAddr]; !exists {
+			order = append(order, job.ServerAddr)
+		}
+		queues[job.ServerAddr] = append(queues[job.ServerAddr], job)
+	}
+	for _, addr := range order {
+		queue := queues[addr]
+		slices.SortStableFunc(queue, func(a, b queryJob) int {
+			return cmp.Compare(a.Iteration, b.Iteration)
+		})
+		if schedule == ScheduleShuffled {
+			rng.Shuffle(len(queue), func(i, j int) { queue[i], queue[j] = queue[j], queue[i] })
+		}
+		queues[addr] = queue
+	}
+
+	scheduled := make([]queryJob, 0, len(jobs))
+	for len(scheduled) < len(jobs) {
+		turn := order
+		if schedule == ScheduleShuffled {
+			turn = append([]string(nil), order...)
+			rng.Shuffle(len(turn), func(i, j int) { turn[i], turn[j] = turn[j], turn[i] })
+		}
+		for _, addr := range turn {
+			if queue := queues[addr]; len(queue) > 0 {
+				scheduled = append(scheduled, queue[0])
+				queues[addr] = queue[1:]
+			}
+		}
+	}
+	return scheduled, nil
+}
+
+// serverPacer spaces queries to the same server address by a fixed delay,
+// emulating client pacing instead of a thundering herd
+type serverPacer struct {
+	delay time.Duration
+	mu    sync.Mutex
+	next  map[string]time.Time
+}
+
+func newServerPacer(delay time.Duration) *serverPacer {
+	return &serverPacer{delay: delay, next: make(map[string]time.Time)}
+}
+
+// wait blocks until the next query slot for addr
+func (p *serverPacer) wait(addr string) {
+	if p.delay <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	slot := p.next[addr]
+	if now := time.Now(); slot.Before(now) {
+		slot = now
+	}
+	p.next[addr] = slot.Add(p.delay)
+	p.mu.Unlock()
+
+	time.Sleep(time.Until(slot))
+}
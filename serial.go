@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// soaAnswer is the SOA record a server returned for a zone
+type soaAnswer struct {
+	Zone   string
+	Serial uint32
+	TTL    uint32
+	RTT    time.Duration
+}
+
+// querySOA asks addr for the SOA of name; the zone is the owner of the SOA
+// in the answer or, for names below the apex, in the authority section
+func querySOA(transport string, addr string, name string, recursion bool) (*soaAnswer, error) {
+	m := &dns.Msg{}
+	m.SetQuestion(queryName(name), dns.TypeSOA)
+	m.RecursionDesired = recursion
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	start := time.Now()
+	r, err := exchange(ctx, m, transport, addr)
+	rtt := time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+	if r.Rcode != dns.RcodeSuccess && r.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("rcode: %s", dns.RcodeToString[r.Rcode])
+	}
+	for _, rr := range append(r.Answer, r.Ns...) {
+		if soa, ok := rr.(*dns.SOA); ok {
+			return &soaAnswer{Zone: strings.ToLower(soa.Hdr.Name), Serial: soa.Serial, TTL: soa.Hdr.Ttl, RTT: rtt}, nil
+		}
+	}
+	return nil, fmt.Errorf("no SOA record")
+}
+
+// serialBehind compares serials with RFC 1982 serial number arithmetic and
+// returns how far serial is behind current (negative when ahead)
+func serialBehind(current uint32, serial uint32) int64 {
+	return int64(int32(current - serial))
+}
+
+// runSOASerial asks every resolver for the SOA of the test zones and compares
+// the serials with the zone's authoritative servers, so resolvers that still
+// serve data from before the last zone update stand out
+func runSOASerial(config *BenchmarkConfig, zones []string) {
+	// Zone and NS lookups go through the first selected resolver
+	var resolver string
+	for _, server := range config.Servers {
+		if addrs := server.endpoints(config.Transport); len(addrs) > 0 {
+			resolver = addrs[0]
+			break
+		}
+	}
+	if resolver == "" {
+		fmt.Printf("%s[!] No resolver available to look up zones%s\n", ColorRed, ColorReset)
+		return
+	}
+
+	// Without --zone, the zones enclosing the test domains are checked
+	if len(zones) == 0 {
+		seen := make(map[string]bool)
+		for _, domain := range config.Domains {
+			soa, err := querySOA(config.Transport, resolver, domain, true)
+			if err != nil {
+				fmt.Printf("%s[!] %s: cannot find zone: %v%s\n", ColorYellow, domain, err, ColorReset)
+				continue
+			}
+			if !seen[soa.Zone] {
+				seen[soa.Zone] = true
+				zones = append(zones, soa.Zone)
+			}
+		}
+	}
+
+	fmt.Printf("%s[*] Comparing SOA serials of %d zone(s) with their authoritative servers...%s\n", ColorBlue, len(zones), ColorReset)
+	stale := 0
+	for _, zone := range zones {
+		stale += checkZoneSerial(config, resolver, queryName(zone))
+	}
+	if stale > 0 {
+		fmt.Printf("%s[!] %d resolver answer(s) behind the authoritative serial; they serve the old zone until the SOA TTL runs out%s\n\n", ColorYellow, stale, ColorReset)
+	}
+}
+
+// checkZoneSerial prints the serial every resolver serves for one zone and
+// returns the number of stale resolvers
+func checkZoneSerial(config *BenchmarkConfig, resolver string, zone string) int {
+	fmt.Printf("\n%s[*] %s%s\n", ColorBlue, displayDomain(zone), ColorReset)
+
+	servers, err := lookupNameServers(config.Transport, resolver, zone)
+	if err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		return 0
+	}
+	// The newest serial among the authoritative servers is the reference;
+	// secondaries that have not transferred the update yet are listed
+	var authSerial uint32
+	authSerials := make(map[string]uint32)
+	for _, server := range servers {
+		soa, err := querySOA(TransportUDP, server.Addr, zone, false)
+		if err != nil {
+			fmt.Printf("%s    %s (%s): %v%s\n", ColorYellow, strings.TrimSuffix(server.Host, "."), server.Addr, err, ColorReset)
+			continue
+		}
+		authSerials[server.Host+" ("+server.Addr+")"] = soa.Serial
+		if len(authSerials) == 1 || serialBehind(soa.Serial, authSerial) < 0 {
+			authSerial = soa.Serial
+		}
+	}
+	if len(authSerials) == 0 {
+		fmt.Printf("%s[!] No authoritative server answered%s\n", ColorRed, ColorReset)
+		return 0
+	}
+	fmt.Printf("    Authoritative serial: %d (%d server address(es))\n", authSerial, len(authSerials))
+	for name, serial := range authSerials {
+		if serial != authSerial {
+			fmt.Printf("%s    %s still serves %d (zone transfer pending?)%s\n", ColorYellow, strings.TrimSuffix(name, "."), serial, ColorReset)
+		}
+	}
+	fmt.Printf("\n")
+
+	type serialResult struct {
+		display string
+		soa     *soaAnswer
+		err     error
+	}
+	var results []serialResult
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, server := range config.Servers {
+		for _, addr := range server.endpoints(config.Transport) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				soa, err := querySOA(config.Transport, addr, zone, true)
+				mu.Lock()
+				results = append(results, serialResult{fmt.Sprintf("%s (%s)", server.Name, addr), soa, err})
+				mu.Unlock()
+			}()
+		}
+	}
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].display < results[j].display })
+
+	fmt.Printf("%s%-30s | %-12s | %-10s | %-10s | %s%s\n", ColorWhite, "Server", "Serial", "Behind", "SOA TTL", "Status", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼──────────────┼────────────┼────────────┼──────────", ColorReset)
+	stale := 0
+	for _, result := range results {
+		if result.err != nil {
+			fmt.Printf("%-30s | %12s | %10s | %10s | %s%v%s\n", result.display, "-", "-", "-", ColorRed, result.err, ColorReset)
+			continue
+		}
+		behind := serialBehind(authSerial, result.soa.Serial)
+		status, color := "current", ColorGreen
+		switch {
+		case result.soa.Zone != zone:
+			status, color = "different zone "+result.soa.Zone, ColorRed
+		case behind > 0:
+			status, color = "stale", ColorRed
+			stale++
+		case behind < 0:
+			// Usually the zone was updated between the authoritative and
+			// resolver queries
+			status, color = "ahead", ColorYellow
+		}
+		fmt.Printf("%-30s | %12d | %10d | %9ds | %s%s%s\n", result.display, result.soa.Serial, max(behind, 0), result.soa.TTL, color, status, ColorReset)
+	}
+	return stale
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// WindowStatus summarizes one resolver over one time window
+type WindowStatus struct {
+	Queries     int     `json:"queries"`
+	SuccessRate float64 `json:"success_rate"`
+	AvgRTT      float64 `json:"avg_rtt_ms"`
+}
+
+// ResolverStatus is one resolver in the serve API
+type ResolverStatus struct {
+	Resolver string                  `json:"resolver"`
+	Lifetime WindowStatus            `json:"lifetime"`
+	Windows  map[string]WindowStatus `json:"windows"`
+	SLA      []SLAStatus             `json:"sla,omitempty"`
+}
+
+// MonitorStatus is the serve API response
+type MonitorStatus struct {
+	Started   time.Time        `json:"started"`
+	Rounds    int              `json:"rounds"`
+	Resolvers []ResolverStatus `json:"resolvers"`
+}
+
+func windowStatus(bucket hourBucket) WindowStatus {
+	status := WindowStatus{Queries: bucket.Total, AvgRTT: ms(bucket.avg())}
+	if bucket.Total > 0 {
+		status.SuccessRate = float64(bucket.Success) / float64(bucket.Total) * 100
+	}
+	return status
+}
+
+// status snapshots lifetime and rolling window stats of every resolver
+func (s *monitorStats) status(now time.Time) []ResolverStatus {
+	s.mu.Lock()
+	names := append([]string(nil), s.order...)
+	s.mu.Unlock()
+	sort.Strings(names)
+
+	resolvers := make([]ResolverStatus, 0, len(names))
+	for _, name := range names {
+		s.mu.Lock()
+		lifetime := *s.lifetime[name]
+		var sla []SLAStatus
+		if len(slaObjectives) > 0 {
+			sla = s.sla[name].compliance()
+		}
+		s.mu.Unlock()
+
+		status := ResolverStatus{Resolver: name, Lifetime: windowStatus(lifetime), Windows: make(map[string]WindowStatus), SLA: sla}
+		for _, d := range rollingWindows {
+			status.Windows[shortDuration(d)] = windowStatus(s.window(name, d, now))
+		}
+		resolvers = append(resolvers, status)
+	}
+	return resolvers
+}
+
+// monitorServer exposes the monitor state over HTTP in serve mode
+type monitorServer struct {
+	stats   *monitorStats
+	started time.Time
+	rounds  func() int
+}
+
+func (srv *monitorServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&MonitorStatus{
+		Started:   srv.started,
+		Rounds:    srv.rounds(),
+		Resolvers: srv.stats.status(time.Now()),
+	})
+}
+
+// startMonitorServer listens on addr and serves /api/status, /api/series
+// and the Grafana datasource until the process exits; it fails early when the address is unavailable
+func startMonitorServer(addr string, srv *monitorServer) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/status", srv.handleStatus)
+	mux.HandleFunc("GET /api/series", srv.handleSeries)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+
+	// Grafana JSON datasource with the URL http://<listen>/grafana; it checks
+	// the connection with GET on the base URL
+	mux.HandleFunc("GET /grafana/{$}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("POST /grafana/metrics", srv.handleGrafanaMetrics)
+	mux.HandleFunc("POST /grafana/query", srv.handleGrafanaQuery)
+	mux.HandleFunc("POST /grafana/search", func(w http.ResponseWriter, r *http.Request) {
+		// Metric list of older (SimpleJSON) datasource versions
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]string{MetricRTT, MetricSuccess})
+	})
+	go http.Serve(ln, mux)
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Default ports for addresses given without one
+const (
+	defaultDNSPort = "53"
+	defaultDoTPort = "853"
+)
+
+// serverFlag collects DNS servers given on the command line.
+// Accepted forms: "addr", "name=addr" and "name=primary,secondary", where an
+// address is host[:port] (port 53 by default, e.g. 127.0.0.1:5353 for a local
+// Unbound or dnsdist), a DoH URL (https://...) or a DoT host (tls://host[:port]).
+type serverFlag []*DNSServer
+
+func (f *serverFlag) String() string {
+	var parts []string
+	for _, srv := range *f {
+		parts = append(parts, srv.Name)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f *serverFlag) Set(value string) error {
+	name, addrs, found := strings.Cut(value, "=")
+	if !found {
+		name, addrs = value, value
+	}
+	server := &DNSServer{Name: strings.TrimSpace(name)}
+
+	for _, addr := range strings.Split(addrs, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if strings.HasPrefix(addr, "https://") {
+			server.DoH = addr
+			continue
+		}
+
+		defaultPort := defaultDNSPort
+		dot := strings.HasPrefix(addr, "tls://")
+		if dot {
+			addr, defaultPort = strings.TrimPrefix(addr, "tls://"), defaultDoTPort
+		}
+		addr, err := hostPort(addr, defaultPort)
+		if err != nil {
+			return err
+		}
+		switch {
+		case dot:
+			server.DoT = addr
+		case server.Primary == "":
+			server.Primary = addr
+		case server.Secondary == "":
+			server.Secondary = addr
+		default:
+			return fmt.Errorf("too many plain addresses in %q (want primary,secondary)", value)
+		}
+	}
+	if server.Primary == "" && server.DoT == "" && server.DoH == "" {
+		return fmt.Errorf("missing server address in %q", value)
+	}
+
+	*f = append(*f, server)
+	return nil
+}
+
+// endpoints returns the addresses to query for the given transport.
+// Plain DNS uses Primary and the optional Secondary, encrypted transports
+// use the server's DoT host or DoH URL.
+func (s *DNSServer) endpoints(transport string) []string {
+	switch transport {
+	case TransportDoT:
+		if s.DoT == "" {
+			return nil
+		}
+		if _, _, err := net.SplitHostPort(s.DoT); err != nil {
+			return []string{net.JoinHostPort(s.DoT, "853")}
+		}
+		return []string{s.DoT}
+	case TransportDoH:
+		if s.DoH == "" {
+			return nil
+		}
+		return []string{s.DoH}
+	}
+
+	var addrs []string
+	for _, addr := range []string{s.Primary, s.Secondary} {
+		if addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// hostPort adds defaultPort to an address given without one and checks the
+// port, accepting "1.1.1.1", "1.1.1.1:5353", "::1", "[::1]:5353" and hostnames
+func hostPort(addr string, defaultPort string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		// No port: a bare IPv6 address also fails to split
+		host = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+		if strings.Contains(host, ":") && net.ParseIP(host) == nil {
+			return "", fmt.Errorf("invalid server address %q", addr)
+		}
+		port = defaultPort
+	}
+	if host == "" {
+		return "", fmt.Errorf("invalid server address %q: missing host", addr)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("invalid server address %q: port must be 1-65535", addr)
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// Causes of a SERVFAIL according to the control resolver
+const (
+	ServfailDomain   = "domain"   // the control resolver fails the name too
+	ServfailResolver = "resolver" // the control resolver answers it
+)
+
+var (
+	// controlResolver answers the SERVFAIL cross-checks and the failure
+	// diagnostics (--control)
+	controlResolver  = trustedDoH
+	controlTransport = TransportDoH
+
+	// servfailCheck re-asks the control resolver for names that got a
+	// SERVFAIL (--servfail-check)
+	servfailCheck = true
+
+	// servfailVerdicts caches the control resolver's verdict per name and
+	// type, so a domain failing everywhere costs one control query
+	servfailVerdicts sync.Map // "name|type" -> *servfailVerdict
+)
+
+// servfailVerdict is the cause of one name's SERVFAILs, checked once
+type servfailVerdict struct {
+	once  sync.Once
+	cause string
+}
+
+// configureControl selects the control resolver: a DoH URL or a plain DNS
+// server as IP[:port]
+func configureControl(addr string) error {
+	if addr == "" {
+		return nil
+	}
+	if strings.HasPrefix(addr, "https://") {
+		controlResolver, controlTransport = addr, TransportDoH
+		return nil
+	}
+	hostport, err := hostPort(addr, "53")
+	if err != nil {
+		return fmt.Errorf("invalid control resolver %q: %v", addr, err)
+	}
+	controlResolver, controlTransport = hostport, TransportUDP
+	return nil
+}
+
+// crossCheckServfail asks the control resolver for a name that got a
+// SERVFAIL and returns ServfailDomain or ServfailResolver, or "" when the
+// control resolver could not be reached
+func crossCheckServfail(domain string, qtype uint16) string {
+	key := domain + "|" + dns.TypeToString[qtype]
+	v, _ := servfailVerdicts.LoadOrStore(key, &servfailVerdict{})
+	verdict := v.(*servfailVerdict)
+	verdict.once.Do(func() {
+		r, err := diagnosticQuery(controlTransport, controlResolver, domain, qtype, false)
+		switch {
+		case err != nil:
+		case r.Rcode == dns.RcodeServerFailure:
+			verdict.cause = ServfailDomain
+		default:
+			// NOERROR and NXDOMAIN are both answers from the domain's servers
+			verdict.cause = ServfailResolver
+		}
+	})
+	return verdict.cause
+}
+
+// printServfailCheck splits each server's SERVFAILs into domain-side
+// failures, which every resolver shares, and resolver-side ones, and shows
+// the success rate with the domain-side failures left out
+func printServfailCheck(statsList []*ServerStats, results []*BenchmarkResult) {
+	type servfailStats struct {
+		total, domain, resolver int
+	}
+	byServer := make(map[string]*servfailStats)
+	checked := false
+	for _, result := range results {
+		if result.Rcode != "SERVFAIL" {
+			continue
+		}
+		checked = checked || result.ServfailCause != ""
+		key := result.ServerName + "|" + result.ServerAddr
+		stats := byServer[key]
+		if stats == nil {
+			stats = &servfailStats{}
+			byServer[key] = stats
+		}
+		stats.total++
+		switch result.ServfailCause {
+		case ServfailDomain:
+			stats.domain++
+		case ServfailResolver:
+			stats.resolver++
+		}
+	}
+	if !checked {
+		// Not checked, or the control resolver never answered
+		return
+	}
+
+	fmt.Printf("\n%s[*] SERVFAIL Cross-check (control: %s):%s\n\n", ColorBlue, controlResolver, ColorReset)
+	fmt.Printf("%s%-30s | %-8s | %-11s | %-13s | %-9s | %-8s | %s%s\n",
+		ColorWhite, "Server", "SERVFAIL", "Domain-side", "Resolver-side", "Unchecked", "Success", "Adjusted", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼──────────┼─────────────┼───────────────┼───────────┼──────────┼─────────", ColorReset)
+
+	for _, server := range statsList {
+		stats, ok := byServer[server.ServerName+"|"+server.ServerAddr]
+		if !ok {
+			continue
+		}
+		adjusted := "-"
+		if counted := server.TotalQueries - stats.domain; counted > 0 {
+			adjusted = fmt.Sprintf("%7.1f%%", float64(server.SuccessQueries)/float64(counted)*100)
+		}
+		resolverColor := ColorGreen
+		if stats.resolver > 0 {
+			resolverColor = ColorRed
+		}
+		fmt.Printf("%-30s | %8d | %11d | %s%13d%s | %9d | %7.1f%% | %s\n",
+			fmt.Sprintf("%s (%s)", server.ServerName, server.ServerAddr),
+			stats.total, stats.domain, resolverColor, stats.resolver, ColorReset,
+			stats.total-stats.domain-stats.resolver, server.SuccessRate(), adjusted)
+	}
+	fmt.Printf("\n%s    Domain-side SERVFAILs fail on the control resolver too and are left out of the adjusted success rate%s\n", ColorCyan, ColorReset)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// defaultServiceName is used by install-service and uninstall-service
+// unless --name is given
+const defaultServiceName = "dnsbench-monitor"
+
+// serviceCtx is cancelled when the Windows service manager stops the
+// service; on other systems services are stopped with SIGTERM
+var serviceCtx = context.Background()
+
+// runInstallService implements "dnsbench install-service [flags] -- [monitor
+// flags]": it checks the monitor flags with a dry run, then registers a
+// service that runs monitor (or serve) with them at boot and restarts it
+// after a failure. A settings file in the working directory is kept.
+func runInstallService(args []string) {
+	fs := flag.NewFlagSet("install-service", flag.ExitOnError)
+	name := fs.String("name", defaultServiceName, "service name")
+	command := fs.String("command", "monitor", "command the service runs: monitor or serve")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: dnsbench install-service [flags] -- [monitor or serve flags]\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *command != "monitor" && *command != "serve" {
+		fmt.Printf("%s[!] --command must be monitor or serve%s\n", ColorRed, ColorReset)
+		os.Exit(2)
+	}
+
+	serviceArgs := append([]string{*command}, fs.Args()...)
+	if _, explicit := configPathArg(serviceArgs); !explicit {
+		// Services do not start in this directory, so name the settings file
+		if path, err := filepath.Abs(defaultConfigPath); err == nil {
+			if _, err := os.Stat(path); err == nil {
+				serviceArgs = append(serviceArgs, "--config", path)
+			}
+		}
+	}
+	if err := checkArgs(serviceArgs); err != nil {
+		fmt.Printf("%s[!] The service would not start: %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(2)
+	}
+
+	exe, err := executablePath()
+	if err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	if err := installService(*name, exe, serviceArgs); err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	fmt.Printf("%s[✓] Installed and started service %s%s\n", ColorGreen, *name, ColorReset)
+	fmt.Printf("    Runs: %s %s\n", exe, strings.Join(serviceArgs, " "))
+	printServiceHints(*name)
+}
+
+// runUninstallService implements "dnsbench uninstall-service": it stops the
+// service and removes it
+func runUninstallService(args []string) {
+	fs := flag.NewFlagSet("uninstall-service", flag.ExitOnError)
+	name := fs.String("name", defaultServiceName, "service name")
+	fs.Parse(args)
+
+	if err := uninstallService(*name); err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		os.Exit(1)
+	}
+	fmt.Printf("%s[✓] Stopped and removed service %s%s\n", ColorGreen, *name, ColorReset)
+}
+
+// executablePath returns the absolute path of the running binary, which the
+// service manager starts
+func executablePath() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(exe)
+}
+
+// checkArgs runs the command line with --dry-run in a child process, so
+// flags and the settings file are validated exactly as at startup
+func checkArgs(args []string) error {
+	exe, err := executablePath()
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	cmd := exec.Command(exe, append(args, "--dry-run")...)
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Run(); err != nil {
+		if problem := lastProblem(out.String()); problem != "" {
+			return errors.New(problem)
+		}
+		return err
+	}
+	return nil
+}
+
+// lastProblem returns the text of the last "[!]" line in output without
+// its colors, or the first line for errors of the flag package
+func lastProblem(output string) string {
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if _, problem, found := strings.Cut(lines[i], "[!] "); found {
+			for _, color := range []string{ColorRed, ColorYellow, ColorReset} {
+				problem = strings.ReplaceAll(problem, color, "")
+			}
+			return strings.TrimSpace(problem)
+		}
+	}
+	first, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	return first
+}
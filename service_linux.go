@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// systemdUnitDir holds units installed by the administrator
+const systemdUnitDir = "/etc/systemd/system"
+
+// installService writes a systemd unit that starts after the network is up,
+// restarts on failure and reloads with SIGHUP, then enables and starts it
+func installService(name string, exe string, args []string) error {
+	// The check sd_booted(3) uses: systemd installed is not systemd running
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		return errors.New("systemd is not running; start dnsbench " + args[0] + " from your init system instead")
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	command := []string{systemdQuote(exe)}
+	for _, arg := range args {
+		command = append(command, systemdQuote(arg))
+	}
+	unit := strings.Join([]string{
+		"[Unit]",
+		"Description=dnsbench " + args[0] + " of DNS resolvers",
+		"Wants=network-online.target",
+		"After=network-online.target",
+		"",
+		"[Service]",
+		"ExecStart=" + strings.Join(command, " "),
+		"ExecReload=/bin/kill -HUP $MAINPID",
+		"WorkingDirectory=" + systemdQuote(dir),
+		"Restart=on-failure",
+		"RestartSec=10",
+		"NoNewPrivileges=yes",
+		"",
+		"[Install]",
+		"WantedBy=multi-user.target",
+		"",
+	}, "\n")
+
+	path := filepath.Join(systemdUnitDir, name+".service")
+	if err := os.WriteFile(path, []byte(unit), 0o644); err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return fmt.Errorf("cannot write %s; run install-service as root (sudo)", path)
+		}
+		return err
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		os.Remove(path)
+		return err
+	}
+	if err := systemctl("enable", "--now", name); err != nil {
+		os.Remove(path)
+		systemctl("daemon-reload")
+		return err
+	}
+	return nil
+}
+
+// uninstallService stops and disables the unit and removes its file
+func uninstallService(name string) error {
+	path := filepath.Join(systemdUnitDir, name+".service")
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("service %s is not installed (%s not found)", name, path)
+	}
+	if err := systemctl("disable", "--now", name); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return fmt.Errorf("cannot remove %s; run uninstall-service as root (sudo)", path)
+		}
+		return err
+	}
+	return systemctl("daemon-reload")
+}
+
+// systemctl runs systemctl, returning its own message on failure
+func systemctl(args ...string) error {
+	out, err := exec.Command("systemctl", args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("systemctl %s: %s", strings.Join(args, " "), msg)
+		}
+		return fmt.Errorf("systemctl %s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// systemdQuote quotes a unit file argument: whitespace and quotes need
+// double quotes, and "%" and "$" would otherwise expand as specifiers and
+// variables
+func systemdQuote(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// printServiceHints shows how to follow and reload the service
+func printServiceHints(name string) {
+	fmt.Printf("    Logs: journalctl -u %s -f\n", name)
+	fmt.Printf("    Reload settings: systemctl reload %s\n", name)
+}
+
+// runAsService is only needed on Windows, where the service manager starts
+// the binary
+func runAsService(args []string) bool {
+	return false
+}
@@ -0,0 +1,27 @@
+//go:build !linux && !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// installService is only implemented for systemd and Windows
+func installService(name string, exe string, args []string) error {
+	return fmt.Errorf("services are not supported on %s; start %s %s from launchd, rc.d or cron instead", runtime.GOOS, exe, args[0])
+}
+
+// uninstallService is only implemented for systemd and Windows
+func uninstallService(name string) error {
+	return errors.New("services are not supported on " + runtime.GOOS)
+}
+
+func printServiceHints(name string) {}
+
+// runAsService is only needed on Windows, where the service manager starts
+// the binary
+func runAsService(args []string) bool {
+	return false
+}
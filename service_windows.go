@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// installService registers an automatically started service that the
+// service manager restarts after a failure, then starts it
+func installService(name string, exe string, args []string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("cannot reach the service manager (run from an administrator prompt): %w", err)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(name); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s already exists; run dnsbench uninstall-service first", name)
+	}
+	s, err := m.CreateService(name, exe, mgr.Config{
+		DisplayName: "dnsbench " + args[0],
+		Description: "Monitors DNS resolver latency and availability",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	s.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 10 * time.Second},
+	}, uint32((24 * time.Hour).Seconds()))
+	return s.Start()
+}
+
+// uninstallService stops the service, waiting briefly, and deletes it
+func uninstallService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("cannot reach the service manager (run from an administrator prompt): %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", name)
+	}
+	defer s.Close()
+	if status, err := s.Control(svc.Stop); err == nil {
+		for deadline := time.Now().Add(10 * time.Second); status.State != svc.Stopped && time.Now().Before(deadline); {
+			time.Sleep(300 * time.Millisecond)
+			if status, err = s.Query(); err != nil {
+				break
+			}
+		}
+	}
+	return s.Delete()
+}
+
+// printServiceHints shows how to manage the service; there is no console,
+// so serve with --listen is the way to watch it
+func printServiceHints(name string) {
+	fmt.Printf("    Manage: sc query %s, sc stop %s, sc start %s\n", name, name, name)
+	fmt.Printf("    Settings changes take effect on restart\n")
+}
+
+// runAsService runs the command under the service manager when the binary
+// was started as a service, and reports whether it was
+func runAsService(args []string) bool {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false
+	}
+	if err := svc.Run(os.Args[0], &windowsService{args: args}); err != nil {
+		os.Exit(1)
+	}
+	return true
+}
+
+// windowsService runs a dnsbench command until the service manager stops it
+type windowsService struct {
+	args []string
+}
+
+func (ws *windowsService) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	serviceCtx = ctx
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runCommand(ws.args)
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case <-done:
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+				select {
+				case <-done:
+				case <-time.After(10 * time.Second):
+				}
+				return false, 0
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// sharePasteURL receives reports for --share paste; paste.rs answers a
+	// POST with the URL of the new paste
+	sharePasteURL = "https://paste.rs/"
+
+	shareTimeout = 30 * time.Second
+)
+
+// validShareTarget checks a --share value: "paste" or an http(s) endpoint
+func validShareTarget(target string) error {
+	if target == "" || target == "paste" {
+		return nil
+	}
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("--share must be \"paste\" or an http(s) URL, got %q", target)
+	}
+	return nil
+}
+
+// shareReport uploads the report as HTML or as a results file (json) and
+// returns the link to it. "paste" posts to a public paste service; any other
+// target gets a PUT when it carries a signed query (S3 and compatible
+// pre-signed URLs) and a POST otherwise.
+func shareReport(target string, format string, report *Report) (string, error) {
+	var body bytes.Buffer
+	contentType := "text/html; charset=utf-8"
+	if format == "json" {
+		contentType = "application/json"
+		enc := json.NewEncoder(&body)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(&ResultsFile{
+			Version:   resultsFileVersion,
+			SavedAt:   report.GeneratedAt,
+			Mode:      report.Mode,
+			Transport: report.Transport,
+			Metadata:  report.Metadata,
+			Labels:    report.Labels,
+			Sources:   report.Sources,
+			Results:   report.Results,
+		}); err != nil {
+			return "", err
+		}
+	} else if err := writeHTML(&body, report); err != nil {
+		return "", err
+	}
+
+	method := http.MethodPost
+	if target == "paste" {
+		target = sharePasteURL
+	} else if u, _ := url.Parse(target); u.Query().Has("X-Amz-Signature") || u.Query().Has("Signature") {
+		method = http.MethodPut
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shareTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, target, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("share: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("share: %s", resp.Status)
+	}
+
+	// Paste services answer with the link; a Location header also names
+	// the upload, and a plain PUT leaves it where it was sent
+	if location := resp.Header.Get("Location"); location != "" {
+		return location, nil
+	}
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if link := strings.TrimSpace(string(reply)); strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://") {
+		if !strings.ContainsAny(link, " \n") {
+			return link, nil
+		}
+	}
+	if method == http.MethodPut {
+		// Strip the signature so the printed link does not grant write access
+		u, _ := url.Parse(target)
+		u.RawQuery = ""
+		return u.String(), nil
+	}
+	return target, nil
+}
+
+// printShare uploads the report and prints the link, noting what it reveals
+func printShare(target string, format string, report *Report) {
+	display := sharePasteURL
+	if target != "paste" {
+		// Keep signatures of pre-signed URLs out of the terminal
+		u, _ := url.Parse(target)
+		u.RawQuery = ""
+		display = u.String()
+	}
+	fmt.Printf("%s[*] Uploading the %s report to %s...%s\n", ColorBlue, format, display, ColorReset)
+	if report.Metadata != nil && report.Metadata.PublicIP != "" {
+		fmt.Printf("%s    The report includes your public IP, ISP and hostname; run with --metadata=false to leave them out%s\n", ColorYellow, ColorReset)
+	}
+	link, err := shareReport(target, format, report)
+	if err != nil {
+		fmt.Printf("%s[!] %v%s\n", ColorRed, err, ColorReset)
+		return
+	}
+	fmt.Printf("%s[✓] Shared: %s%s\n", ColorGreen, link, ColorReset)
+}
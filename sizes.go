@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// fragmentationThreshold is the EDNS buffer size recommended by DNS Flag
+	// Day 2020; larger UDP responses risk IP fragmentation
+	fragmentationThreshold = 1232
+
+	// paddingBlockSize is the query padding block size recommended by RFC 8467
+	paddingBlockSize = 128
+)
+
+// addSizes accumulates the message sizes of one result
+func (s *ServerStats) addSizes(result *BenchmarkResult) {
+	s.RequestBytes += result.RequestSize
+	if result.ResponseSize == 0 {
+		return
+	}
+	s.Responses++
+	s.ResponseBytes += result.ResponseSize
+	s.MaxResponse = max(s.MaxResponse, result.ResponseSize)
+	if result.ResponseSize > fragmentationThreshold {
+		s.LargeResponses++
+	}
+}
+
+// probePadding sends an RFC 7830 padded query and reports whether the
+// response carries a padding option as well
+func probePadding(transport string, addr string) (bool, error) {
+	m := &dns.Msg{}
+	m.SetQuestion(dns.Fqdn("example.com"), dns.TypeA)
+	m.SetEdns0(fragmentationThreshold, false)
+	padding := &dns.EDNS0_PADDING{}
+	opt := m.IsEdns0()
+	opt.Option = append(opt.Option, padding)
+	padding.Padding = make([]byte, (paddingBlockSize-m.Len()%paddingBlockSize)%paddingBlockSize)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	r, err := exchange(ctx, m, transport, addr)
+	if err != nil {
+		return false, err
+	}
+	if opt := r.IsEdns0(); opt != nil {
+		for _, option := range opt.Option {
+			if _, ok := option.(*dns.EDNS0_PADDING); ok {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// printMessageSizes reports average request/response sizes per server, how
+// often responses exceed the fragmentation-safe size, and padding support
+// when probe is set
+func printMessageSizes(statsList []*ServerStats, probe bool) {
+	fmt.Printf("\n%s[*] Message Sizes and EDNS Padding:%s\n\n", ColorBlue, ColorReset)
+
+	padded := make([]string, len(statsList))
+	var wg sync.WaitGroup
+	for i, stats := range statsList {
+		if !probe {
+			padded[i] = "-"
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := probePadding(stats.Transport, stats.ServerAddr)
+			switch {
+			case err != nil:
+				padded[i] = ColorYellow + "error" + ColorReset
+			case ok:
+				padded[i] = ColorGreen + "yes" + ColorReset
+			default:
+				padded[i] = "no"
+			}
+		}()
+	}
+	wg.Wait()
+
+	fmt.Printf("%s%-30s | %-10s | %-12s | %-12s | %-10s | %-7s%s\n",
+		ColorWhite, "Server", "Avg Query", "Avg Response", "Max Response", "> 1232 B", "Padding", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼────────────┼──────────────┼──────────────┼────────────┼────────", ColorReset)
+
+	large, plaintext := false, false
+	for i, stats := range statsList {
+		var avgRequest, avgResponse int
+		if stats.TotalQueries > 0 {
+			avgRequest = stats.RequestBytes / stats.TotalQueries
+		}
+		if stats.Responses > 0 {
+			avgResponse = stats.ResponseBytes / stats.Responses
+		}
+		largeCount := fmt.Sprintf("%10d", stats.LargeResponses)
+		if stats.LargeResponses > 0 {
+			largeCount = ColorRed + largeCount + ColorReset
+			large = true
+		}
+		if stats.Transport == TransportUDP || stats.Transport == TransportTCP {
+			plaintext = true
+		}
+
+		serverDisplay := fmt.Sprintf("%s (%s)", stats.ServerName, stats.ServerAddr)
+		fmt.Printf("%-30s | %8d B | %10d B | %10d B | %s | %s\n",
+			serverDisplay, avgRequest, avgResponse, stats.MaxResponse, largeCount, padded[i])
+	}
+
+	if large {
+		fmt.Printf("\n%s[!] Responses above %d bytes risk IP fragmentation over UDP%s\n", ColorYellow, fragmentationThreshold, ColorReset)
+	}
+	if plaintext {
+		fmt.Printf("\n%s    Resolvers usually pad only over encrypted transports (--transport dot or doh)%s\n", ColorCyan, ColorReset)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slaObjective is one target of --sla: either a latency percentile that must
+// stay under a threshold ("p95<30ms") or a minimum availability
+// ("availability>=99.9%")
+type slaObjective struct {
+	Spec       string
+	Percentile float64       // 0 for availability
+	Threshold  time.Duration // latency objectives only
+	Target     float64       // required percentage
+}
+
+// latency reports whether the objective bounds a latency percentile
+func (o slaObjective) latency() bool {
+	return o.Percentile > 0
+}
+
+// slaObjectives are checked by monitor mode over the whole run window
+var slaObjectives []slaObjective
+
+// configureSLA parses --sla, a comma-separated list of objectives
+func configureSLA(spec string) error {
+	slaObjectives = nil
+	for _, part := range splitList(spec) {
+		objective, err := parseSLAObjective(part)
+		if err != nil {
+			return fmt.Errorf("--sla: %w", err)
+		}
+		slaObjectives = append(slaObjectives, objective)
+	}
+	return nil
+}
+
+func parseSLAObjective(part string) (slaObjective, error) {
+	objective := slaObjective{Spec: strings.ReplaceAll(part, " ", "")}
+	metric, value, found := strings.Cut(objective.Spec, "<")
+	if found {
+		// "p95<30ms" and "p95<=30ms" mean the same for a nearest-rank percentile
+		value = strings.TrimPrefix(value, "=")
+		p, err := strconv.ParseFloat(strings.TrimPrefix(strings.ToLower(metric), "p"), 64)
+		if !strings.HasPrefix(strings.ToLower(metric), "p") || err != nil || p <= 0 || p >= 100 {
+			return objective, fmt.Errorf("%q: want a percentile like p95", metric)
+		}
+		threshold, err := time.ParseDuration(value)
+		if err != nil || threshold <= 0 {
+			return objective, fmt.Errorf("%q: want a duration like 30ms", value)
+		}
+		objective.Percentile, objective.Threshold, objective.Target = p, threshold, p
+		return objective, nil
+	}
+
+	metric, value, found = strings.Cut(objective.Spec, ">=")
+	if !found || (metric != "availability" && metric != "avail") {
+		return objective, fmt.Errorf("%q: want e.g. p95<30ms or availability>=99.9%%", part)
+	}
+	target, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil || target <= 0 || target > 100 {
+		return objective, fmt.Errorf("%q: want a percentage like 99.9%%", value)
+	}
+	objective.Target = target
+	return objective, nil
+}
+
+// slaTally counts one resolver's queries against every objective; counting
+// queries under each threshold gives exact percentile compliance without
+// keeping every RTT of a run that may last days
+type slaTally struct {
+	Total   int
+	Success int   // answered queries
+	Under   []int // answered queries under each objective's threshold
+}
+
+func (t *slaTally) add(result *BenchmarkResult) {
+	if t.Under == nil {
+		t.Under = make([]int, len(slaObjectives))
+	}
+	t.Total++
+	// NXDOMAIN and other rcodes are answers too; only silence is downtime
+	if unanswered(result) {
+		return
+	}
+	t.Success++
+	for i, objective := range slaObjectives {
+		if objective.latency() && result.RTT < objective.Threshold {
+			t.Under[i]++
+		}
+	}
+}
+
+// SLAStatus is one objective of one resolver, in the report and serve API
+type SLAStatus struct {
+	Objective string  `json:"objective"`
+	Actual    float64 `json:"actual_percent"` // availability, or share of answers under the threshold
+	Target    float64 `json:"target_percent"`
+	Samples   int     `json:"samples"`
+	Met       bool    `json:"met"`
+	Budget    float64 `json:"error_budget_used_percent"` // share of the allowed misses already spent
+}
+
+// compliance evaluates the tally against every objective
+func (t *slaTally) compliance() []SLAStatus {
+	statuses := make([]SLAStatus, len(slaObjectives))
+	for i, objective := range slaObjectives {
+		hits, total := t.Success, t.Total
+		if objective.latency() {
+			hits, total = t.Under[i], t.Success
+		}
+		status := SLAStatus{Objective: objective.Spec, Target: objective.Target, Samples: total}
+		if total > 0 {
+			status.Actual = float64(hits) / float64(total) * 100
+			allowed := (100 - objective.Target) / 100 * float64(total)
+			missed := float64(total - hits)
+			switch {
+			case allowed > 0:
+				status.Budget = missed / allowed * 100
+			case missed > 0:
+				status.Budget = 100
+			}
+		}
+		status.Met = total > 0 && status.Actual >= objective.Target
+		statuses[i] = status
+	}
+	return statuses
+}
+
+// slaCell renders the actual percentage and budget used, 22 columns wide
+func slaCell(status SLAStatus) string {
+	if status.Samples == 0 {
+		return ColorRed + fmt.Sprintf("%-22s", "no answers") + ColorReset
+	}
+	color := ColorGreen
+	if !status.Met {
+		color = ColorRed
+	}
+	budget := fmt.Sprintf("%4.0f%%", status.Budget)
+	if status.Budget > 999 {
+		budget = ">999%"
+	}
+	return fmt.Sprintf("%s%8.3f%% (%s bgt) %s", color, status.Actual, budget, ColorReset)
+}
+
+// printSLA reports each resolver's compliance over the monitoring window
+func printSLA(stats *monitorStats, started time.Time, rounds int) {
+	if len(slaObjectives) == 0 {
+		return
+	}
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	fmt.Printf("%s[*] SLA compliance, %s to %s (%d rounds):%s\n\n",
+		ColorBlue, started.Format("2006-01-02 15:04"), time.Now().Format("2006-01-02 15:04"), rounds, ColorReset)
+	fmt.Printf("%s%-28s", ColorWhite, "Resolver")
+	for _, objective := range slaObjectives {
+		fmt.Printf(" | %-22s", objective.Spec)
+	}
+	fmt.Printf(" | %s%s\n", "Compliant", ColorReset)
+	fmt.Printf("%s%s", ColorYellow, strings.Repeat("─", 29))
+	for range slaObjectives {
+		fmt.Printf("┼%s", strings.Repeat("─", 24))
+	}
+	fmt.Printf("┼──────────%s\n", ColorReset)
+
+	names := append([]string(nil), stats.order...)
+	sort.Strings(names)
+	var breached int
+	for _, name := range names {
+		fmt.Printf("%-28s", name)
+		compliant := true
+		for _, status := range stats.sla[name].compliance() {
+			if !status.Met {
+				compliant = false
+			}
+			fmt.Printf(" | %s", slaCell(status))
+		}
+		if compliant {
+			fmt.Printf(" | %syes%s\n", ColorGreen, ColorReset)
+		} else {
+			breached++
+			fmt.Printf(" | %sno%s\n", ColorRed, ColorReset)
+		}
+	}
+
+	fmt.Printf("\n%s    Latency cells show the share of answers under the threshold, availability the share answered;%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s    bgt is the share of the error budget (allowed misses) used.%s\n", ColorCyan, ColorReset)
+	if breached > 0 {
+		fmt.Printf("%s[!] %d of %d resolvers breached the SLA%s\n\n", ColorRed, breached, len(names), ColorReset)
+	} else {
+		fmt.Printf("%s[✓] Every resolver met the SLA%s\n\n", ColorGreen, ColorReset)
+	}
+}
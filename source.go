@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+)
+
+var (
+	// sourceIPs holds the local addresses outgoing sockets bind to (empty = OS default)
+	sourceIPs []net.IP
+
+	// sourceControl binds outgoing sockets to the --interface device where
+	// the OS supports it (nil = routing table decides)
+	sourceControl func(network, address string, c syscall.RawConn) error
+)
+
+// configureSource selects the local addresses used for outgoing queries,
+// either from an explicit --source-ip or from the addresses of --interface,
+// and binds sockets to --interface on Linux and macOS
+func configureSource(iface string, sourceIP string) error {
+	var ifi *net.Interface
+	if iface != "" {
+		var err error
+		if ifi, err = net.InterfaceByName(iface); err != nil {
+			return fmt.Errorf("interface %s: %w", iface, err)
+		}
+		sourceControl = bindInterface(ifi.Name, ifi.Index)
+	}
+
+	if sourceIP != "" {
+		ip := net.ParseIP(sourceIP)
+		if ip == nil {
+			return fmt.Errorf("invalid source IP %q", sourceIP)
+		}
+		sourceIPs = []net.IP{ip}
+		return nil
+	}
+
+	if ifi == nil {
+		return nil
+	}
+
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return fmt.Errorf("interface %s: %w", iface, err)
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		sourceIPs = append(sourceIPs, ipNet.IP)
+	}
+	if len(sourceIPs) == 0 {
+		return fmt.Errorf("interface %s has no usable addresses", iface)
+	}
+	return nil
+}
+
+// sourceIPFor picks a configured source address matching the target's address family.
+// Hostnames (non-IP targets) get the first IPv4 source, falling back to any source.
+func sourceIPFor(target string) net.IP {
+	if len(sourceIPs) == 0 {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(target)
+	if err != nil {
+		host = target
+	}
+	wantV4 := true
+	if ip := net.ParseIP(host); ip != nil {
+		wantV4 = ip.To4() != nil
+	}
+
+	for _, ip := range sourceIPs {
+		if (ip.To4() != nil) == wantV4 {
+			return ip
+		}
+	}
+	return sourceIPs[0]
+}
+
+// newDialer returns a dialer bound to the configured source address for the given target
+func newDialer(network string, target string) *net.Dialer {
+	dialer := &net.Dialer{Control: sourceControl}
+	ip := sourceIPFor(target)
+	if ip == nil {
+		return dialer
+	}
+
+	switch network {
+	case "udp", "udp4", "udp6":
+		dialer.LocalAddr = &net.UDPAddr{IP: ip}
+	default:
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+	return dialer
+}
+
+// listenUDP opens an unconnected UDP socket bound like newDialer's for
+// target, for QUIC, which sends and receives its packets itself
+func listenUDP(ctx context.Context, target string) (net.PacketConn, error) {
+	dialer := newDialer("udp", target)
+	local := ":0"
+	if dialer.LocalAddr != nil {
+		local = dialer.LocalAddr.String()
+	}
+	return (&net.ListenConfig{Control: dialer.Control}).ListenPacket(ctx, "udp", local)
+}
+
+// dialContext dials TCP from the configured source address, restricting the
+// connection to the source's address family so hostnames resolve compatibly
+func dialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	dialer := newDialer("tcp", addr)
+	ip := sourceIPFor(addr)
+	if ip == nil {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	if ip.To4() != nil {
+		network = "tcp4"
+	} else {
+		network = "tcp6"
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+func joinIPs(ips []net.IP) string {
+	var parts []string
+	for _, ip := range ips {
+		parts = append(parts, ip.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// dialContextVia returns a dial function that resolves hostnames through the
+// given DNS server instead of the system resolver (empty = system resolver)
+func dialContextVia(resolverAddr string) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	if resolverAddr == "" {
+		return dialContext
+	}
+	return dialResolved(resolverVia(resolverAddr), "ip")
+}
+
+// dialContextFamily is like dialContextVia but only connects to addresses of
+// one family: "ip4" (A records) or "ip6" (AAAA records)
+func dialContextFamily(resolverAddr string, family string) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	return dialResolved(resolverVia(resolverAddr), family)
+}
+
+// resolverVia returns a resolver querying the given DNS server (empty =
+// system resolver)
+func resolverVia(resolverAddr string) *net.Resolver {
+	if resolverAddr == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+			return newDialer(network, resolverAddr).DialContext(ctx, network, resolverAddr)
+		},
+	}
+}
+
+// dialResolved returns a dial function that looks hostnames up with resolver
+// and tries the resulting addresses of the given family in order
+func dialResolved(resolver *net.Resolver, family string) func(ctx context.Context, network string, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := resolver.LookupIP(ctx, family, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := dialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
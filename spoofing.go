@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// DNS-OARC's test zones: the resolver is made to send a chain of queries to
+// their servers, which rate the source ports or transaction IDs it used
+const (
+	portTestName = "porttest.dns-oarc.net."
+	txidTestName = "txidtest.dns-oarc.net."
+)
+
+// randomnessTestTimeout covers the chain of about 26 queries the resolver
+// sends to answer a port or TXID test
+const randomnessTestTimeout = 15 * time.Second
+
+// caseProbes is how many random-case queries are sent per resolver
+const caseProbes = 10
+
+// randomnessRating matches the verdict the DNS-OARC servers return, e.g.
+// "192.0.2.1 is GREAT: 26 queries in 1.2 seconds from 26 ports with std dev 17685"
+var randomnessRating = regexp.MustCompile(`is (\w+): (\d+) queries in [\d.]+ seconds from (\d+) (?:ports|txids) with std dev ([\d.]+)`)
+
+// randomnessReport is the DNS-OARC verdict on a resolver's ports or TXIDs
+type randomnessReport struct {
+	Rating  string // GREAT, GOOD or POOR; empty when the test gave no answer
+	Queries int
+	Unique  int
+	StdDev  float64
+}
+
+func (r randomnessReport) String() string {
+	if r.Rating == "" {
+		return "n/a"
+	}
+	return fmt.Sprintf("%s (%d/%d, σ %.0f)", r.Rating, r.Unique, r.Queries, r.StdDev)
+}
+
+// spoofingResult holds the checks of one resolver address
+type spoofingResult struct {
+	ServerName    string
+	ServerAddr    string
+	CaseSent      int
+	CasePreserved int
+	Ports         randomnessReport
+	TXIDs         randomnessReport
+}
+
+// runSpoofing checks what makes a resolver hard to poison that can be seen
+// from outside: whether it returns the question in the exact case it was
+// asked (needed by clients and forwarders using 0x20 encoding) and how
+// random the source ports and transaction IDs of its own upstream queries
+// are, as rated by DNS-OARC's porttest and txidtest services
+func runSpoofing(config *BenchmarkConfig) {
+	fmt.Printf("%s[*] Checking 0x20 case preservation and port/TXID randomness...%s\n", ColorBlue, ColorReset)
+	fmt.Printf("%s    The randomness tests make each resolver send about 26 queries to DNS-OARC and take a few seconds%s\n\n", ColorCyan, ColorReset)
+
+	var results []*spoofingResult
+	for _, server := range config.Servers {
+		for _, addr := range server.endpoints(config.Transport) {
+			result := &spoofingResult{ServerName: server.Name, ServerAddr: addr}
+			for i := 0; i < caseProbes; i++ {
+				domain := config.Domains[i%len(config.Domains)]
+				sent, echoed, ok := queryRandomCase(config.Transport, addr, domain)
+				if !ok {
+					continue
+				}
+				result.CaseSent++
+				if echoed == sent {
+					result.CasePreserved++
+				}
+			}
+			result.Ports = randomnessTest(config.Transport, addr, portTestName)
+			result.TXIDs = randomnessTest(config.Transport, addr, txidTestName)
+			results = append(results, result)
+		}
+	}
+	printSpoofing(results)
+}
+
+// queryRandomCase asks for domain in random letter case and returns the
+// name sent and the name in the question of the response
+func queryRandomCase(transport, addr, domain string) (string, string, bool) {
+	name := randomCase(queryName(domain))
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutFor(addr))
+	defer cancel()
+
+	m := &dns.Msg{}
+	m.SetQuestion(name, dns.TypeA)
+	r, err := exchange(ctx, m, transport, addr)
+	if err != nil || len(r.Question) == 0 {
+		return name, "", false
+	}
+	return name, r.Question[0].Name, true
+}
+
+// randomCase flips each letter to upper or lower case at random, making
+// sure at least one letter differs from the lowercase name
+func randomCase(name string) string {
+	b := []byte(strings.ToLower(name))
+	var letters []int
+	for i, c := range b {
+		if c >= 'a' && c <= 'z' {
+			letters = append(letters, i)
+			if rand.IntN(2) == 0 {
+				b[i] = c - 'a' + 'A'
+			}
+		}
+	}
+	if len(letters) > 0 && string(b) == strings.ToLower(name) {
+		i := letters[rand.IntN(len(letters))]
+		b[i] = b[i] - 'a' + 'A'
+	}
+	return string(b)
+}
+
+// randomnessTest runs one DNS-OARC test through the resolver and parses the
+// rating from the TXT answer
+func randomnessTest(transport, addr, name string) randomnessReport {
+	ctx, cancel := context.WithTimeout(context.Background(), max(timeoutFor(addr), randomnessTestTimeout))
+	defer cancel()
+
+	m := &dns.Msg{}
+	m.SetQuestion(name, dns.TypeTXT)
+	r, err := exchange(ctx, m, transport, addr)
+	if err != nil {
+		return randomnessReport{}
+	}
+	for _, rr := range r.Answer {
+		txt, ok := rr.(*dns.TXT)
+		if !ok {
+			continue
+		}
+		match := randomnessRating.FindStringSubmatch(strings.Join(txt.Txt, ""))
+		if match == nil {
+			continue
+		}
+		queries, _ := strconv.Atoi(match[2])
+		unique, _ := strconv.Atoi(match[3])
+		stdDev, _ := strconv.ParseFloat(match[4], 64)
+		return randomnessReport{Rating: match[1], Queries: queries, Unique: unique, StdDev: stdDev}
+	}
+	return randomnessReport{}
+}
+
+// spoofingResistance sums up the checks: strong when ports and TXIDs are
+// both rated GOOD or better, weak when either is POOR. Case preservation
+// only adds to it, since a resolver need not use 0x20 itself.
+func spoofingResistance(result *spoofingResult) (string, string) {
+	good := func(r randomnessReport) bool { return r.Rating == "GREAT" || r.Rating == "GOOD" }
+	switch {
+	case result.Ports.Rating == "POOR" || result.TXIDs.Rating == "POOR":
+		return "weak", ColorRed
+	case good(result.Ports) && good(result.TXIDs) && result.CasePreserved == result.CaseSent:
+		return "strong", ColorGreen
+	case good(result.Ports) && good(result.TXIDs):
+		return "good", ColorGreen
+	}
+	return "unknown", ColorYellow
+}
+
+func printSpoofing(results []*spoofingResult) {
+	fmt.Printf("%s%-30s | %-10s | %-22s | %-22s | %s%s\n",
+		ColorWhite, "Server", "0x20 Case", "Source Ports", "Transaction IDs", "Resistance", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼────────────┼────────────────────────┼────────────────────────┼───────────", ColorReset)
+
+	for _, result := range results {
+		serverDisplay := fmt.Sprintf("%s (%s)", result.ServerName, result.ServerAddr)
+		caseCell, caseColor := "-", ColorYellow
+		if result.CaseSent > 0 {
+			caseCell = fmt.Sprintf("%d/%d", result.CasePreserved, result.CaseSent)
+			caseColor = ColorGreen
+			if result.CasePreserved < result.CaseSent {
+				caseColor = ColorRed
+			}
+		}
+		resistance, color := spoofingResistance(result)
+		fmt.Printf("%-30s | %s%-10s%s | %s%-22s%s | %s%-22s%s | %s%s%s\n",
+			serverDisplay,
+			caseColor, caseCell, ColorReset,
+			ratingColor(result.Ports), result.Ports, ColorReset,
+			ratingColor(result.TXIDs), result.TXIDs, ColorReset,
+			color, resistance, ColorReset)
+	}
+
+	fmt.Printf("\n%s    0x20 Case: answers whose question kept the random letter case of the query%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s    Ports and IDs: DNS-OARC rating (unique/queries, standard deviation) of the resolver's own upstream queries%s\n", ColorCyan, ColorReset)
+	fmt.Printf("%s    n/a means the test zone did not answer through this resolver, so randomness could not be observed%s\n\n", ColorCyan, ColorReset)
+}
+
+// ratingColor colors a DNS-OARC rating
+func ratingColor(r randomnessReport) string {
+	switch r.Rating {
+	case "GREAT", "GOOD":
+		return ColorGreen
+	case "POOR":
+		return ColorRed
+	}
+	return ColorYellow
+}
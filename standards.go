@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Values of the EDNS probes, as used by ISC's EDNS compliance tester: option
+// code 100 and EDNS flag 0x80 are unassigned, so resolvers must ignore them
+const (
+	unknownEDNSOption = 100
+	unknownEDNSFlag   = 0x80
+	unknownOpcode     = 15
+)
+
+// standardsProbe is one protocol behavior check; check returns a short
+// verdict and whether it is what the RFCs ask for
+type standardsProbe struct {
+	Name   string
+	Column string
+	Build  func(name string) *dns.Msg
+	Check  func(r *dns.Msg, err error) (string, bool)
+}
+
+// standardsProbes are sent to every resolver after a plain query confirms it
+// answers at all
+var standardsProbes = []standardsProbe{
+	{
+		Name:   "ANY query (RFC 8482 minimal response)",
+		Column: "ANY",
+		Build: func(name string) *dns.Msg {
+			m := &dns.Msg{}
+			m.SetQuestion(name, dns.TypeANY)
+			m.SetEdns0(dns.DefaultMsgSize, false)
+			return m
+		},
+		Check: checkANY,
+	},
+	{
+		Name:   "EDNS(0) query answered with an OPT record (RFC 6891)",
+		Column: "EDNS",
+		Build: func(name string) *dns.Msg {
+			m := &dns.Msg{}
+			m.SetQuestion(name, dns.TypeSOA)
+			m.SetEdns0(dns.DefaultMsgSize, false)
+			return m
+		},
+		Check: func(r *dns.Msg, err error) (string, bool) {
+			if err != nil || r.Rcode != dns.RcodeSuccess {
+				return probeFailure(r, err), false
+			}
+			if r.IsEdns0() == nil {
+				return "no OPT", false
+			}
+			return "ok", true
+		},
+	},
+	{
+		Name:   "EDNS version 1 answered with BADVERS (RFC 6891 6.1.3)",
+		Column: "Version",
+		Build: func(name string) *dns.Msg {
+			m := &dns.Msg{}
+			m.SetQuestion(name, dns.TypeSOA)
+			m.SetEdns0(dns.DefaultMsgSize, false)
+			m.IsEdns0().SetVersion(1)
+			return m
+		},
+		Check: func(r *dns.Msg, err error) (string, bool) {
+			if err != nil {
+				return probeFailure(r, err), false
+			}
+			opt := r.IsEdns0()
+			switch {
+			case r.Rcode == dns.RcodeBadVers && opt != nil && opt.Version() == 0:
+				return "ok", true
+			case r.Rcode == dns.RcodeBadVers:
+				return "bad OPT", false
+			case r.Rcode == dns.RcodeSuccess:
+				return "ignored", false
+			}
+			return probeFailure(r, err), false
+		},
+	},
+	{
+		Name:   "Unknown EDNS option ignored, not echoed (RFC 6891 6.1.2)",
+		Column: "Option",
+		Build: func(name string) *dns.Msg {
+			m := &dns.Msg{}
+			m.SetQuestion(name, dns.TypeSOA)
+			m.SetEdns0(dns.DefaultMsgSize, false)
+			opt := m.IsEdns0()
+			opt.Option = append(opt.Option, &dns.EDNS0_LOCAL{Code: unknownEDNSOption, Data: []byte{}})
+			return m
+		},
+		Check: func(r *dns.Msg, err error) (string, bool) {
+			if err != nil || r.Rcode != dns.RcodeSuccess {
+				return probeFailure(r, err), false
+			}
+			if opt := r.IsEdns0(); opt != nil {
+				for _, option := range opt.Option {
+					if option.Option() == unknownEDNSOption {
+						return "echoed", false
+					}
+				}
+			}
+			return "ok", true
+		},
+	},
+	{
+		Name:   "Unknown EDNS flag ignored and cleared (RFC 6891 6.1.4)",
+		Column: "EDNS Flag",
+		Build: func(name string) *dns.Msg {
+			m := &dns.Msg{}
+			m.SetQuestion(name, dns.TypeSOA)
+			m.SetEdns0(dns.DefaultMsgSize, false)
+			m.IsEdns0().SetZ(unknownEDNSFlag)
+			return m
+		},
+		Check: func(r *dns.Msg, err error) (string, bool) {
+			if err != nil || r.Rcode != dns.RcodeSuccess {
+				return probeFailure(r, err), false
+			}
+			if opt := r.IsEdns0(); opt != nil && opt.Z()&unknownEDNSFlag != 0 {
+				return "echoed", false
+			}
+			return "ok", true
+		},
+	},
+	{
+		Name:   "Reserved header Z bit ignored and cleared (RFC 1035 4.1.1)",
+		Column: "Z Bit",
+		Build: func(name string) *dns.Msg {
+			m := &dns.Msg{}
+			m.SetQuestion(name, dns.TypeSOA)
+			m.Zero = true
+			return m
+		},
+		Check: func(r *dns.Msg, err error) (string, bool) {
+			if err != nil || r.Rcode != dns.RcodeSuccess {
+				return probeFailure(r, err), false
+			}
+			if r.Zero {
+				return "echoed", false
+			}
+			return "ok", true
+		},
+	},
+	{
+		Name:   "Unknown opcode answered with NOTIMP (RFC 1035 4.1.1)",
+		Column: "Opcode",
+		Build: func(name string) *dns.Msg {
+			m := &dns.Msg{}
+			m.SetQuestion(name, dns.TypeSOA)
+			m.Opcode = unknownOpcode
+			return m
+		},
+		Check: func(r *dns.Msg, err error) (string, bool) {
+			if err == nil && r.Rcode == dns.RcodeNotImplemented {
+				return "ok", true
+			}
+			return probeFailure(r, err), false
+		},
+	},
+}
+
+// standardsResult holds the verdicts of one resolver address, in probe order
+type standardsResult struct {
+	ServerName string
+	ServerAddr string
+	Reachable  bool
+	Verdicts   []string
+	Passed     []bool
+}
+
+// runStandards checks how each resolver handles the corners of the protocol
+// that trip up middleboxes and old software: ANY queries, EDNS versions,
+// unknown options and flags, the reserved header bit and unknown opcodes.
+// A failure here rarely shows in everyday lookups, but explains breakage
+// with DNSSEC, cookies and future extensions.
+func runStandards(config *BenchmarkConfig) {
+	name := queryName(config.Domains[0])
+	fmt.Printf("%s[*] Probing standards behavior with %s...%s\n\n", ColorBlue, strings.TrimSuffix(name, "."), ColorReset)
+
+	var results []*standardsResult
+	for _, server := range config.Servers {
+		for _, addr := range server.endpoints(config.Transport) {
+			result := &standardsResult{ServerName: server.Name, ServerAddr: addr}
+			results = append(results, result)
+
+			m := &dns.Msg{}
+			m.SetQuestion(name, dns.TypeSOA)
+			if r, err := probeExchange(config.Transport, addr, m); err != nil || r.Rcode != dns.RcodeSuccess {
+				continue
+			}
+			result.Reachable = true
+			for _, probe := range standardsProbes {
+				r, err := probeExchange(config.Transport, addr, probe.Build(name))
+				verdict, ok := probe.Check(r, err)
+				result.Verdicts = append(result.Verdicts, verdict)
+				result.Passed = append(result.Passed, ok)
+			}
+		}
+	}
+	printStandards(results)
+}
+
+// probeExchange sends one probe with the resolver's timeout
+func probeExchange(transport, addr string, m *dns.Msg) (*dns.Msg, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutFor(addr))
+	defer cancel()
+	return exchange(ctx, m, transport, addr)
+}
+
+// checkANY classifies the answer to an ANY query: the RFC 8482 HINFO
+// placeholder or a single RRset are minimal, every RRset at once is the
+// amplification vector RFC 8482 retires
+func checkANY(r *dns.Msg, err error) (string, bool) {
+	if err != nil {
+		return probeFailure(r, err), false
+	}
+	switch r.Rcode {
+	case dns.RcodeSuccess:
+	case dns.RcodeNotImplemented, dns.RcodeRefused:
+		// Allowed, though RFC 8482 prefers a minimal answer
+		return dns.RcodeToString[r.Rcode], true
+	default:
+		return probeFailure(r, err), false
+	}
+	if r.Truncated {
+		return "TC", true
+	}
+	types := make(map[uint16]bool)
+	for _, rr := range r.Answer {
+		if hinfo, ok := rr.(*dns.HINFO); ok && hinfo.Cpu == "RFC8482" {
+			return "HINFO", true
+		}
+		if rr.Header().Rrtype != dns.TypeRRSIG {
+			types[rr.Header().Rrtype] = true
+		}
+	}
+	switch len(types) {
+	case 0:
+		return "empty", true
+	case 1:
+		return "subset", true
+	}
+	return "full", false
+}
+
+// probeFailure names what went wrong with a probe: timeout, a network error
+// or the unexpected rcode
+func probeFailure(r *dns.Msg, err error) string {
+	switch {
+	case err != nil && isTimeout(err):
+		return "timeout"
+	case err != nil:
+		return "error"
+	case r.Rcode == dns.RcodeBadVers && r.IsEdns0() != nil:
+		return "BADVERS"
+	}
+	if rcode, ok := dns.RcodeToString[r.Rcode]; ok {
+		return rcode
+	}
+	return fmt.Sprintf("rcode %d", r.Rcode)
+}
+
+func printStandards(results []*standardsResult) {
+	fmt.Printf("%s[*] Standards behavior:%s\n\n", ColorBlue, ColorReset)
+	fmt.Printf("%s%-30s", ColorWhite, "Server")
+	separator := "───────────────────────────────"
+	for _, probe := range standardsProbes {
+		fmt.Printf(" | %-9s", probe.Column)
+		separator += "┼───────────"
+	}
+	fmt.Printf("%s\n", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, separator, ColorReset)
+
+	var compliant, reachable int
+	failures := make([]int, len(standardsProbes))
+	for _, result := range results {
+		fmt.Printf("%-30s", fmt.Sprintf("%s (%s)", result.ServerName, result.ServerAddr))
+		if !result.Reachable {
+			fmt.Printf(" | %sunreachable%s\n", ColorRed, ColorReset)
+			continue
+		}
+		reachable++
+		passedAll := true
+		for i, verdict := range result.Verdicts {
+			color := ColorGreen
+			if !result.Passed[i] {
+				color = ColorRed
+				failures[i]++
+				passedAll = false
+			}
+			fmt.Printf(" | %s%-9s%s", color, verdict, ColorReset)
+		}
+		fmt.Printf("\n")
+		if passedAll {
+			compliant++
+		}
+	}
+
+	fmt.Printf("\n")
+	for i, probe := range standardsProbes {
+		fmt.Printf("%s    %-9s %s%s\n", ColorCyan, probe.Column, probe.Name, ColorReset)
+		if failures[i] > 0 {
+			fmt.Printf("%s              failed by %d of %d resolvers%s\n", ColorYellow, failures[i], reachable, ColorReset)
+		}
+	}
+	switch {
+	case reachable > 0 && compliant == reachable:
+		fmt.Printf("\n%s[✓] All %d reachable resolvers behave as the RFCs ask%s\n\n", ColorGreen, reachable, ColorReset)
+	case reachable > 0:
+		fmt.Printf("\n%s[!] %d of %d reachable resolvers deviate from the RFCs%s\n\n", ColorYellow, reachable-compliant, reachable, ColorReset)
+	default:
+		fmt.Printf("\n%s[!] No resolver answered the plain query%s\n\n", ColorRed, ColorReset)
+	}
+}
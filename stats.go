@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// percentile returns the p-th percentile (0-100) of the given durations
+// using nearest-rank on a sorted copy
+func percentile(values []time.Duration, p float64) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	rank = max(0, min(rank, len(sorted)-1))
+	return sorted[rank]
+}
+
+// ms converts a duration to fractional milliseconds for display
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// tTable holds two-sided 95% critical values of Student's t for 1 to 30
+// degrees of freedom
+var tTable = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// tCritical95 returns the 95% critical value for df degrees of freedom,
+// rounding df down so small samples err on the wide side
+func tCritical95(df float64) float64 {
+	switch {
+	case df < 1:
+		return math.Inf(1)
+	case df <= float64(len(tTable)):
+		return tTable[int(df)-1]
+	case df <= 60:
+		return 2.000
+	case df <= 120:
+		return 1.980
+	}
+	return 1.960
+}
+
+// meanVariance returns the mean and sample variance in nanoseconds
+func meanVariance(values []time.Duration) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += float64(v)
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 {
+		return mean, 0
+	}
+	var squares float64
+	for _, v := range values {
+		squares += (float64(v) - mean) * (float64(v) - mean)
+	}
+	return mean, squares / float64(len(values)-1)
+}
+
+// confidence95 returns the half-width of the 95% confidence interval of the
+// mean, or 0 with fewer than two values
+func confidence95(values []time.Duration) time.Duration {
+	if len(values) < 2 {
+		return 0
+	}
+	_, variance := meanVariance(values)
+	n := float64(len(values))
+	return time.Duration(tCritical95(n-1) * math.Sqrt(variance/n))
+}
+
+// significantlyDifferent runs Welch's t-test on two RTT samples and reports
+// whether their means differ at 95% confidence
+func significantlyDifferent(a []time.Duration, b []time.Duration) bool {
+	if len(a) < 2 || len(b) < 2 {
+		return false
+	}
+	meanA, varA := meanVariance(a)
+	meanB, varB := meanVariance(b)
+	seA, seB := varA/float64(len(a)), varB/float64(len(b))
+	if seA+seB == 0 {
+		return meanA != meanB
+	}
+	t := math.Abs(meanA-meanB) / math.Sqrt(seA+seB)
+	df := (seA + seB) * (seA + seB) / (seA*seA/float64(len(a)-1) + seB*seB/float64(len(b)-1))
+	return t > tCritical95(df)
+}
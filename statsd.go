@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// statsdMaxPacket keeps batched StatsD datagrams under a typical path MTU
+const statsdMaxPacket = 1432
+
+// statsdAddr is the StatsD/DogStatsD agent address; empty when disabled
+var statsdAddr string
+
+// configureStatsD validates the agent address (host:port, port 8125 if omitted)
+func configureStatsD(addr string) error {
+	if addr == "" {
+		return nil
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "8125")
+	}
+	if _, err := net.ResolveUDPAddr("udp", addr); err != nil {
+		return fmt.Errorf("invalid --statsd %q: %v", addr, err)
+	}
+	statsdAddr = addr
+	return nil
+}
+
+// statsdTag sanitizes a DogStatsD tag value; ',', '|' and '#' are separators
+func statsdTag(value string) string {
+	return strings.NewReplacer(",", "_", "|", "_", "#", "_", " ", "_").Replace(value)
+}
+
+// statsdLines renders a timing per successful query and a counter per query,
+// tagged with the resolver, endpoint, transport and status
+func statsdLines(results []*BenchmarkResult) []string {
+	var lines []string
+	for _, result := range results {
+		tags := fmt.Sprintf("#resolver:%s,server:%s,transport:%s",
+			statsdTag(result.ServerName), statsdTag(result.ServerAddr), result.Transport)
+		if result.Status == "SUCCESS" {
+			lines = append(lines, fmt.Sprintf("dnsbench.query.rtt:%.3f|ms|%s", ms(result.RTT), tags))
+		}
+		lines = append(lines, fmt.Sprintf("dnsbench.query.count:1|c|%s,status:%s", tags, strings.ToLower(result.Status)))
+	}
+	return lines
+}
+
+// exportStatsD sends the metrics of results to the agent over UDP, batching
+// newline-separated lines into datagrams
+func exportStatsD(results []*BenchmarkResult) error {
+	if statsdAddr == "" || len(results) == 0 {
+		return nil
+	}
+	conn, err := net.Dial("udp", statsdAddr)
+	if err != nil {
+		return fmt.Errorf("statsd: %w", err)
+	}
+	defer conn.Close()
+
+	var packet strings.Builder
+	flush := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := conn.Write([]byte(packet.String()))
+		packet.Reset()
+		return err
+	}
+	for _, line := range statsdLines(results) {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > statsdMaxPacket {
+			if err := flush(); err != nil {
+				return fmt.Errorf("statsd: %w", err)
+			}
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	if err := flush(); err != nil {
+		return fmt.Errorf("statsd: %w", err)
+	}
+	return nil
+}
+
+// reportStatsD exports results and prints the outcome
+func reportStatsD(results []*BenchmarkResult) {
+	if statsdAddr == "" {
+		return
+	}
+	if err := exportStatsD(results); err != nil {
+		slog.Warn("Export failed", "err", err)
+		return
+	}
+	fmt.Printf("%s[✓] Sent StatsD metrics for %d queries to %s%s\n", ColorGreen, len(results), statsdAddr, ColorReset)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+// outageStreak is the number of consecutive failures from which a streak is
+// reported as an outage rather than scattered loss
+const outageStreak = 3
+
+// failureStreaks is the longest run of consecutive failed queries to one
+// resolver address, in the order the queries were sent
+type failureStreaks struct {
+	ServerName string
+	ServerAddr string
+	Failures   int
+	Queries    int
+	Longest    int
+	Start      time.Time // when the longest streak's first query was sent
+	Duration   time.Duration
+}
+
+// unanswered reports whether a result means the resolver gave no usable
+// answer: timeouts, transport errors and error rcodes other than NXDOMAIN,
+// which is a valid answer for a name that does not exist
+func unanswered(result *BenchmarkResult) bool {
+	return result.Status == "TIMEOUT" || (result.Status == "FAILED" && result.Rcode != "NXDOMAIN")
+}
+
+// queryOutcome is what the streak report needs of one query
+type queryOutcome struct {
+	sent       time.Time
+	rtt        time.Duration
+	unanswered bool
+}
+
+// addOutcome records whether a query to the server was answered
+func (a *aggregator) addOutcome(key string, result *BenchmarkResult) {
+	a.outcomes[key] = append(a.outcomes[key], queryOutcome{result.Timestamp, result.RTT, unanswered(result)})
+}
+
+// failureStreaks finds the longest failure streak per resolver address;
+// addresses without failures are left out
+func (a *aggregator) failureStreaks() []*failureStreaks {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var streaks []*failureStreaks
+	for key, outcomes := range a.outcomes {
+		// Concurrent queries finish out of order; streaks follow send order
+		list := slices.Clone(outcomes)
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].sent.Before(list[j].sent)
+		})
+		s := &failureStreaks{ServerName: a.servers[key].ServerName, ServerAddr: a.servers[key].ServerAddr, Queries: len(list)}
+		run := 0
+		for i, outcome := range list {
+			if !outcome.unanswered {
+				run = 0
+				continue
+			}
+			s.Failures++
+			run++
+			if run > s.Longest {
+				first := list[i-run+1]
+				s.Longest = run
+				s.Start = first.sent
+				s.Duration = outcome.sent.Add(outcome.rtt).Sub(first.sent)
+			}
+		}
+		if s.Failures > 0 {
+			streaks = append(streaks, s)
+		}
+	}
+	sort.Slice(streaks, func(i, j int) bool {
+		if streaks[i].Longest != streaks[j].Longest {
+			return streaks[i].Longest > streaks[j].Longest
+		}
+		return streaks[i].ServerAddr < streaks[j].ServerAddr
+	})
+	return streaks
+}
+
+// printFailureStreaks tells resolvers with scattered loss apart from ones
+// that stopped answering for a while
+func printFailureStreaks(agg *aggregator) {
+	streaks := agg.failureStreaks()
+	if len(streaks) == 0 {
+		return
+	}
+	fmt.Printf("\n%s[*] Failure Streaks (consecutive failed queries per address):%s\n\n", ColorBlue, ColorReset)
+	fmt.Printf("%s%-30s | %-10s | %-14s | %-10s | %-12s | %s%s\n",
+		ColorWhite, "Server", "Failures", "Longest Streak", "Started", "Dark For", "Pattern", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼────────────┼────────────────┼────────────┼──────────────┼──────────", ColorReset)
+
+	for _, s := range streaks {
+		pattern := ColorYellow + "scattered" + ColorReset
+		if s.Longest >= outageStreak {
+			pattern = ColorRed + "outage" + ColorReset
+		}
+		if s.Failures == s.Queries {
+			pattern = ColorRed + "never answered" + ColorReset
+		}
+		serverDisplay := fmt.Sprintf("%s (%s)", s.ServerName, s.ServerAddr)
+		fmt.Printf("%-30s | %4d/%-5d | %14d | %-10s | %9.1f s | %s\n",
+			serverDisplay, s.Failures, s.Queries, s.Longest, consoleTime(s.Start, "15:04:05"), s.Duration.Seconds(), pattern)
+	}
+}
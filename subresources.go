@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// subresourceBodyLimit caps how much of each page is read for references
+const subresourceBodyLimit = 2 << 20
+
+// subresourceAttrs are the attributes of each tag that load something
+// while the page renders; <a href> is navigation and left out
+var subresourceAttrs = map[string][]string{
+	"script": {"src"},
+	"link":   {"href"},
+	"img":    {"src"},
+	"iframe": {"src"},
+	"source": {"src"},
+	"video":  {"src", "poster"},
+	"audio":  {"src"},
+	"embed":  {"src"},
+}
+
+// expandSubresources fetches every test page once and adds up to limit
+// hostnames each references (scripts, stylesheets, CDNs, trackers) to the
+// queried domains, so the workload resembles real page loads. Pages that
+// cannot be fetched keep only their own hostname.
+func expandSubresources(config *BenchmarkConfig, limit int) {
+	fmt.Printf("%s[*] Fetching %d pages for the hostnames they reference...%s\n", ColorBlue, len(config.Domains), ColorReset)
+	client := &http.Client{Timeout: config.HTTPTimeout}
+
+	config.Subresources = make(map[string][]string)
+	for _, page := range config.Domains {
+		hosts, err := pageSubresources(client, page, limit)
+		if err != nil {
+			fmt.Printf("    %s[!] %s: %v%s\n", ColorYellow, page, err, ColorReset)
+			continue
+		}
+		config.Subresources[page] = hosts
+		var added []string
+		for _, host := range hosts {
+			if !slices.Contains(config.Domains, host) && !slices.Contains(config.SubresourceHosts, host) {
+				config.SubresourceHosts = append(config.SubresourceHosts, host)
+				added = append(added, host)
+			}
+		}
+		fmt.Printf("    %-30s %d hosts, %d new\n", page, len(hosts), len(added))
+	}
+	config.Domains = append(config.Domains, config.SubresourceHosts...)
+	fmt.Printf("%s[✓] Added %d subresource hostnames, %d domains in total%s\n\n", ColorGreen, len(config.SubresourceHosts), len(config.Domains), ColorReset)
+}
+
+// pageSubresources returns the hostnames other than its own that a page
+// loads from, in document order
+func pageSubresources(client *http.Client, page string, limit int) ([]string, error) {
+	resp, err := client.Get("https://" + page + "/")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	// After redirects, relative references resolve against the final URL
+	base := resp.Request.URL
+	var hosts []string
+	tokenizer := html.NewTokenizer(io.LimitReader(resp.Body, subresourceBodyLimit))
+	for len(hosts) < limit {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// io.EOF at the end of the page
+			return hosts, nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			for _, attr := range token.Attr {
+				if !slices.Contains(subresourceAttrs[token.Data], attr.Key) {
+					continue
+				}
+				ref, err := base.Parse(strings.TrimSpace(attr.Val))
+				if err != nil || (ref.Scheme != "http" && ref.Scheme != "https") {
+					continue
+				}
+				host := strings.ToLower(ref.Hostname())
+				if host == "" || host == base.Hostname() || net.ParseIP(host) != nil || slices.Contains(hosts, host) {
+					continue
+				}
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return hosts, nil
+}
+
+// pages returns the test domains without the hosts --subresources added
+func (c *BenchmarkConfig) pages() []string {
+	if len(c.SubresourceHosts) == 0 {
+		return c.Domains
+	}
+	var pages []string
+	for _, domain := range c.Domains {
+		if !slices.Contains(c.SubresourceHosts, domain) {
+			pages = append(pages, domain)
+		}
+	}
+	return pages
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// systemTimeout bounds one lookup through either path
+const systemTimeout = 5 * time.Second
+
+// systemResolver resolves through the OS: getaddrinfo when the binary is
+// built with cgo, otherwise Go's own resolver following resolv.conf and
+// nsswitch.conf
+var systemResolver = &net.Resolver{PreferGo: false}
+
+// runSystemResolver resolves every test domain through the OS resolver path
+// and directly against the first resolv.conf nameserver, alternating between
+// the two, to show how much a stub resolver (nscd, systemd-resolved, the libc
+// resolver) adds to or saves on each lookup
+func runSystemResolver(config *BenchmarkConfig) {
+	resolvConf, err := dns.ClientConfigFromFile(resolvConfPath)
+	if err != nil || len(resolvConf.Servers) == 0 {
+		fmt.Printf("%s[!] No nameserver in %s to query directly: %v%s\n", ColorRed, resolvConfPath, err, ColorReset)
+		return
+	}
+	addr := net.JoinHostPort(resolvConf.Servers[0], resolvConf.Port)
+
+	fmt.Printf("%s[*] Resolving %d domains %d times through the system resolver and directly via %s...%s\n\n",
+		ColorBlue, len(config.Domains), config.QueryNum, addr, ColorReset)
+
+	var systemRTTs, directRTTs []time.Duration
+	var systemFailed, directFailed int
+	for i := 0; i < config.QueryNum; i++ {
+		for _, domain := range config.Domains {
+			name := queryName(domain)
+			// Alternate which path goes first so neither always warms the
+			// other's cache
+			paths := []bool{true, false}
+			if i%2 == 1 {
+				paths = []bool{false, true}
+			}
+			for _, system := range paths {
+				var rtt time.Duration
+				var err error
+				if system {
+					rtt, err = lookupSystem(name)
+				} else {
+					rtt, err = lookupDirect(addr, name)
+				}
+				switch {
+				case err != nil && system:
+					systemFailed++
+				case err != nil:
+					directFailed++
+				case system:
+					systemRTTs = append(systemRTTs, rtt)
+				default:
+					directRTTs = append(directRTTs, rtt)
+				}
+			}
+		}
+	}
+
+	fmt.Printf("%s%-30s | %-12s | %-12s | %-12s | %-8s%s\n", ColorWhite, "Path", "Average", "Median", "P95", "Failed", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼──────────────┼──────────────┼──────────────┼─────────", ColorReset)
+	printSystemPath("System (getaddrinfo)", systemRTTs, systemFailed)
+	printSystemPath("Direct ("+addr+")", directRTTs, directFailed)
+	fmt.Printf("\n")
+
+	if len(systemRTTs) == 0 || len(directRTTs) == 0 {
+		fmt.Printf("%s[!] Not enough answers on both paths to compare%s\n\n", ColorYellow, ColorReset)
+		return
+	}
+	overhead := percentile(systemRTTs, 50) - percentile(directRTTs, 50)
+	if overhead > 0 {
+		fmt.Printf("%s[*] The system resolver path adds %.2f ms per lookup (median)%s\n", ColorYellow, ms(overhead), ColorReset)
+	} else {
+		fmt.Printf("%s[✓] The system resolver path is %.2f ms faster per lookup (median), likely a local cache%s\n", ColorGreen, ms(-overhead), ColorReset)
+	}
+	fmt.Printf("%s    Direct lookups send A and AAAA in parallel like getaddrinfo does; build with CGO_ENABLED=1 to measure libc rather than Go's resolver%s\n\n", ColorCyan, ColorReset)
+}
+
+// lookupSystem resolves name through the OS resolver
+func lookupSystem(name string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), systemTimeout)
+	defer cancel()
+	start := time.Now()
+	_, err := systemResolver.LookupHost(ctx, name)
+	return time.Since(start), err
+}
+
+// lookupDirect sends the A and AAAA queries getaddrinfo would send, in
+// parallel, and returns once both are answered
+func lookupDirect(addr string, name string) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), systemTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	start := time.Now()
+	for i, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m := &dns.Msg{}
+			m.SetQuestion(name, qtype)
+			r, err := exchange(ctx, m, TransportUDP, addr)
+			if err == nil && r.Rcode != dns.RcodeSuccess {
+				// getaddrinfo fails on these as well
+				err = fmt.Errorf("rcode: %s", dns.RcodeToString[r.Rcode])
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+	rtt := time.Since(start)
+	for _, err := range errs {
+		if err != nil {
+			return 0, err
+		}
+	}
+	return rtt, nil
+}
+
+func printSystemPath(label string, rtts []time.Duration, failed int) {
+	if len(rtts) == 0 {
+		fmt.Printf("%-30s | %s%-12s%s | %-12s | %-12s | %8d\n", label, ColorRed, "no answers", ColorReset, "", "", failed)
+		return
+	}
+	fmt.Printf("%-30s | %9.2f ms | %9.2f ms | %9.2f ms | %8d\n",
+		label, ms(avgDuration(rtts)), ms(percentile(rtts, 50)), ms(percentile(rtts, 95)), failed)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Console time formats accepted by --time-format besides a Go layout
+const (
+	TimeClock   = "clock"
+	TimeRFC3339 = "rfc3339"
+)
+
+// machineTimeLayout is RFC 3339 with milliseconds, used in exports and
+// structured logs
+const machineTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+var (
+	// timeZone is the --timezone all timestamps are shown and saved in
+	timeZone = time.Local
+	// consoleLayout is the --time-format layout of console timestamps;
+	// empty keeps the short clock each line uses
+	consoleLayout string
+)
+
+// configureTime applies --timezone (local, utc or an IANA name such as
+// Europe/Berlin) and --time-format (clock, rfc3339 or a Go layout)
+func configureTime(zone string, format string) error {
+	switch strings.ToLower(zone) {
+	case "local", "":
+		timeZone = time.Local
+	case "utc":
+		timeZone = time.UTC
+	default:
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			return fmt.Errorf("unknown time zone %q (want local, utc or a name such as Europe/Berlin)", zone)
+		}
+		timeZone = loc
+	}
+
+	switch format {
+	case TimeClock, "":
+		consoleLayout = ""
+	case TimeRFC3339:
+		consoleLayout = machineTimeLayout
+	default:
+		if !strings.ContainsAny(format, "0123456789") {
+			return fmt.Errorf("unknown time format %q (want %s, %s or a Go layout such as 2006-01-02 15:04:05)", format, TimeClock, TimeRFC3339)
+		}
+		consoleLayout = format
+	}
+	return nil
+}
+
+// now returns the current time in the configured zone; results are stamped
+// with it so saved files and exports carry the same offset
+func now() time.Time {
+	return time.Now().In(timeZone)
+}
+
+// consoleTime formats t for a console line: clock is the layout the line
+// uses by default, replaced by --time-format when one is set
+func consoleTime(t time.Time, clock string) string {
+	if consoleLayout != "" {
+		clock = consoleLayout
+	}
+	return t.In(timeZone).Format(clock)
+}
+
+// machineTime formats t as RFC 3339 with milliseconds in the configured zone
+func machineTime(t time.Time) string {
+	return t.In(timeZone).Format(machineTimeLayout)
+}
+
+// replaceTimeAttr renders the time of structured log records with
+// machineTime instead of the handler's local RFC 3339 nanoseconds
+func replaceTimeAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.TimeKey && a.Value.Kind() == slog.KindTime {
+		return slog.String(slog.TimeKey, machineTime(a.Value.Time()))
+	}
+	return a
+}
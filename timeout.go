@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Adaptive timeout tuning: after adaptiveMinSamples answers a resolver's
+// timeout follows adaptiveFactor times the p95 of its last adaptiveWindow
+// RTTs, within the floor and ceiling. Resolvers that have never answered
+// get half the timeout after each adaptiveDeadAfter consecutive timeouts.
+const (
+	adaptiveWindow     = 50
+	adaptiveMinSamples = 5
+	adaptiveFactor     = 4
+	adaptiveDeadAfter  = 3
+	adaptiveFloor      = 50 * time.Millisecond
+	adaptiveCeiling    = 10 * time.Second
+	adaptiveDeadFloor  = 500 * time.Millisecond
+)
+
+var (
+	// queryTimeout is the per-query timeout, or the starting point of
+	// adaptive timeouts
+	queryTimeout = 3 * time.Second
+
+	// timeouts tracks RTTs per resolver address when --adaptive-timeout is set
+	timeouts *adaptiveTimeouts
+
+	// connectTimeout bounds opening a TCP, DoT or DoH connection, TLS
+	// handshake included (--connect-timeout); 0 leaves it to queryTimeout
+	connectTimeout time.Duration
+
+	// readTimeout bounds the wait for the response once the query is sent
+	// (--read-timeout); 0 leaves it to queryTimeout
+	readTimeout time.Duration
+)
+
+// readDeadline returns when the response to a query sent now is given up
+// on: after readTimeout, but never past the query's deadline
+func readDeadline(deadline time.Time) time.Time {
+	if readTimeout > 0 {
+		if read := time.Now().Add(readTimeout); deadline.IsZero() || read.Before(deadline) {
+			return read
+		}
+	}
+	return deadline
+}
+
+// resolverTimeout is the adaptive state of one resolver address
+type resolverTimeout struct {
+	rtts     []time.Duration // ring of recent successful RTTs
+	next     int
+	answered bool
+	misses   int // consecutive timeouts
+	timeout  time.Duration
+}
+
+// adaptiveTimeouts derives per-resolver timeouts from observed RTTs, so
+// slow but working resolvers are not cut off at a fixed limit and dead ones
+// stop costing the full timeout per query
+type adaptiveTimeouts struct {
+	mu        sync.Mutex
+	base      time.Duration
+	resolvers map[string]*resolverTimeout
+}
+
+func newAdaptiveTimeouts(base time.Duration) *adaptiveTimeouts {
+	return &adaptiveTimeouts{base: base, resolvers: make(map[string]*resolverTimeout)}
+}
+
+// timeoutFor returns the timeout for the next query to addr
+func timeoutFor(addr string) time.Duration {
+	if timeouts == nil {
+		return queryTimeout
+	}
+	timeouts.mu.Lock()
+	defer timeouts.mu.Unlock()
+	if state, ok := timeouts.resolvers[addr]; ok {
+		return state.timeout
+	}
+	return timeouts.base
+}
+
+// observe updates addr's timeout with the outcome of a query
+func (t *adaptiveTimeouts) observe(addr string, rtt time.Duration, timedOut bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.resolvers[addr]
+	if !ok {
+		state = &resolverTimeout{timeout: t.base}
+		t.resolvers[addr] = state
+	}
+
+	if timedOut {
+		state.misses++
+		if !state.answered && state.misses%adaptiveDeadAfter == 0 {
+			state.timeout = max(state.timeout/2, adaptiveDeadFloor)
+		}
+		return
+	}
+
+	state.misses = 0
+	state.answered = true
+	if len(state.rtts) < adaptiveWindow {
+		state.rtts = append(state.rtts, rtt)
+	} else {
+		state.rtts[state.next] = rtt
+		state.next = (state.next + 1) % adaptiveWindow
+	}
+	if len(state.rtts) < adaptiveMinSamples {
+		state.timeout = t.base
+		return
+	}
+	state.timeout = min(max(adaptiveFactor*percentile(state.rtts, 95), adaptiveFloor), adaptiveCeiling)
+}
+
+// printAdaptiveTimeouts lists the timeout each resolver ended the run with
+func printAdaptiveTimeouts() {
+	if timeouts == nil {
+		return
+	}
+	timeouts.mu.Lock()
+	defer timeouts.mu.Unlock()
+
+	addrs := make([]string, 0, len(timeouts.resolvers))
+	for addr := range timeouts.resolvers {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+
+	fmt.Printf("%s[*] Adaptive timeouts (%dx rolling p95, started at %v):%s\n", ColorBlue, adaptiveFactor, timeouts.base, ColorReset)
+	for _, addr := range addrs {
+		state := timeouts.resolvers[addr]
+		note := ""
+		if !state.answered {
+			note = ColorRed + " (never answered)" + ColorReset
+		}
+		fmt.Printf("    %-30s %8.0f ms%s\n", addr, ms(state.timeout), note)
+	}
+	fmt.Printf("\n")
+}
@@ -0,0 +1,131 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+	"unsafe"
+
+	"github.com/miekg/dns"
+	"golang.org/x/sys/unix"
+)
+
+// timestampingFlags requests software transmit and receive timestamps; with
+// OPT_TSONLY the transmit timestamp arrives on the error queue without a
+// copy of the packet
+const timestampingFlags = unix.SOF_TIMESTAMPING_TX_SOFTWARE | unix.SOF_TIMESTAMPING_RX_SOFTWARE |
+	unix.SOF_TIMESTAMPING_SOFTWARE | unix.SOF_TIMESTAMPING_OPT_TSONLY
+
+// checkKernelTimestamps verifies that the kernel accepts SO_TIMESTAMPING
+func checkKernelTimestamps() error {
+	fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM, 0)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+	return unix.SetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_TIMESTAMPING, timestampingFlags)
+}
+
+// exchangeTimestamped sends a UDP query and measures the RTT between the
+// kernel's transmit and receive timestamps, leaving out the time the Go
+// scheduler takes to run the goroutine before the write and after the reply.
+// When no transmit timestamp is available the time before the write is used.
+func exchangeTimestamped(ctx context.Context, m *dns.Msg, addr string) (*dns.Msg, time.Duration, error) {
+	conn, err := newDialer("udp", addr).DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+	socketsOpened.Add(1)
+	udpConn := conn.(*net.UDPConn)
+	if deadline, ok := ctx.Deadline(); ok {
+		udpConn.SetDeadline(deadline)
+	}
+
+	raw, err := udpConn.SyscallConn()
+	if err != nil {
+		return nil, 0, err
+	}
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_TIMESTAMPING, timestampingFlags)
+	}); err != nil {
+		return nil, 0, err
+	}
+	if sockErr != nil {
+		return nil, 0, sockErr
+	}
+
+	packed, err := m.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+	sent := time.Now()
+	if _, err := udpConn.Write(packed); err != nil {
+		return nil, 0, err
+	}
+
+	buf := make([]byte, dns.MaxMsgSize)
+	oob := make([]byte, 512)
+	for {
+		var n, oobn int
+		var recvErr error
+		err := raw.Read(func(fd uintptr) bool {
+			n, oobn, _, _, recvErr = unix.Recvmsg(int(fd), buf, oob, 0)
+			return recvErr != unix.EAGAIN
+		})
+		if err == nil {
+			err = recvErr
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		r := &dns.Msg{}
+		if err := r.Unpack(buf[:n]); err != nil || r.Id != m.Id {
+			continue
+		}
+		received, ok := kernelTimestamp(oob[:oobn])
+		if !ok {
+			return nil, 0, errors.New("no kernel receive timestamp")
+		}
+
+		// The transmit timestamp is queued right after the send, well
+		// before the reply arrives, so it can be read without waiting
+		if err := raw.Read(func(fd uintptr) bool {
+			_, oobn, _, _, recvErr = unix.Recvmsg(int(fd), nil, oob, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT)
+			return true
+		}); err == nil && recvErr == nil {
+			if transmitted, ok := kernelTimestamp(oob[:oobn]); ok {
+				sent = transmitted
+			}
+		}
+		return r, received.Sub(sent), nil
+	}
+}
+
+// kernelTimestamp extracts the software timestamp from SCM_TIMESTAMPING
+// control messages
+func kernelTimestamp(oob []byte) (time.Time, bool) {
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, msg := range msgs {
+		if msg.Header.Level != unix.SOL_SOCKET || msg.Header.Type != unix.SCM_TIMESTAMPING {
+			continue
+		}
+		if len(msg.Data) < int(unsafe.Sizeof(unix.ScmTimestamping{})) {
+			continue
+		}
+		ts := (*unix.ScmTimestamping)(unsafe.Pointer(&msg.Data[0]))
+		if ts.Ts[0].Sec == 0 && ts.Ts[0].Nsec == 0 {
+			continue
+		}
+		return time.Unix(ts.Ts[0].Unix()), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+var errNoKernelTimestamps = errors.New("kernel timestamps are only supported on Linux")
+
+func checkKernelTimestamps() error {
+	return errNoKernelTimestamps
+}
+
+func exchangeTimestamped(ctx context.Context, m *dns.Msg, addr string) (*dns.Msg, time.Duration, error) {
+	return nil, 0, errNoKernelTimestamps
+}
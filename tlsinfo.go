@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// certExpiryWarning is how close to expiry a certificate gets flagged
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// CertInfo describes one certificate of a presented chain
+type CertInfo struct {
+	Subject  string    `json:"subject"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"not_after"`
+	SPKI     string    `json:"spki_sha256"`
+}
+
+// TLSInfo holds the TLS session details observed for an encrypted endpoint
+type TLSInfo struct {
+	Endpoint string     `json:"endpoint"`
+	Version  string     `json:"version"`
+	ALPN     string     `json:"alpn,omitempty"`
+	Chain    []CertInfo `json:"chain"`
+	Pinned   bool       `json:"pinned"`
+}
+
+var (
+	// tlsPins maps a TLS server name (host or IP) to accepted SPKI SHA-256 hashes
+	tlsPins = make(map[string][]string)
+
+	tlsInfos  = make(map[string]*TLSInfo)
+	tlsInfoMu sync.Mutex
+)
+
+// pinFlag collects SPKI pins given as host=sha256/BASE64 (or host=BASE64)
+type pinFlag struct{}
+
+func (pinFlag) String() string { return "" }
+
+func (pinFlag) Set(value string) error {
+	host, pin, found := strings.Cut(value, "=")
+	if !found || host == "" || pin == "" {
+		return fmt.Errorf("invalid pin %q (want host=sha256/BASE64)", value)
+	}
+	pin = strings.TrimPrefix(pin, "sha256/")
+	if raw, err := base64.StdEncoding.DecodeString(pin); err != nil || len(raw) != sha256.Size {
+		return fmt.Errorf("invalid pin %q: not a base64 SHA-256 hash", value)
+	}
+	tlsPins[host] = append(tlsPins[host], pin)
+	return nil
+}
+
+// spkiHash returns the base64 SHA-256 of a certificate's SubjectPublicKeyInfo
+func spkiHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// handshakeTLS performs a TLS client handshake on conn for the endpoint addr,
+// enforcing configured SPKI pins and recording the presented chain
+func handshakeTLS(ctx context.Context, conn net.Conn, addr string, nextProtos []string) (*tls.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+
+	config := &tls.Config{
+		ServerName: host,
+		NextProtos: nextProtos,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return verifyPins(host, cs.PeerCertificates)
+		},
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, fmt.Errorf("tls handshake: %w", err)
+	}
+	recordTLSState(addr, tlsConn.ConnectionState())
+	return tlsConn, nil
+}
+
+// verifyPins accepts the chain if any certificate matches a pin for host
+func verifyPins(host string, chain []*x509.Certificate) error {
+	pins, exists := tlsPins[host]
+	if !exists {
+		return nil
+	}
+	for _, cert := range chain {
+		if slices.Contains(pins, spkiHash(cert)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("SPKI pin mismatch for %s", host)
+}
+
+func recordTLSState(endpoint string, cs tls.ConnectionState) {
+	tlsInfoMu.Lock()
+	defer tlsInfoMu.Unlock()
+	if _, exists := tlsInfos[endpoint]; exists {
+		return
+	}
+
+	host, _, _ := net.SplitHostPort(endpoint)
+	info := &TLSInfo{
+		Endpoint: endpoint,
+		Version:  tls.VersionName(cs.Version),
+		ALPN:     cs.NegotiatedProtocol,
+		Pinned:   len(tlsPins[host]) > 0,
+	}
+	for _, cert := range cs.PeerCertificates {
+		info.Chain = append(info.Chain, CertInfo{
+			Subject:  cert.Subject.CommonName,
+			Issuer:   cert.Issuer.CommonName,
+			NotAfter: cert.NotAfter,
+			SPKI:     spkiHash(cert),
+		})
+	}
+	tlsInfos[endpoint] = info
+}
+
+// printTLSInfo reports the certificate chain presented by each encrypted endpoint
+func printTLSInfo() {
+	tlsInfoMu.Lock()
+	defer tlsInfoMu.Unlock()
+	if len(tlsInfos) == 0 {
+		return
+	}
+
+	var endpoints []string
+	for endpoint := range tlsInfos {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+
+	fmt.Printf("%s[*] TLS Certificates:%s\n\n", ColorBlue, ColorReset)
+	for _, endpoint := range endpoints {
+		info := tlsInfos[endpoint]
+		pinStatus := ""
+		if info.Pinned {
+			pinStatus = fmt.Sprintf(" %s[pin verified]%s", ColorGreen, ColorReset)
+		}
+		alpn := info.ALPN
+		if alpn == "" {
+			alpn = "none"
+		}
+		fmt.Printf("%s%s%s (%s, ALPN %s)%s\n", ColorWhite, endpoint, ColorReset, info.Version, alpn, pinStatus)
+
+		for i, cert := range info.Chain {
+			expiryColor := ColorGreen
+			remaining := time.Until(cert.NotAfter)
+			if remaining < certExpiryWarning {
+				expiryColor = ColorYellow
+			}
+			if remaining < 0 {
+				expiryColor = ColorRed
+			}
+			fmt.Printf("    %d. %s\n", i, cert.Subject)
+			fmt.Printf("       Issuer:  %s\n", cert.Issuer)
+			fmt.Printf("       Expires: %s%s (%d days)%s\n", expiryColor, cert.NotAfter.Format("2006-01-02"), int(remaining.Hours()/24), ColorReset)
+			fmt.Printf("       SPKI:    sha256/%s\n", cert.SPKI)
+		}
+		fmt.Printf("\n")
+	}
+}
+
+// printWebsiteTLS summarizes the TLS version, cipher suite and certificate
+// expiry per website of the HTTP test. Sites are reached through several DNS
+// servers and may hit different edges, so the soonest expiry seen is shown.
+func printWebsiteTLS(webResults []*websiteResult) {
+	type siteTLS struct {
+		version string
+		cipher  string
+		issuer  string
+		expiry  time.Time
+	}
+	sites := make(map[string]*siteTLS)
+	var domains []string
+	for _, result := range webResults {
+		if result.tls == nil || len(result.tls.PeerCertificates) == 0 {
+			continue
+		}
+		leaf := result.tls.PeerCertificates[0]
+		site, ok := sites[result.domain]
+		if !ok {
+			site = &siteTLS{
+				version: tls.VersionName(result.tls.Version),
+				cipher:  tls.CipherSuiteName(result.tls.CipherSuite),
+				issuer:  leaf.Issuer.CommonName,
+				expiry:  leaf.NotAfter,
+			}
+			sites[result.domain] = site
+			domains = append(domains, result.domain)
+		}
+		if leaf.NotAfter.Before(site.expiry) {
+			site.expiry, site.issuer = leaf.NotAfter, leaf.Issuer.CommonName
+		}
+	}
+	if len(domains) == 0 {
+		return
+	}
+	sort.Strings(domains)
+
+	fmt.Printf("%s[*] Website TLS:%s\n\n", ColorBlue, ColorReset)
+	fmt.Printf("%s%-25s | %-8s | %-40s | %-12s | %s%s\n", ColorWhite, "Domain", "Version", "Cipher Suite", "Cert Expires", "Issuer", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "──────────────────────────┼──────────┼──────────────────────────────────────────┼──────────────┼─────────────", ColorReset)
+
+	var expiring []string
+	for _, domain := range domains {
+		site := sites[domain]
+		remaining := time.Until(site.expiry)
+		expiryColor := ColorGreen
+		if remaining < certExpiryWarning {
+			expiryColor = ColorYellow
+			expiring = append(expiring, fmt.Sprintf("%s expires %s (%d days)", domain, site.expiry.Format("2006-01-02"), int(remaining.Hours()/24)))
+		}
+		if remaining < 0 {
+			expiryColor = ColorRed
+		}
+		versionColor := ColorGreen
+		if site.version != "TLS 1.3" && site.version != "TLS 1.2" {
+			versionColor = ColorRed
+		}
+		fmt.Printf("%-25s | %s%-8s%s | %-40s | %s%-12s%s | %s\n",
+			domain, versionColor, site.version, ColorReset, site.cipher,
+			expiryColor, site.expiry.Format("2006-01-02"), ColorReset, site.issuer)
+	}
+	fmt.Printf("\n")
+	for _, warning := range expiring {
+		fmt.Printf("%s[!] Certificate of %s%s\n", ColorYellow, warning, ColorReset)
+	}
+	if len(expiring) > 0 {
+		fmt.Printf("\n")
+	}
+}
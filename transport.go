@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/proxy"
+)
+
+// DNS transports
+const (
+	TransportUDP = "udp"
+	TransportTCP = "tcp"
+	TransportDoT = "dot"
+	TransportDoH = "doh"
+)
+
+var (
+	// proxyURL routes TCP-based transports through a SOCKS5 or HTTP proxy when set
+	proxyURL *url.URL
+
+	// kernelTimestamps measures UDP RTTs from kernel packet timestamps
+	kernelTimestamps bool
+
+	dohClient     *http.Client
+	dohClientOnce sync.Once
+)
+
+// validTransport reports whether the given transport name is supported
+func validTransport(transport string) bool {
+	switch transport {
+	case TransportUDP, TransportTCP, TransportDoT, TransportDoH:
+		return true
+	}
+	return false
+}
+
+// configureProxy parses a proxy URL (socks5://, socks5h://, http:// or https://)
+func configureProxy(raw string) error {
+	if raw == "" {
+		return nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL %q: %w", raw, err)
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h", "http", "https":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q (want socks5, socks5h, http or https)", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("proxy URL %q has no host", raw)
+	}
+
+	proxyURL = u
+	return nil
+}
+
+// exchange sends a query over the given transport and returns the response
+func exchange(ctx context.Context, m *dns.Msg, transport string, addr string) (*dns.Msg, error) {
+	r, _, err := exchangeSession(ctx, m, transport, addr)
+	return r, err
+}
+
+// exchangeSession is exchange that also returns the session the query went
+// over, so DoT and DoH queries can be checked for downgrades
+func exchangeSession(ctx context.Context, m *dns.Msg, transport string, addr string) (*dns.Msg, session, error) {
+	if reuseConns && transport != TransportDoH {
+		return exchangePooled(ctx, m, transport, addr)
+	}
+	switch transport {
+	case TransportUDP:
+		socketsOpened.Add(1)
+		if readTimeout > 0 {
+			// UDP has no connection to open, so the whole query is the read
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, readTimeout)
+			defer cancel()
+		}
+		client := &dns.Client{Dialer: newDialer("udp", addr)}
+		r, _, err := client.ExchangeContext(ctx, m, addr)
+		return r, session{}, err
+	case TransportTCP, TransportDoT:
+		return exchangeStream(ctx, m, transport, addr)
+	case TransportDoH:
+		return exchangeDoHWith(ctx, getDoHClient(), http.MethodPost, m, addr)
+	}
+	return nil, session{}, fmt.Errorf("unknown transport %q", transport)
+}
+
+// exchangeStream sends a query over a fresh TCP or DNS-over-TLS connection
+func exchangeStream(ctx context.Context, m *dns.Msg, transport string, addr string) (*dns.Msg, session, error) {
+	var nextProtos []string
+	if transport == TransportDoT {
+		nextProtos = []string{"dot"}
+	}
+	conn, err := connect(ctx, addr, nextProtos)
+	if err != nil {
+		return nil, session{}, err
+	}
+	defer conn.Close()
+
+	s := connSession(conn)
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	co := &dns.Conn{Conn: conn}
+	if err := co.WriteMsg(m); err != nil {
+		return nil, s, err
+	}
+	conn.SetReadDeadline(readDeadline(deadline))
+	r, err := co.ReadMsg()
+	return r, s, err
+}
+
+// connectError marks a failure to open the connection, so an unreachable
+// server is told apart from one that was reached but answered slowly
+type connectError struct {
+	err error
+}
+
+func (e *connectError) Error() string { return "connect: " + e.err.Error() }
+
+func (e *connectError) Unwrap() error { return e.err }
+
+// isConnectError reports whether a query failed before it was sent
+func isConnectError(err error) bool {
+	var connErr *connectError
+	return errors.As(err, &connErr)
+}
+
+// connect opens a TCP connection to addr and, given ALPN protocols, completes
+// a TLS handshake on it, all within connectTimeout when set
+func connect(ctx context.Context, addr string, nextProtos []string) (net.Conn, error) {
+	if connectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, connectTimeout)
+		defer cancel()
+	}
+	conn, err := dialStream(ctx, addr)
+	if err != nil {
+		return nil, &connectError{err}
+	}
+	if nextProtos == nil {
+		return conn, nil
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	tlsConn, err := handshakeTLS(ctx, conn, addr, nextProtos)
+	if err != nil {
+		conn.Close()
+		return nil, &connectError{err}
+	}
+	tlsConn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+// exchangeDoH sends a query as an RFC 8484 POST to a DNS-over-HTTPS endpoint
+func exchangeDoH(ctx context.Context, m *dns.Msg, endpoint string) (*dns.Msg, error) {
+	r, _, err := exchangeDoHWith(ctx, getDoHClient(), http.MethodPost, m, endpoint)
+	return r, err
+}
+
+// exchangeDoHWith sends a DoH query using the given client and HTTP method
+// (GET or POST) and also returns the session, with the negotiated HTTP
+// protocol, the response came over
+func exchangeDoHWith(ctx context.Context, client *http.Client, method string, m *dns.Msg, endpoint string) (*dns.Msg, session, error) {
+	// RFC 8484 recommends ID 0 so responses are cache friendly
+	q := m.Copy()
+	q.Id = 0
+	packed, err := q.Pack()
+	if err != nil {
+		return nil, session{}, err
+	}
+
+	var req *http.Request
+	if method == http.MethodGet {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, session{}, err
+		}
+		query := u.Query()
+		query.Set("dns", base64.RawURLEncoding.EncodeToString(packed))
+		u.RawQuery = query.Encode()
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, session{}, err
+		}
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(packed))
+		if err != nil {
+			return nil, session{}, err
+		}
+		req.Header.Set("Content-Type", "application/dns-message")
+	}
+	req.Header.Set("Accept", "application/dns-message")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, session{}, err
+	}
+	defer resp.Body.Close()
+	s := responseSession(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, s, fmt.Errorf("doh: HTTP %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, s, err
+	}
+
+	r := &dns.Msg{}
+	if err := r.Unpack(body); err != nil {
+		return nil, s, fmt.Errorf("doh: %w", err)
+	}
+	r.Id = m.Id
+	return r, s, nil
+}
+
+func getDoHClient() *http.Client {
+	dohClientOnce.Do(func() {
+		dohClient = newDoHClient(true)
+	})
+	return dohClient
+}
+
+// newDoHClient returns an HTTP client for DoH queries, optionally allowing HTTP/2.
+// TLS is dialed through connect so the proxy, source address, certificate
+// pinning and connect timeout apply exactly as they do for DoT; the read
+// timeout bounds the wait for the response headers.
+func newDoHClient(allowHTTP2 bool) *http.Client {
+	nextProtos := []string{"http/1.1"}
+	if allowHTTP2 {
+		nextProtos = []string{"h2", "http/1.1"}
+	}
+
+	transport := &http.Transport{
+		DialTLSContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			return connect(ctx, addr, nextProtos)
+		},
+		ForceAttemptHTTP2:     allowHTTP2,
+		MaxIdleConns:          100,
+		ResponseHeaderTimeout: readTimeout,
+	}
+	return &http.Client{Transport: transport}
+}
+
+// dialStream opens a TCP connection to addr, through the proxy if configured.
+// Hostnames pinned with --resolve or looked up through --bootstrap are
+// dialed at those addresses in order; TLS still verifies the hostname.
+func dialStream(ctx context.Context, addr string) (net.Conn, error) {
+	targets, err := bootstrapAddrs(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	if targets == nil {
+		return dialStreamTo(ctx, addr)
+	}
+	var lastErr error
+	for _, target := range targets {
+		conn, err := dialStreamTo(ctx, target)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// dialStreamTo opens one TCP connection, through the proxy if configured
+func dialStreamTo(ctx context.Context, addr string) (net.Conn, error) {
+	socketsOpened.Add(1)
+	if proxyURL == nil {
+		return newDialer("tcp", addr).DialContext(ctx, "tcp", addr)
+	}
+
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if proxyURL.User != nil {
+			password, _ := proxyURL.User.Password()
+			auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+		}
+		dialer, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, newDialer("tcp", proxyURL.Host))
+		if err != nil {
+			return nil, err
+		}
+		return dialer.(proxy.ContextDialer).DialContext(ctx, "tcp", addr)
+	default:
+		return dialHTTPConnect(ctx, addr)
+	}
+}
+
+// dialHTTPConnect tunnels a TCP connection through an HTTP proxy using CONNECT
+func dialHTTPConnect(ctx context.Context, addr string) (net.Conn, error) {
+	conn, err := newDialer("tcp", proxyURL.Host).DialContext(ctx, "tcp", proxyURL.Host)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy tls handshake: %w", err)
+		}
+		conn = tlsConn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	var req strings.Builder
+	fmt.Fprintf(&req, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n", addr, addr)
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		fmt.Fprintf(&req, "Proxy-Authorization: Basic %s\r\n", credentials)
+	}
+	req.WriteString("\r\n")
+	if _, err := io.WriteString(conn, req.String()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT: %s", resp.Status)
+	}
+	return conn, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// TypeStats holds one server's latency for one record type. Answered counts
+// NOERROR responses with or without records, since many names have no AAAA
+// or HTTPS records but the lookup time still matters
+type TypeStats struct {
+	ServerName   string
+	ServerAddr   string
+	QType        string
+	AvgRTT       time.Duration
+	TotalQueries int
+	Answered     int
+
+	rttTotal time.Duration
+}
+
+// qtype returns the record type, A for results from before --types
+func (r *BenchmarkResult) qtype() string {
+	return cmp.Or(r.QType, "A")
+}
+
+// logDomain labels the domain with its record type unless it is a plain A query
+func (r *BenchmarkResult) logDomain() string {
+	if r.qtype() == "A" {
+		return r.Domain
+	}
+	return r.Domain + " " + r.qtype()
+}
+
+// addType accumulates a result under its server and record type
+func (a *aggregator) addType(key string, result *BenchmarkResult) {
+	if a.types[key] == nil {
+		a.types[key] = make(map[string]*TypeStats)
+	}
+	stats := a.types[key][result.qtype()]
+	if stats == nil {
+		stats = &TypeStats{ServerName: result.ServerName, ServerAddr: result.ServerAddr, QType: result.qtype()}
+		a.types[key][result.qtype()] = stats
+	}
+	stats.TotalQueries++
+	if result.Status == "SUCCESS" || result.Status == "NO_RECORDS" {
+		stats.Answered++
+		stats.rttTotal += result.RTT
+	}
+}
+
+// typeStats returns a snapshot of the per-type statistics keyed by server
+// ("name - addr") and record type, and the record types seen ordered by
+// type number so A comes before AAAA and HTTPS
+func (a *aggregator) typeStats() (map[string]map[string]*TypeStats, []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	snapshot := make(map[string]map[string]*TypeStats, len(a.types))
+	var qtypes []string
+	for key, types := range a.types {
+		snapshot[key] = make(map[string]*TypeStats, len(types))
+		for qtype, stats := range types {
+			t := *stats
+			if t.Answered > 0 {
+				t.AvgRTT = t.rttTotal / time.Duration(t.Answered)
+			}
+			snapshot[key][qtype] = &t
+			if !slices.Contains(qtypes, qtype) {
+				qtypes = append(qtypes, qtype)
+			}
+		}
+	}
+	sort.Slice(qtypes, func(i, j int) bool { return dns.StringToType[qtypes[i]] < dns.StringToType[qtypes[j]] })
+	return snapshot, qtypes
+}
+
+// typeRange returns the answered types with the lowest and highest average RTT
+func typeRange(types map[string]*TypeStats) (fastest *TypeStats, slowest *TypeStats) {
+	for _, stats := range types {
+		if stats.Answered == 0 {
+			continue
+		}
+		if fastest == nil || stats.AvgRTT < fastest.AvgRTT {
+			fastest = stats
+		}
+		if slowest == nil || stats.AvgRTT > slowest.AvgRTT {
+			slowest = stats
+		}
+	}
+	return fastest, slowest
+}
+
+// printTypeBreakdown shows each server's average RTT per record type when
+// more than one type was queried; types at least 1.5x slower than the
+// server's fastest one are highlighted
+func printTypeBreakdown(agg *aggregator) {
+	byServer, qtypes := agg.typeStats()
+	if len(qtypes) < 2 {
+		return
+	}
+	statsList := agg.serverStats()
+
+	fmt.Printf("\n%s[*] Latency per Record Type (average of NOERROR answers):%s\n\n", ColorBlue, ColorReset)
+	fmt.Printf("%s%-30s", ColorWhite, "Server")
+	separator := "───────────────────────────────"
+	for _, qtype := range qtypes {
+		fmt.Printf(" | %-11s", qtype)
+		separator += "┼─────────────"
+	}
+	fmt.Printf(" | %s%s\n", "Slowest", ColorReset)
+	fmt.Printf("%s%s┼──────────────%s\n", ColorYellow, separator, ColorReset)
+
+	for _, stats := range statsList {
+		types := byServer[stats.ServerName+" - "+stats.ServerAddr]
+		fastest, slowest := typeRange(types)
+
+		fmt.Printf("%-30s", stats.ServerName+" ("+stats.ServerAddr+")")
+		for _, qtype := range qtypes {
+			t := types[qtype]
+			if t == nil || t.Answered == 0 {
+				fmt.Printf(" | %s%11s%s", ColorRed, "-", ColorReset)
+				continue
+			}
+			color := ColorGreen
+			if float64(t.AvgRTT) >= 1.5*float64(fastest.AvgRTT) {
+				color = ColorYellow
+			}
+			fmt.Printf(" | %s%8.2f ms%s", color, ms(t.AvgRTT), ColorReset)
+		}
+		if fastest != nil && float64(slowest.AvgRTT) >= 1.5*float64(fastest.AvgRTT) {
+			fmt.Printf(" | %s%s %.1fx%s\n", ColorYellow, slowest.QType, float64(slowest.AvgRTT)/float64(fastest.AvgRTT), ColorReset)
+		} else {
+			fmt.Printf(" | %s\n", "-")
+		}
+	}
+
+	// Types that fail outright on some servers would otherwise only show "-"
+	for _, stats := range statsList {
+		key := stats.ServerName + " (" + stats.ServerAddr + ")"
+		for _, qtype := range qtypes {
+			t := byServer[stats.ServerName+" - "+stats.ServerAddr][qtype]
+			if t != nil && t.Answered < t.TotalQueries {
+				fmt.Printf("%s[!] %s: %d of %d %s queries failed%s\n", ColorYellow, key, t.TotalQueries-t.Answered, t.TotalQueries, qtype, ColorReset)
+			}
+		}
+	}
+}
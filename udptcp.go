@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// tcpThrottled is how much slower than UDP the median TCP query may be before
+// the path is flagged; a fresh TCP connection costs about one extra round
+// trip for the handshake, so up to twice the UDP time is expected
+const tcpThrottled = 3.0
+
+// pairedTransports holds the UDP and TCP results of one resolver address
+type pairedTransports struct {
+	ServerName string
+	ServerAddr string
+	UDP, TCP   []time.Duration // RTTs of answered queries
+	UDPTotal   int
+	TCPTotal   int
+	Deltas     []time.Duration // TCP minus UDP RTT of queries answered over both
+}
+
+// runUDPvsTCP sends every query to each plain DNS address over UDP and over
+// TCP back to back, alternating which goes first, and compares the two per
+// resolver. Resolvers or paths that block or throttle TCP 53 stand out, and
+// since truncated answers and large responses fall back to TCP, they matter
+// even when UDP looks healthy.
+func runUDPvsTCP(config *BenchmarkConfig) {
+	fmt.Printf("%s[*] Querying every server over UDP and TCP side by side...%s\n", ColorBlue, ColorReset)
+	if reuseConns {
+		fmt.Printf("%s    TCP connections are reused, so TCP times leave out the handshake%s\n", ColorYellow, ColorReset)
+	}
+	fmt.Printf("\n")
+
+	var pairs []*pairedTransports
+	for _, server := range config.Servers {
+		for _, addr := range server.endpoints(TransportUDP) {
+			pair := &pairedTransports{ServerName: server.Name, ServerAddr: addr}
+			for i := 0; i < config.QueryNum; i++ {
+				for j, domain := range config.Domains {
+					for _, qtype := range config.QTypes {
+						order := []string{TransportUDP, TransportTCP}
+						if (i+j)%2 == 1 {
+							order = []string{TransportTCP, TransportUDP}
+						}
+						rtts := make(map[string]time.Duration)
+						for _, transport := range order {
+							result := queryDNS(queryJob{
+								ServerName: server.Name,
+								ServerAddr: addr,
+								Transport:  transport,
+								Domain:     domain,
+								QType:      qtype,
+								Iteration:  i,
+							})
+							if transport == TransportUDP {
+								pair.UDPTotal++
+							} else {
+								pair.TCPTotal++
+							}
+							if unanswered(result) {
+								continue
+							}
+							rtts[transport] = result.RTT
+							if transport == TransportUDP {
+								pair.UDP = append(pair.UDP, result.RTT)
+							} else {
+								pair.TCP = append(pair.TCP, result.RTT)
+							}
+						}
+						udp, okUDP := rtts[TransportUDP]
+						tcp, okTCP := rtts[TransportTCP]
+						if okUDP && okTCP {
+							pair.Deltas = append(pair.Deltas, tcp-udp)
+						}
+					}
+				}
+			}
+			pairs = append(pairs, pair)
+		}
+	}
+	printUDPvsTCP(pairs)
+}
+
+func printUDPvsTCP(pairs []*pairedTransports) {
+	fmt.Printf("%s%-30s | %-12s | %-12s | %-12s | %-8s | %-8s | %s%s\n",
+		ColorWhite, "Server", "UDP Median", "TCP Median", "Paired Δ", "UDP OK", "TCP OK", "Verdict", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼──────────────┼──────────────┼──────────────┼──────────┼──────────┼──────────", ColorReset)
+
+	var flagged int
+	for _, pair := range pairs {
+		udpRate := float64(len(pair.UDP)) / float64(max(pair.UDPTotal, 1)) * 100
+		tcpRate := float64(len(pair.TCP)) / float64(max(pair.TCPTotal, 1)) * 100
+		udpMedian, tcpMedian := percentile(pair.UDP, 50), percentile(pair.TCP, 50)
+
+		verdict := ColorGreen + "ok" + ColorReset
+		switch {
+		case len(pair.UDP) == 0 && len(pair.TCP) == 0:
+			verdict = ColorRed + "unreachable" + ColorReset
+		case len(pair.TCP) == 0:
+			verdict = ColorRed + "TCP blocked" + ColorReset
+			flagged++
+		case len(pair.UDP) == 0:
+			verdict = ColorRed + "UDP blocked" + ColorReset
+			flagged++
+		case tcpRate < udpRate-10:
+			verdict = ColorYellow + "TCP lossy" + ColorReset
+			flagged++
+		case float64(tcpMedian) > tcpThrottled*float64(udpMedian):
+			verdict = ColorYellow + "TCP throttled" + ColorReset
+			flagged++
+		}
+
+		delta := "-"
+		if len(pair.Deltas) > 0 {
+			delta = fmt.Sprintf("%+8.2f ms", ms(percentile(pair.Deltas, 50)))
+		}
+		serverDisplay := fmt.Sprintf("%s (%s)", pair.ServerName, pair.ServerAddr)
+		fmt.Printf("%-30s | %9.2f ms | %9.2f ms | %12s | %7.1f%% | %7.1f%% | %s\n",
+			serverDisplay, ms(udpMedian), ms(tcpMedian), delta, udpRate, tcpRate, verdict)
+	}
+	fmt.Printf("\n%s    Paired Δ is the median of TCP minus UDP time for queries answered over both%s\n", ColorCyan, ColorReset)
+
+	if flagged > 0 {
+		fmt.Printf("%s[!] %d address(es) block or throttle one of the transports; truncated answers will fail or stall there%s\n\n", ColorYellow, flagged, ColorReset)
+	} else {
+		fmt.Printf("%s[✓] Every address answers over both UDP and TCP%s\n\n", ColorGreen, ColorReset)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// webhookTimeout bounds each webhook delivery
+const webhookTimeout = 10 * time.Second
+
+// WatchEvent is emitted when a resolver's answer differs from the previous
+// one it gave; it is the JSON body of webhook deliveries
+type WatchEvent struct {
+	Time           time.Time `json:"time"`
+	Name           string    `json:"name"`
+	Type           string    `json:"type"`
+	Resolver       string    `json:"resolver"`
+	Addr           string    `json:"addr"`
+	PreviousStatus string    `json:"previous_status"`
+	Previous       []string  `json:"previous"`
+	Status         string    `json:"status"`
+	Current        []string  `json:"current"`
+	// Text is a one-line summary so Slack-style webhooks display something
+	Text string `json:"text"`
+}
+
+// runWatch implements "dnsbench watch NAME [TYPE]": the record is polled
+// through every selected resolver each interval and an event is printed
+// (and optionally posted to a webhook) whenever a resolver's answer changes,
+// which shows a migration spreading or a resolver suddenly being hijacked
+func runWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	sel := addSelectionFlags(fs)
+	interval := fs.Duration("interval", 30*time.Second, "time between polls")
+	duration := fs.Duration("duration", 0, "stop after this long (0 = until interrupted)")
+	webhook := fs.String("webhook", "", "POST every change event as JSON to this URL")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: dnsbench watch NAME [TYPE] [flags]\n")
+		fs.PrintDefaults()
+	}
+
+	positional := parseInterspersed(fs, args)
+	if len(positional) == 0 || len(positional) > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	name := positional[0]
+	qtype := "A"
+	if len(positional) == 2 {
+		qtype = positional[1]
+	}
+	rrtype, ok := dns.StringToType[strings.ToUpper(qtype)]
+	if !ok {
+		fmt.Printf("%s[!] Unknown record type %q%s\n", ColorRed, qtype, ColorReset)
+		os.Exit(2)
+	}
+	fqdn, err := toASCII(name)
+	if err != nil {
+		fmt.Printf("%s[!] Invalid name %q: %v%s\n", ColorRed, name, err, ColorReset)
+		os.Exit(2)
+	}
+	if *interval <= 0 {
+		fmt.Printf("%s[!] --interval must be positive%s\n", ColorRed, ColorReset)
+		os.Exit(2)
+	}
+	if *webhook != "" {
+		if u, err := url.Parse(*webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			fmt.Printf("%s[!] --webhook must be an http:// or https:// URL%s\n", ColorRed, ColorReset)
+			os.Exit(2)
+		}
+	}
+	selected := sel.resolve(fs)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	display, typeName := displayDomain(name), dns.TypeToString[rrtype]
+	answers := askResolvers(selected, *sel.transport, dns.Fqdn(fqdn), rrtype, *sel.timeout)
+	printPropagation(display, typeName, answers)
+	if len(answers) == 0 {
+		os.Exit(1)
+	}
+
+	// last holds the latest successful answer per endpoint; failures are
+	// reported separately and never count as a change
+	last := make(map[string]*propagationAnswer)
+	failing := make(map[string]bool)
+	for _, answer := range answers {
+		if answer.Err == nil {
+			last[answer.Addr] = answer
+		} else {
+			failing[answer.Addr] = true
+		}
+	}
+
+	fmt.Printf("\n%s[*] Watching %s %s every %v", ColorBlue, display, typeName, *interval)
+	if *duration > 0 {
+		fmt.Printf(" for %v", *duration)
+	}
+	fmt.Printf(" (Ctrl+C to stop)...%s\n", ColorReset)
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	changes := 0
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Printf("\n%s[*] Stopped after %d change(s)%s\n", ColorBlue, changes, ColorReset)
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		for _, answer := range askResolvers(selected, *sel.transport, dns.Fqdn(fqdn), rrtype, *sel.timeout) {
+			resolver := fmt.Sprintf("%s (%s)", answer.Server.Name, answer.Addr)
+			if answer.Err != nil {
+				if !failing[answer.Addr] {
+					fmt.Printf("%s[%s] %s stopped answering: %v%s\n", ColorYellow, now.Format("15:04:05"), resolver, answer.Err, ColorReset)
+				}
+				failing[answer.Addr] = true
+				continue
+			}
+			if failing[answer.Addr] {
+				fmt.Printf("%s[%s] %s answers again%s\n", ColorCyan, now.Format("15:04:05"), resolver, ColorReset)
+				failing[answer.Addr] = false
+			}
+
+			previous := last[answer.Addr]
+			last[answer.Addr] = answer
+			if previous == nil || previous.key() == answer.key() {
+				continue
+			}
+
+			changes++
+			event := newWatchEvent(now, display, typeName, previous, answer)
+			fmt.Printf("%s[%s] %s%s\n", ColorRed, now.Format("15:04:05"), event.Text, ColorReset)
+			if *webhook != "" {
+				if err := postWebhook(*webhook, event); err != nil {
+					fmt.Printf("%s[!] %v%s\n", ColorYellow, err, ColorReset)
+				}
+			}
+		}
+	}
+}
+
+func newWatchEvent(at time.Time, name string, qtype string, previous *propagationAnswer, current *propagationAnswer) *WatchEvent {
+	return &WatchEvent{
+		Time:           at.UTC(),
+		Name:           name,
+		Type:           qtype,
+		Resolver:       current.Server.Name,
+		Addr:           current.Addr,
+		PreviousStatus: previous.Status,
+		Previous:       previous.Answers,
+		Status:         current.Status,
+		Current:        current.Answers,
+		Text: fmt.Sprintf("%s %s changed at %s (%s): %s → %s", name, qtype, current.Server.Name, current.Addr,
+			watchAnswer(previous), watchAnswer(current)),
+	}
+}
+
+// watchAnswer formats an answer for the one-line event summary
+func watchAnswer(answer *propagationAnswer) string {
+	if len(answer.Answers) == 0 {
+		return answer.Status + " (no records)"
+	}
+	return strings.Join(answer.Answers, ", ")
+}
+
+// postWebhook delivers one event as JSON
+func postWebhook(target string, event any) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// resolvConfPath is where the system search list and ndots are read from
+const resolvConfPath = "/etc/resolv.conf"
+
+// wildcardProbe is a name that must not exist; an answer means rewriting
+type wildcardProbe struct {
+	Label  string
+	Suffix string
+}
+
+// wildcardProbes cover hijacking of unregistered .com names and of a TLD
+// that cannot exist (RFC 2606 reserves .invalid)
+var wildcardProbes = []wildcardProbe{
+	{"Random .com", "com"},
+	{"Random .net", "net"},
+	{"Random .invalid", "invalid"},
+}
+
+// randomLabel returns a label no real zone will contain
+func randomLabel() string {
+	return fmt.Sprintf("dnsbench-%012x", rand.Uint64()&0xffffffffffff)
+}
+
+// lookupA returns the A records for name, or the rcode name when there are none
+func lookupA(transport string, addr string, name string) ([]string, string, error) {
+	m := &dns.Msg{}
+	m.SetQuestion(queryName(name), dns.TypeA)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	r, err := exchange(ctx, m, transport, addr)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var ips []string
+	for _, rr := range r.Answer {
+		if a, ok := rr.(*dns.A); ok {
+			ips = append(ips, a.A.String())
+		}
+	}
+	return ips, dns.RcodeToString[r.Rcode], nil
+}
+
+// runWildcard detects resolvers that answer for names that do not exist
+// (NXDOMAIN rewriting) and checks whether the system search list and ndots
+// setting could silently turn a hostname into a different one
+func runWildcard(config *BenchmarkConfig) {
+	fmt.Printf("%s[*] Checking for NXDOMAIN wildcarding...%s\n\n", ColorBlue, ColorReset)
+
+	fmt.Printf("%s%-30s", ColorWhite, "Server")
+	for _, probe := range wildcardProbes {
+		fmt.Printf(" | %-18s", probe.Label)
+	}
+	fmt.Printf("%s\n", ColorReset)
+	fmt.Printf("%s%s%s\n", ColorYellow, "───────────────────────────────┼────────────────────┼────────────────────┼───────────────────", ColorReset)
+
+	var wildcarding []string
+	for _, server := range config.Servers {
+		for _, addr := range server.endpoints(config.Transport) {
+			rewritten := false
+			fmt.Printf("%-30s", fmt.Sprintf("%s (%s)", server.Name, addr))
+			for _, probe := range wildcardProbes {
+				ips, rcode, err := lookupA(config.Transport, addr, randomLabel()+"."+probe.Suffix)
+				switch {
+				case err != nil:
+					fmt.Printf(" | %s%-18s%s", ColorYellow, "error", ColorReset)
+				case len(ips) > 0:
+					rewritten = true
+					fmt.Printf(" | %s%-18s%s", ColorRed, "→ "+ips[0], ColorReset)
+				default:
+					fmt.Printf(" | %s%-18s%s", ColorGreen, rcode, ColorReset)
+				}
+			}
+			fmt.Printf("\n")
+			if rewritten {
+				wildcarding = append(wildcarding, fmt.Sprintf("%s (%s)", server.Name, addr))
+			}
+		}
+	}
+
+	if len(wildcarding) > 0 {
+		fmt.Printf("\n%s[!] Answers for non-existent names (NXDOMAIN rewriting): %s%s\n", ColorRed, strings.Join(wildcarding, ", "), ColorReset)
+		fmt.Printf("%s    Typos and dead hostnames will resolve to these addresses instead of failing%s\n", ColorYellow, ColorReset)
+	} else {
+		fmt.Printf("\n%s[✓] No resolver rewrote non-existent names%s\n", ColorGreen, ColorReset)
+	}
+
+	checkSearchDomains(config)
+}
+
+// checkSearchDomains evaluates the system's search list: names with fewer
+// dots than ndots are tried with each suffix first, so a wildcarded suffix
+// captures them
+func checkSearchDomains(config *BenchmarkConfig) {
+	fmt.Printf("\n%s[*] Search domains (%s):%s\n", ColorBlue, resolvConfPath, ColorReset)
+
+	resolvConf, err := dns.ClientConfigFromFile(resolvConfPath)
+	if err != nil {
+		fmt.Printf("%s    Not available on this system: %v%s\n", ColorYellow, err, ColorReset)
+		return
+	}
+	if len(resolvConf.Search) == 0 {
+		fmt.Printf("%s[✓] No search domains configured, hostnames are never rewritten%s\n", ColorGreen, ColorReset)
+		return
+	}
+	fmt.Printf("    search %s, ndots %d\n", strings.Join(resolvConf.Search, " "), resolvConf.Ndots)
+
+	// Names the benchmark itself uses that would be tried with a suffix first
+	var affected []string
+	for _, domain := range config.Domains {
+		if dns.CountLabel(domain)-1 < resolvConf.Ndots {
+			affected = append(affected, domain)
+		}
+	}
+	if len(affected) > 0 {
+		fmt.Printf("%s[!] ndots %d makes %d of %d test domains try search suffixes first (e.g. %s.%s)%s\n",
+			ColorYellow, resolvConf.Ndots, len(affected), len(config.Domains), affected[0], resolvConf.Search[0], ColorReset)
+	}
+
+	// Check every suffix through the first reachable server
+	var addr string
+	for _, server := range config.Servers {
+		if addrs := server.endpoints(config.Transport); len(addrs) > 0 {
+			addr = addrs[0]
+			break
+		}
+	}
+	if addr == "" {
+		return
+	}
+
+	for _, suffix := range resolvConf.Search {
+		suffix = strings.TrimSuffix(suffix, ".")
+		ips, _, err := lookupA(config.Transport, addr, randomLabel()+"."+suffix)
+		switch {
+		case err != nil:
+			fmt.Printf("%s    %-30s could not be checked: %v%s\n", ColorYellow, suffix, err, ColorReset)
+		case len(ips) > 0:
+			fmt.Printf("%s[!] %-30s is wildcarded (→ %s): short names and typos resolve here%s\n", ColorRed, suffix, ips[0], ColorReset)
+		default:
+			fmt.Printf("%s[✓] %-30s does not wildcard%s\n", ColorGreen, suffix, ColorReset)
+		}
+
+		// A test domain that exists under the suffix gets silently rewritten
+		for _, domain := range affected {
+			if ips, _, err := lookupA(config.Transport, addr, domain+"."+suffix); err == nil && len(ips) > 0 {
+				fmt.Printf("%s[!] %s resolves as %s.%s (→ %s) before the real name is tried%s\n", ColorRed, domain, domain, suffix, ips[0], ColorReset)
+			}
+		}
+	}
+}